@@ -0,0 +1,135 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Analysis describes a position from the point of view of the player to move.
+type Analysis struct {
+	NimSum       int      // XOR of the values in the table, see Explanation
+	Winning      bool     // the player to move can force a win
+	WinningMoves [][]int  // positions reachable by winning moves
+	Explanation  []string // lines explaining the classification
+}
+
+// analyzePosition classifies piles under the rules of solver and explains why.
+// For the variants with a closed form the explanation is the binary column
+// table behind it; other variants are classified by exhaustive search.
+func analyzePosition(piles []int, solver *Solver) Analysis {
+	r := solver.rules
+	a := Analysis{Winning: solver.isWon(piles), WinningMoves: solver.winningMoves(piles)}
+	switch {
+	case r.MaxPiles <= 1 && !r.Misere:
+		values := make([]int, len(piles))
+		label := "pile"
+		for i, pile := range piles {
+			values[i] = solver.grundy.Value(pileGame{stones: pile, rules: r})
+		}
+		if r.MaxTake > 0 {
+			label = fmt.Sprintf("pile mod %d", r.MaxTake+1)
+			a.Explanation = append(a.Explanation,
+				fmt.Sprintf("Taking at most %d stones, a pile counts as its size modulo %d.", r.MaxTake, r.MaxTake+1))
+		}
+		a.NimSum = nimSum(values)
+		a.Explanation = append(a.Explanation, columnTable(values, label, 2)...)
+		a.Explanation = append(a.Explanation, fmt.Sprintf("Nim-sum: %d = %b", a.NimSum, a.NimSum))
+		if a.Winning {
+			a.Explanation = append(a.Explanation,
+				"Some column has an odd number of ones, so the position is winning:",
+				"change one pile so that every column becomes even.")
+		} else {
+			a.Explanation = append(a.Explanation,
+				"Every column has an even number of ones, so the position is losing:",
+				"any move makes some column odd again.")
+		}
+	case r.MaxPiles <= 1 && r.MaxTake == 0:
+		a.NimSum = nimSum(piles)
+		a.Explanation = append(a.Explanation, columnTable(piles, "pile", 2)...)
+		a.Explanation = append(a.Explanation, fmt.Sprintf("Nim-sum: %d = %b", a.NimSum, a.NimSum))
+		big := 0
+		for _, pile := range piles {
+			if pile > 1 {
+				big++
+			}
+		}
+		if big == 0 {
+			a.Explanation = append(a.Explanation,
+				"In misère Nim with only single stones left, the player to move wins",
+				"exactly when an even number of them remain.")
+		} else {
+			a.Explanation = append(a.Explanation,
+				"In misère Nim play as in normal Nim (make every column even), except",
+				"when the move would leave only single stones: then leave an odd number of them.")
+		}
+	case r.MaxPiles > 1 && r.MaxTake == 0 && !r.Misere:
+		a.Explanation = append(a.Explanation, columnTable(piles, "pile", r.MaxPiles+1)...)
+		a.NimSum = nimSum(piles)
+		a.Explanation = append(a.Explanation, fmt.Sprintf(
+			"Taking from up to %d piles, the position is losing exactly when every column count is divisible by %d.",
+			r.MaxPiles, r.MaxPiles+1))
+	default:
+		a.NimSum = nimSum(piles)
+		a.Explanation = append(a.Explanation, fmt.Sprintf(
+			"No simple formula is used for rules %v; the position was classified by searching the game tree.", r))
+	}
+	return a
+}
+
+// columnTable writes values in binary and counts the ones in every column.
+// For modulus 2 the counts are shown as parities, otherwise modulo modulus.
+func columnTable(values []int, label string, modulus int) []string {
+	width := 1
+	for _, v := range values {
+		if n := len(strconv.FormatInt(int64(v), 2)); n > width {
+			width = n
+		}
+	}
+	var lines []string
+	counts := make([]int, width)
+	for i, v := range values {
+		bits := fmt.Sprintf("%0*b", width, v)
+		for j, bit := range bits {
+			if bit == '1' {
+				counts[j]++
+			}
+		}
+		lines = append(lines, fmt.Sprintf("  %-12s %3d = %s", fmt.Sprintf("%s %d", label, i+1), v, spaced(bits)))
+	}
+	countText := make([]string, width)
+	restText := make([]string, width)
+	for j, count := range counts {
+		countText[j] = strconv.Itoa(count)
+		restText[j] = strconv.Itoa(count % modulus)
+	}
+	lines = append(lines, fmt.Sprintf("  %-18s %s", "ones per column", strings.Join(countText, " ")))
+	if modulus == 2 {
+		lines = append(lines, fmt.Sprintf("  %-18s %s", "parity (nim-sum)", strings.Join(restText, " ")))
+	} else {
+		lines = append(lines, fmt.Sprintf("  %-18s %s", fmt.Sprintf("count mod %d", modulus), strings.Join(restText, " ")))
+	}
+	return lines
+}
+
+// spaced puts a space between the digits of bits to line them up with counts.
+func spaced(bits string) string {
+	return strings.Join(strings.Split(bits, ""), " ")
+}
+
+// printAnalysis writes the analysis of a position for the human player.
+func printAnalysis(w io.Writer, piles []int, a Analysis) {
+	fmt.Fprintln(w, "Position analysis:")
+	for _, line := range a.Explanation {
+		fmt.Fprintln(w, line)
+	}
+	if !a.Winning {
+		fmt.Fprintln(w, "You are in a losing position; every move can be answered by a winning one.")
+		return
+	}
+	fmt.Fprintln(w, "You are winning. Winning moves:")
+	for _, next := range a.WinningMoves {
+		fmt.Fprintf(w, "  %s\n", describeMove(piles, next))
+	}
+}
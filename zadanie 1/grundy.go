@@ -0,0 +1,286 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Impartial is a position of an impartial game, in which both players have
+// the same moves. The player who cannot move loses (normal play).
+type Impartial interface {
+	// Options returns the positions reachable in one move.
+	Options() []Impartial
+	// Key returns a canonical encoding; positions with equal keys must have
+	// equal Grundy values.
+	Key() string
+	String() string
+}
+
+// GrundyCalculator computes Sprague-Grundy values: the Grundy value of a
+// position is the smallest non-negative integer that is not the value of one
+// of its options (mex). Every impartial position is equivalent to a Nim heap
+// of that size, so a sum of games is lost exactly when the XOR of the values
+// of its components is 0. Values are memoised on Impartial.Key.
+type GrundyCalculator struct {
+	memo map[string]int
+}
+
+// NewGrundyCalculator returns a calculator with an empty memo.
+func NewGrundyCalculator() *GrundyCalculator {
+	return &GrundyCalculator{memo: make(map[string]int)}
+}
+
+// Value returns the Grundy value of p.
+func (c *GrundyCalculator) Value(p Impartial) int {
+	if sum, ok := p.(Sum); ok {
+		value := 0
+		for _, component := range sum {
+			value ^= c.Value(component)
+		}
+		return value
+	}
+	key := p.Key()
+	if value, ok := c.memo[key]; ok {
+		return value
+	}
+	options := p.Options()
+	values := make([]int, len(options))
+	for i, option := range options {
+		values[i] = c.Value(option)
+	}
+	value := mex(values)
+	c.memo[key] = value
+	return value
+}
+
+// mex returns the minimum excluded value of values.
+func mex(values []int) int {
+	seen := make(map[int]bool, len(values))
+	for _, v := range values {
+		seen[v] = true
+	}
+	m := 0
+	for seen[m] {
+		m++
+	}
+	return m
+}
+
+// WinningOption returns a move from sum to a position of Grundy value 0, as
+// the index of the component to play in and the position that replaces it.
+// ok is false when sum is already lost.
+func (c *GrundyCalculator) WinningOption(sum Sum) (index int, option Impartial, ok bool) {
+	total := c.Value(sum)
+	if total == 0 {
+		return 0, nil, false
+	}
+	for i, component := range sum {
+		target := c.Value(component) ^ total
+		for _, option := range component.Options() {
+			if c.Value(option) == target {
+				return i, option, true
+			}
+		}
+	}
+	return 0, nil, false
+}
+
+// Sum is the disjunctive sum of impartial games: a move is made in exactly one
+// of the components.
+type Sum []Impartial
+
+// Options replaces one component by one of its options.
+func (s Sum) Options() []Impartial {
+	var options []Impartial
+	for i, component := range s {
+		for _, option := range component.Options() {
+			next := make(Sum, 0, len(s))
+			next = append(next, s[:i]...)
+			next = append(next, option)
+			next = append(next, s[i+1:]...)
+			options = append(options, next)
+		}
+	}
+	return options
+}
+
+// Key joins the sorted keys of the components.
+func (s Sum) Key() string {
+	keys := make([]string, len(s))
+	for i, component := range s {
+		keys[i] = component.Key()
+	}
+	sort.Strings(keys)
+	return "(" + strings.Join(keys, " + ") + ")"
+}
+
+// String lists the components.
+func (s Sum) String() string {
+	parts := make([]string, len(s))
+	for i, component := range s {
+		parts[i] = component.String()
+	}
+	return strings.Join(parts, " + ")
+}
+
+// pileGame is a single pile of stones played under a ruleset. Its moves come
+// from the same generator that Game.generateSuccessors uses, so with the
+// classic rules it is a Nim heap and with max-take=K a subtraction game.
+type pileGame struct {
+	stones int
+	rules  Ruleset
+}
+
+// Options returns the smaller piles reachable under the ruleset.
+func (p pileGame) Options() []Impartial {
+	var options []Impartial
+	for _, next := range p.rules.successors([]int{p.stones}) {
+		options = append(options, pileGame{stones: next[0], rules: p.rules})
+	}
+	return options
+}
+
+// Key identifies the pile size and the rules.
+func (p pileGame) Key() string {
+	return fmt.Sprintf("pile[%v]:%d", p.rules, p.stones)
+}
+
+// String describes the pile, e.g. "pile of 7 (max-take=3)".
+func (p pileGame) String() string {
+	return fmt.Sprintf("pile of %d (%v)", p.stones, p.rules)
+}
+
+// kaylesRow is a row of pins in Kayles. A move knocks down one pin or two
+// adjacent pins, which may split the row in two.
+type kaylesRow int
+
+// Options returns the rows left after every possible throw.
+func (k kaylesRow) Options() []Impartial {
+	var options []Impartial
+	for width := 1; width <= 2; width++ {
+		// Throws are symmetric, so only those starting in the left half are needed.
+		for left := 0; 2*left <= int(k)-width; left++ {
+			right := int(k) - width - left
+			options = append(options, Sum{kaylesRow(left), kaylesRow(right)})
+		}
+	}
+	return options
+}
+
+// Key identifies the row length.
+func (k kaylesRow) Key() string {
+	return "kayles:" + strconv.Itoa(int(k))
+}
+
+// String describes the row, e.g. "Kayles row of 5".
+func (k kaylesRow) String() string {
+	return fmt.Sprintf("Kayles row of %d", int(k))
+}
+
+// parseSum reads a sum of games such as "nim:3,sub3:7,kayles:5": nim:N is a Nim
+// heap, subK:N a pile from which at most K stones may be taken and kayles:N a
+// Kayles row of N pins.
+func parseSum(spec string) (Sum, error) {
+	var sum Sum
+	for _, part := range strings.Split(spec, ",") {
+		kind, size, ok := strings.Cut(strings.TrimSpace(part), ":")
+		n, err := strconv.Atoi(size)
+		if !ok || err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid game %q (want KIND:SIZE)", part)
+		}
+		switch {
+		case kind == "nim":
+			sum = append(sum, pileGame{stones: n})
+		case strings.HasPrefix(kind, "sub"):
+			k, err := strconv.Atoi(strings.TrimPrefix(kind, "sub"))
+			if err != nil || k < 1 {
+				return nil, fmt.Errorf("invalid subtraction game %q (want subK:SIZE)", part)
+			}
+			sum = append(sum, pileGame{stones: n, rules: Ruleset{MaxTake: k}})
+		case kind == "kayles":
+			sum = append(sum, kaylesRow(n))
+		default:
+			return nil, fmt.Errorf("unknown game %q (want nim, subK or kayles)", kind)
+		}
+	}
+	return sum, nil
+}
+
+// analyseSum prints the Grundy value of every component of sum, their nim-sum
+// and the winning move if there is one.
+func analyseSum(sum Sum) {
+	calculator := NewGrundyCalculator()
+	for _, component := range sum {
+		fmt.Printf("%-30s Grundy value %d\n", component, calculator.Value(component))
+	}
+	fmt.Printf("Nim-sum of the values: %d\n", calculator.Value(sum))
+	if i, option, ok := calculator.WinningOption(sum); ok {
+		fmt.Printf("Winning move: replace %v by %v\n", sum[i], option)
+	} else {
+		fmt.Println("The position is lost for the player to move.")
+	}
+}
+
+// playSum plays a sum of impartial games between the console player, who
+// types numbers on in, and an AI that moves to a position of Grundy value 0
+// whenever it can. It returns errNoInput when in ends before the game does.
+func playSum(sum Sum, in io.Reader) error {
+	calculator := NewGrundyCalculator()
+	input := newHumanPlayer("Player", in, os.Stdout)
+	for {
+		if len(sum.Options()) == 0 {
+			fmt.Println("Game over! You cannot move, AI wins.")
+			return nil
+		}
+		for {
+			fmt.Println("Current games:")
+			for i, component := range sum {
+				fmt.Printf("Game %d: %v\n", i+1, component)
+			}
+			fmt.Print("Enter game number (1, 2, ...): ")
+			index, err := input.readInt()
+			if err != nil {
+				return err
+			}
+			if index < 1 || index > len(sum) || len(sum[index-1].Options()) == 0 {
+				fmt.Println("Invalid move! Please try again.")
+				continue
+			}
+			options := sum[index-1].Options()
+			for i, option := range options {
+				fmt.Printf("  %d: %v\n", i+1, option)
+			}
+			fmt.Print("Enter option number: ")
+			choice, err := input.readInt()
+			if err != nil {
+				return err
+			}
+			if choice >= 1 && choice <= len(options) {
+				sum[index-1] = options[choice-1]
+				break
+			}
+			fmt.Println("Invalid move! Please try again.")
+		}
+
+		if len(sum.Options()) == 0 {
+			fmt.Println("Game over! AI cannot move, you win.")
+			return nil
+		}
+		i, option, ok := calculator.WinningOption(sum)
+		if !ok {
+			// Lost position: make any move and hope for a mistake.
+			for i = range sum {
+				if options := sum[i].Options(); len(options) > 0 {
+					option = options[0]
+					break
+				}
+			}
+		}
+		fmt.Printf("AI replaces %v by %v.\n", sum[i], option)
+		sum[i] = option
+	}
+}
@@ -9,62 +9,43 @@
 package main
 
 import (
-	"container/heap"
+	"flag"
 	"fmt"
-	_ "math"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
 )
 
 // Game represents a Nim game with multiple piles of stones.
+// turn is the index of the player to move; when record is set, play logs
+// every move in it.
 type Game struct {
-	piles []int
+	piles  []int
+	rules  Ruleset
+	turn   int
+	record *GameRecord
 }
 
-// A* Priority Queue Element
-type Node struct {
-	state    []int // The configuration of the piles
-	cost     int   // g(n): Cost to reach this node (depth)
-	priority int   // f(n): g(n) + h(n), priority in A*
-	index    int   // The index of the node in the heap
-}
-
-// A Priority Queue implementation using heap.Interface
-type PriorityQueue []*Node
-
-func (pq PriorityQueue) Len() int           { return len(pq) }
-
-func (pq PriorityQueue) Less(i, j int) bool { return pq[i].priority < pq[j].priority }
-
-func (pq PriorityQueue) Swap(i, j int) {
-	pq[i], pq[j] = pq[j], pq[i]
-	pq[i].index, pq[j].index = i, j
-}
-
-func (pq *PriorityQueue) Push(x interface{}) {
-	n := len(*pq)
-	node := x.(*Node)
-	node.index = n
-	*pq = append(*pq, node)
-}
-func (pq *PriorityQueue) Pop() interface{} {
-	old := *pq
-	n := len(old)
-	node := old[n-1]
-	node.index = -1 // for safety
-	*pq = old[0 : n-1]
-	return node
-}
-
-// displayPiles prints the current state of the piles to the console.
-func (g *Game) displayPiles() {
-	fmt.Println("Current piles:")
+// displayPiles prints the current state of the piles to w.
+func (g *Game) displayPiles(w io.Writer) {
+	fmt.Fprintln(w, "Current piles:")
 	for i, pile := range g.piles {
-		fmt.Printf("Pile %d: %d\n", i+1, pile)
+		fmt.Fprintf(w, "Pile %d: %d\n", i+1, pile)
 	}
 }
 
 // isGameOver checks if the game is over, which occurs when all piles are empty.
+// Every supported ruleset allows taking a stone from any non-empty pile, so
+// this is also the only position without legal moves.
 func (g *Game) isGameOver() bool {
-	for _, pile := range g.piles {
+	return isEmpty(g.piles)
+}
+
+// isEmpty reports whether every pile in state is empty.
+func isEmpty(state []int) bool {
+	for _, pile := range state {
 		if pile > 0 {
 			return false
 		}
@@ -73,129 +54,273 @@ func (g *Game) isGameOver() bool {
 }
 
 // removeStones updates the specified pile by removing the given number of stones.
+// The move is rejected when the ruleset does not allow it.
 func (g *Game) removeStones(pileIndex, stones int) {
-	if pileIndex < 0 || pileIndex >= len(g.piles) || stones <= 0 || stones > g.piles[pileIndex] {
-		fmt.Println("Invalid move!")
+	if err := g.rules.checkTake(g.piles, pileIndex, stones); err != nil {
+		fmt.Println("Invalid move!", err)
 		return
 	}
 	g.piles[pileIndex] -= stones
 }
 
-// heuristic estimates the remaining cost (h(n)) to a winning position for AI.
-// A simple heuristic is to count the total number of stones.
-func (g *Game) heuristic(state []int) int {
-	stoneCount := 0
-	for _, pile := range state {
-		stoneCount += pile
-	}
-	return stoneCount
+// generateSuccessors returns all possible states (after AI move) from the current state
+// that are allowed by the ruleset of the game.
+func (g *Game) generateSuccessors(state []int) [][]int {
+	return g.rules.successors(state)
 }
 
-// generateSuccessors returns all possible states (after AI move) from the current state.
-func (g *Game) generateSuccessors(state []int) [][]int {
-	var successors [][]int
-	for i, pile := range state {
-		for stones := 1; stones <= pile; stones++ {
-			newState := make([]int, len(state))
-			copy(newState, state)
-			newState[i] -= stones
-			successors = append(successors, newState)
+// play runs the game between players[0], who moves first, and players[1]
+// until it is over, reporting the moves to out. A resumed game continues
+// with players[g.turn]. It returns the index of the winner and the number of
+// moves played. Who wins on the empty table depends on whether the ruleset is misère.
+func (g *Game) play(players [2]Player, out io.Writer) (winner, moves int, err error) {
+	fmt.Fprintf(out, "Playing Nim with rules: %v\n", g.rules)
+	for !g.isGameOver() {
+		player := players[g.turn]
+		next, err := player.Move(g)
+		if err != nil {
+			return 0, moves, fmt.Errorf("%s could not move: %w", player.Name(), err)
+		}
+		if !g.isSuccessor(next) {
+			return 0, moves, fmt.Errorf("%s made an illegal move %v -> %v", player.Name(), g.piles, next)
 		}
+		fmt.Fprintf(out, "%s: %s\n", player.Name(), describeMove(g.piles, next))
+		g.apply(next)
+		moves++
 	}
-	return successors
+	winner = g.winner()
+	fmt.Fprintf(out, "Game over! %s wins.\n", players[winner].Name())
+	return winner, moves, nil
 }
 
-func (g *Game) aiMove() {
-	fmt.Println("AI is making its move using A*...")
-	pq := make(PriorityQueue, 0)
-	heap.Init(&pq)
-
-	initialState := g.piles
-	initialNode := &Node{
-		state:    initialState,
-		cost:     0,
-		priority: g.heuristic(initialState),
-	}
-	heap.Push(&pq, initialNode)
-
-	visited := make(map[string]bool)
-
-	for pq.Len() > 0 {
-		currentNode := heap.Pop(&pq).(*Node)
-
-		// Check if this state is game over (AI should leave all piles empty)
-		if g.heuristic(currentNode.state) == 0 { // All piles are empty
-			g.piles = currentNode.state
-			fmt.Println("AI finished its move.")
-			return
-		}
-
-		// Generate possible next moves (successor states)
-		successors := g.generateSuccessors(currentNode.state)
-		for _, successor := range successors {
-			stateKey := fmt.Sprint(successor)
-			if !visited[stateKey] {
-				visited[stateKey] = true
-				gScore := currentNode.cost + 1
-				hScore := g.heuristic(successor)
-				fScore := gScore + hScore
-
-				// Push successor to priority queue
-				newNode := &Node{
-					state:    successor,
-					cost:     gScore,
-					priority: fScore,
-				}
-				heap.Push(&pq, newNode)
-
-				// Set the game's piles to this state (AI's move)
-				g.piles = successor
-				fmt.Println("AI makes a move:")
-				//g.displayPiles()
-				return
-			}
-		}
+// apply makes next the current position, logs the move in the record and
+// passes the turn. next must be a legal successor of the current piles.
+func (g *Game) apply(next []int) {
+	if g.record != nil {
+		g.record.Moves = append(g.record.Moves, RecordedMove{Player: g.turn, Takes: takesBetween(g.piles, next)})
+	}
+	g.piles = next
+	g.turn = 1 - g.turn
+	if g.record != nil && g.isGameOver() {
+		g.record.Result = &RecordResult{Winner: g.winner()}
 	}
 }
 
-// playerMove is unchanged, allowing the player to make a move
-func (g *Game) playerMove() {
-	var pileIndex, stones int
-	for {
-		g.displayPiles()
-		fmt.Print("Enter pile number (1, 2, ...): ")
-		fmt.Scan(&pileIndex)
-		fmt.Print("Enter number of stones to remove: ")
-		fmt.Scan(&stones)
-		if pileIndex > 0 && pileIndex <= len(g.piles) {
-			if stones > 0 && stones <= g.piles[pileIndex-1] {
-				break
-			}
-		}
-		fmt.Println("Invalid move! Please try again.")
+// winner returns the index of the player who won a finished game. g.turn is
+// then the player who did not take the last stone.
+func (g *Game) winner() int {
+	if g.rules.lastMoverWins() {
+		return 1 - g.turn
 	}
-	g.removeStones(pileIndex-1, stones)
+	return g.turn
 }
 
-// play starts the game loop, alternating between player and AI moves until the game is over.
-func (g *Game) play() {
-	for !g.isGameOver() {
-		g.playerMove()
-		if g.isGameOver() {
-			fmt.Println("Game over! You win.")
-			return
-		}
-		g.aiMove()
-		if g.isGameOver() {
-			fmt.Println("Game over! AI wins.")
-			return
+// isSuccessor reports whether next can be reached from the current piles in
+// one legal move.
+func (g *Game) isSuccessor(next []int) bool {
+	return g.rules.isMove(g.piles, next)
+}
+
+// parsePiles reads a comma separated list of pile sizes such as "3,4,5".
+func parsePiles(spec string) ([]int, error) {
+	var piles []int
+	for _, field := range strings.Split(spec, ",") {
+		pile, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil || pile < 0 {
+			return nil, fmt.Errorf("invalid pile size %q", field)
 		}
+		piles = append(piles, pile)
 	}
+	return piles, nil
 }
 
 // main initializes the game with a starting configuration and begins play.
+// With -verify it instead brute-forces small positions to check the solver,
+// with -tournament it lets two computer players compete and with -serve it
+// lets remote clients play over HTTP.
 func main() {
-	initialPiles := []int{3, 4, 5} // Example starting configuration
-	game := Game{piles: initialPiles}
-	game.play()
+	fallbackName := flag.String("fallback", "stall", "AI strategy in lost positions: stall, greedy or random")
+	seed := flag.Int64("seed", 1, "random seed for the random fallback and mcts")
+	verify := flag.Bool("verify", false, "verify the solver against brute force and exit")
+	rulesSpec := flag.String("rules", "normal", "game variant, e.g. normal, misere, max-take=3, max-piles=2 or a comma separated combination")
+	pilesSpec := flag.String("piles", "3,4,5", "comma separated starting pile sizes")
+	aiKind := flag.String("ai", "solver", "AI player: "+playerKinds)
+	depth := flag.Int("depth", 0, "alphabeta depth limit in plies, 0 for unlimited")
+	think := flag.Duration("think", 0, "alphabeta and mcts time limit per move, 0 for unlimited (2s with -serve)")
+	iterations := flag.Int("iterations", 2000, "mcts iterations per move, 0 for unlimited")
+	sumSpec := flag.String("sum", "", "play a sum of impartial games instead, e.g. nim:3,sub3:7,kayles:5")
+	grundySpec := flag.String("grundy", "", "print the Grundy values of a sum of impartial games and exit")
+	tournament := flag.Bool("tournament", false, "play AI against AI and report statistics instead of a console game")
+	player1 := flag.String("p1", "solver", "first tournament player: "+playerKinds)
+	player2 := flag.String("p2", "mcts", "second tournament player: "+playerKinds)
+	games := flag.Int("games", 100, "number of tournament games")
+	pileCount := flag.Int("pile-count", 3, "number of piles in tournament games")
+	maxStones := flag.Int("max-stones", 7, "maximum size of a random tournament pile")
+	asJSON := flag.Bool("json", false, "print the tournament report as JSON")
+	recordPath := flag.String("record", "", "save the console game as a JSON game record to this file")
+	loadPath := flag.String("load", "", "resume the console game from a game record")
+	replayPath := flag.String("replay", "", "step through a game record and exit")
+	serveAddr := flag.String("serve", "", "serve games over HTTP and WebSocket on this address, e.g. :8080")
+	hints := flag.Bool("hints", false, "analyse every position for the console player and point out mistakes")
+	checkPath := flag.String("check", "", "replay a game record, report where the -ai player would move differently and exit")
+	flag.Parse()
+
+	if *verify {
+		if err := verifySolver(4, 6); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *sumSpec != "" && *grundySpec != "" {
+		fmt.Println("-sum and -grundy cannot be used together")
+		os.Exit(2)
+	}
+	if *sumSpec != "" || *grundySpec != "" {
+		spec := *sumSpec + *grundySpec
+		sum, err := parseSum(spec)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(2)
+		}
+		if *grundySpec != "" {
+			analyseSum(sum)
+		} else if err := playSum(sum, os.Stdin); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	fallback, err := parseFallback(*fallbackName)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(2)
+	}
+	rules, err := parseRuleset(*rulesSpec)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(2)
+	}
+	opts := aiOptions{fallback: fallback, seed: *seed, depth: *depth, think: *think, iterations: *iterations}
+
+	if *serveAddr != "" {
+		fmt.Printf("Serving Nim on %s\n", *serveAddr)
+		if err := http.ListenAndServe(*serveAddr, newServer(opts)); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *replayPath != "" {
+		record, _, err := loadRecord(*replayPath)
+		if err == nil {
+			err = showReplay(record, os.Stdin, os.Stdout)
+		}
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *checkPath != "" {
+		record, _, err := loadRecord(*checkPath)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		if rules, err = parseRuleset(record.Rules); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		ai, err := newPlayer(*aiKind, rules, opts)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(2)
+		}
+		differences, err := checkRecord(record, ai)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		for _, difference := range differences {
+			fmt.Println(difference)
+		}
+		if len(differences) > 0 {
+			os.Exit(1)
+		}
+		fmt.Printf("All moves of %s match the record.\n", ai.Name())
+		return
+	}
+
+	if *tournament {
+		players := [2]Player{}
+		for i, kind := range []string{*player1, *player2} {
+			if players[i], err = newPlayer(kind, rules, opts); err != nil {
+				fmt.Println(err)
+				os.Exit(2)
+			}
+			opts.seed++
+		}
+		config := TournamentConfig{Games: *games, Piles: *pileCount, MaxStones: *maxStones, Rules: rules, Seed: *seed}
+		report, err := runTournament(players, config)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		if *asJSON {
+			err = report.writeJSON(os.Stdout)
+		} else {
+			err = report.writeTable(os.Stdout)
+		}
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	game := &Game{rules: rules}
+	if *loadPath != "" {
+		var record *GameRecord
+		record, game, err = loadRecord(*loadPath)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		rules = game.rules
+		game.record = record
+		fmt.Printf("Resuming game after %d moves.\n", len(record.Moves))
+	} else if game.piles, err = parsePiles(*pilesSpec); err != nil {
+		fmt.Println(err)
+		os.Exit(2)
+	}
+	opts.verbose = os.Stdout
+	ai, err := newPlayer(*aiKind, rules, opts)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(2)
+	}
+	human := newHumanPlayer("Player", os.Stdin, os.Stdout)
+	if *hints {
+		human.coach = NewSolver(rules, fallback, *seed)
+	}
+	players := [2]Player{human, ai}
+	if game.record == nil {
+		game.record = newRecord(game, [2]string{players[0].Name(), players[1].Name()})
+	}
+	_, _, err = game.play(players, os.Stdout)
+	if *recordPath != "" {
+		// An interrupted game is saved as well, so that it can be resumed.
+		if err := game.record.save(*recordPath); err != nil {
+			fmt.Println("Could not save the game record:", err)
+		}
+	}
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
 }
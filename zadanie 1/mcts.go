@@ -0,0 +1,156 @@
+package main
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"time"
+)
+
+// MCTS chooses moves with Monte Carlo Tree Search: it grows a search tree by
+// UCT selection, expands one new state per iteration, finishes the game with
+// uniformly random moves and backs the result up the tree. Unlike Solver and
+// Searcher it is not perfect, but its strength is tuned by the budget alone.
+type MCTS struct {
+	Iterations  int           // iteration budget per move, 0 means unlimited
+	TimeLimit   time.Duration // time budget per move, 0 means unlimited
+	Exploration float64       // UCT exploration constant
+
+	// Stats holds the statistics of the root moves of the last search,
+	// most visited first.
+	Stats []MoveStat
+
+	rng *rand.Rand
+}
+
+// MoveStat describes how a candidate move fared in the search.
+type MoveStat struct {
+	Move    Action
+	Visits  int
+	Wins    float64 // sum of rewards, a draw counts as half a win
+	WinRate float64
+}
+
+// mctsNode is a state in the search tree.
+type mctsNode struct {
+	state    GameState
+	move     Action // move that led here from parent
+	mover    int    // player who played move
+	parent   *mctsNode
+	children []*mctsNode
+	untried  []Action
+	visits   int
+	wins     float64 // rewards from the point of view of mover
+}
+
+// NewMCTS returns an MCTS player with the given budgets; at least one of them
+// should be non-zero. When both are zero it runs 1000 iterations.
+func NewMCTS(iterations int, timeLimit time.Duration, seed int64) *MCTS {
+	if iterations == 0 && timeLimit == 0 {
+		iterations = 1000
+	}
+	return &MCTS{
+		Iterations:  iterations,
+		TimeLimit:   timeLimit,
+		Exploration: math.Sqrt2,
+		rng:         rand.New(rand.NewSource(seed)),
+	}
+}
+
+// BestMove searches from state within the budget and returns the most visited
+// move, or nil for a terminal state. Statistics are left in m.Stats.
+func (m *MCTS) BestMove(state GameState) Action {
+	m.Stats = nil
+	if state.Terminal() {
+		return nil
+	}
+	root := &mctsNode{state: state, mover: 1 - state.CurrentPlayer(), untried: state.Moves()}
+	if len(root.untried) == 0 {
+		return nil
+	}
+
+	deadline := time.Now().Add(m.TimeLimit)
+	for i := 0; m.Iterations == 0 || i < m.Iterations; i++ {
+		if m.TimeLimit > 0 && time.Now().After(deadline) {
+			break
+		}
+		node := m.selectNode(root)
+		node = m.expand(node)
+		utility := m.rollout(node.state)
+		for ; node != nil; node = node.parent {
+			node.visits++
+			node.wins += float64(utility(node.mover)+1) / 2
+		}
+	}
+
+	for _, child := range root.children {
+		m.Stats = append(m.Stats, MoveStat{
+			Move:    child.move,
+			Visits:  child.visits,
+			Wins:    child.wins,
+			WinRate: child.wins / float64(child.visits),
+		})
+	}
+	sort.SliceStable(m.Stats, func(i, j int) bool {
+		return m.Stats[i].Visits > m.Stats[j].Visits
+	})
+	if len(m.Stats) == 0 {
+		return root.untried[0]
+	}
+	return m.Stats[0].Move
+}
+
+// selectNode descends from node along the children with the highest UCT
+// value until it reaches a node that is terminal or not fully expanded.
+func (m *MCTS) selectNode(node *mctsNode) *mctsNode {
+	for len(node.untried) == 0 && len(node.children) > 0 {
+		var best *mctsNode
+		bestValue := math.Inf(-1)
+		logVisits := math.Log(float64(node.visits))
+		for _, child := range node.children {
+			value := child.wins/float64(child.visits) +
+				m.Exploration*math.Sqrt(logVisits/float64(child.visits))
+			if value > bestValue {
+				best, bestValue = child, value
+			}
+		}
+		node = best
+	}
+	return node
+}
+
+// expand adds a random untried move of node as a new child and returns it.
+// A terminal node is returned unchanged.
+func (m *MCTS) expand(node *mctsNode) *mctsNode {
+	if len(node.untried) == 0 {
+		return node
+	}
+	i := m.rng.Intn(len(node.untried))
+	move := node.untried[i]
+	node.untried[i] = node.untried[len(node.untried)-1]
+	node.untried = node.untried[:len(node.untried)-1]
+
+	state := node.state.Apply(move)
+	child := &mctsNode{
+		state:   state,
+		move:    move,
+		mover:   node.state.CurrentPlayer(),
+		parent:  node,
+		untried: state.Moves(),
+	}
+	node.children = append(node.children, child)
+	return child
+}
+
+// rollout plays random moves from state until the game ends and returns the
+// utility function of the final state.
+func (m *MCTS) rollout(state GameState) func(player int) int {
+	for !state.Terminal() {
+		moves := state.Moves()
+		if len(moves) == 0 {
+			return func(int) int { return 0 }
+		}
+		state = state.Apply(moves[m.rng.Intn(len(moves))])
+	}
+	return state.Utility
+}
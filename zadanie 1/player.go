@@ -0,0 +1,208 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"strconv"
+	"time"
+)
+
+// Player chooses moves in a Game.
+type Player interface {
+	// Name identifies the player in messages and reports.
+	Name() string
+	// Move returns the piles after the player's move. The game must not be over
+	// and is not modified.
+	Move(g *Game) ([]int, error)
+}
+
+// errNoInput is returned by a human player whose input has ended.
+var errNoInput = errors.New("no more input")
+
+// humanPlayer reads moves as whitespace separated numbers from in and writes
+// prompts to out, so a game can be played from the console or from a script.
+// When coach is set, the player is shown an analysis of every position and
+// told when a move threw away a win.
+type humanPlayer struct {
+	name  string
+	words *bufio.Scanner
+	out   io.Writer
+	coach *Solver
+}
+
+// newHumanPlayer returns a player reading moves from in.
+func newHumanPlayer(name string, in io.Reader, out io.Writer) *humanPlayer {
+	words := bufio.NewScanner(in)
+	words.Split(bufio.ScanWords)
+	return &humanPlayer{name: name, words: words, out: out}
+}
+
+// Name returns the name given to newHumanPlayer.
+func (p *humanPlayer) Name() string {
+	return p.name
+}
+
+// readInt reads the next number. Input that is not a number yields -1, which
+// is never a valid pile or stone count.
+func (p *humanPlayer) readInt() (int, error) {
+	if !p.words.Scan() {
+		if err := p.words.Err(); err != nil {
+			return 0, err
+		}
+		return 0, errNoInput
+	}
+	n, err := strconv.Atoi(p.words.Text())
+	if err != nil {
+		return -1, nil
+	}
+	return n, nil
+}
+
+// Move asks for a pile and a number of stones. When the ruleset allows taking
+// from several piles at once, the player may continue with further piles.
+func (p *humanPlayer) Move(g *Game) ([]int, error) {
+	trial := Game{piles: append([]int(nil), g.piles...), rules: g.rules}
+	if p.coach != nil {
+		printAnalysis(p.out, g.piles, analyzePosition(g.piles, p.coach))
+	}
+	used := make(map[int]bool)
+	for len(used) < g.rules.pileLimit() {
+		trial.displayPiles(p.out)
+		if len(used) == 0 {
+			fmt.Fprint(p.out, "Enter pile number (1, 2, ...): ")
+		} else {
+			fmt.Fprint(p.out, "Enter another pile number (0 to finish): ")
+		}
+		pileIndex, err := p.readInt()
+		if err != nil {
+			return nil, err
+		}
+		if pileIndex == 0 && len(used) > 0 {
+			break
+		}
+		fmt.Fprint(p.out, "Enter number of stones to remove: ")
+		stones, err := p.readInt()
+		if err != nil {
+			return nil, err
+		}
+		if err := g.rules.checkTake(trial.piles, pileIndex-1, stones); err != nil || used[pileIndex] {
+			fmt.Fprintln(p.out, "Invalid move! Please try again.")
+			continue
+		}
+		used[pileIndex] = true
+		trial.removeStones(pileIndex-1, stones)
+	}
+	if p.coach != nil && p.coach.isWon(g.piles) && p.coach.isWon(trial.piles) {
+		fmt.Fprintf(p.out, "Mistake: %s turns your winning position into a losing one.\n", describeMove(g.piles, trial.piles))
+		fmt.Fprintf(p.out, "A winning move was: %s.\n", describeMove(g.piles, p.coach.winningMoves(g.piles)[0]))
+	}
+	return trial.piles, nil
+}
+
+// solverPlayer plays with the exact Solver.
+type solverPlayer struct {
+	solver *Solver
+}
+
+func (p solverPlayer) Name() string { return "solver" }
+
+func (p solverPlayer) Move(g *Game) ([]int, error) {
+	return p.solver.nextState(g.piles), nil
+}
+
+// searchPlayer plays with alpha-beta search over nimState.
+type searchPlayer struct {
+	searcher *Searcher
+}
+
+func (p searchPlayer) Name() string { return "alphabeta" }
+
+func (p searchPlayer) Move(g *Game) ([]int, error) {
+	move, _ := p.searcher.BestMove(nimState{piles: g.piles, rules: g.rules})
+	return move.(nimAction).to, nil
+}
+
+// mctsPlayer plays with Monte Carlo Tree Search. When out is set, the
+// statistics of the most visited moves are printed after every search.
+type mctsPlayer struct {
+	mcts *MCTS
+	out  io.Writer
+}
+
+func (p mctsPlayer) Name() string { return "mcts" }
+
+func (p mctsPlayer) Move(g *Game) ([]int, error) {
+	next := p.mcts.BestMove(nimState{piles: g.piles, rules: g.rules}).(nimAction).to
+	if p.out != nil {
+		fmt.Fprintln(p.out, "Most visited candidate moves:")
+		for i, stat := range p.mcts.Stats {
+			if i == 5 {
+				break
+			}
+			fmt.Fprintf(p.out, "  %-40s visits %6d  win rate %5.1f%%\n", stat.Move, stat.Visits, 100*stat.WinRate)
+		}
+	}
+	return next, nil
+}
+
+// randomPlayer plays a uniformly random legal move.
+type randomPlayer struct {
+	rng *rand.Rand
+}
+
+func (p randomPlayer) Name() string { return "random" }
+
+func (p randomPlayer) Move(g *Game) ([]int, error) {
+	successors := g.generateSuccessors(g.piles)
+	return successors[p.rng.Intn(len(successors))], nil
+}
+
+// greedyPlayer takes as many stones as the rules allow from the largest pile.
+type greedyPlayer struct{}
+
+func (greedyPlayer) Name() string { return "greedy" }
+
+func (greedyPlayer) Move(g *Game) ([]int, error) {
+	next := append([]int(nil), g.piles...)
+	largest := 0
+	for i, pile := range next {
+		if pile > next[largest] {
+			largest = i
+		}
+	}
+	next[largest] -= g.rules.takeLimit(next[largest])
+	return next, nil
+}
+
+// aiOptions configures the players built by newPlayer.
+type aiOptions struct {
+	fallback   Fallback
+	seed       int64
+	depth      int
+	think      time.Duration
+	iterations int
+	verbose    io.Writer // where players may explain their moves, nil for silence
+}
+
+// playerKinds lists the names accepted by newPlayer.
+const playerKinds = "solver, alphabeta, mcts, random or greedy"
+
+// newPlayer builds a computer player by name for the given rules.
+func newPlayer(kind string, rules Ruleset, opts aiOptions) (Player, error) {
+	switch kind {
+	case "solver":
+		return solverPlayer{NewSolver(rules, opts.fallback, opts.seed)}, nil
+	case "alphabeta":
+		return searchPlayer{NewSearcher(opts.depth, opts.think)}, nil
+	case "mcts":
+		return mctsPlayer{mcts: NewMCTS(opts.iterations, opts.think, opts.seed), out: opts.verbose}, nil
+	case "random":
+		return randomPlayer{rand.New(rand.NewSource(opts.seed))}, nil
+	case "greedy":
+		return greedyPlayer{}, nil
+	}
+	return nil, fmt.Errorf("unknown player %q (want %s)", kind, playerKinds)
+}
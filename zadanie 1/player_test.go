@@ -0,0 +1,47 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestHumanPlayerScriptedInput(t *testing.T) {
+	tests := []struct {
+		rules   string
+		input   string
+		invalid int
+		want    string
+	}{
+		// a word instead of a pile, too many stones, then a valid move
+		{"normal", "abc 1  2 9  2 3", 2, "[3 1 5]"},
+		// a second take from the same pile is rejected, 0 ends the move
+		{"max-piles=2", "1 1  1 1  0", 1, "[2 4 5]"},
+		{"max-piles=2", "1 1  3 2", 0, "[2 4 3]"},
+	}
+	for _, tt := range tests {
+		rules, err := parseRuleset(tt.rules)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var out strings.Builder
+		p := newHumanPlayer("you", strings.NewReader(tt.input), &out)
+		g := &Game{piles: []int{3, 4, 5}, rules: rules}
+		next, err := p.Move(g)
+		if err != nil {
+			t.Fatalf("%s %q: %v", tt.rules, tt.input, err)
+		}
+		if fmt.Sprint(next) != tt.want || fmt.Sprint(g.piles) != "[3 4 5]" {
+			t.Errorf("%s %q: expected %s with the game unchanged, found %v and %v", tt.rules, tt.input, tt.want, next, g.piles)
+		}
+		if invalid := strings.Count(out.String(), "Invalid move!"); invalid != tt.invalid {
+			t.Errorf("%s %q: expected %d invalid moves, found %d", tt.rules, tt.input, tt.invalid, invalid)
+		}
+
+		// the input has ended, so the next move cannot be read
+		if _, err := p.Move(g); !errors.Is(err, errNoInput) {
+			t.Errorf("%s %q: expected errNoInput, found %v", tt.rules, tt.input, err)
+		}
+	}
+}
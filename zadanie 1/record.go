@@ -0,0 +1,250 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+)
+
+// recordFormat identifies the version of the game record format.
+const recordFormat = "nim-record/1"
+
+// GameRecord is the move log of a match. It is stored as JSON:
+//
+//	{
+//	  "format": "nim-record/1",
+//	  "rules": "misere,max-take=3",
+//	  "piles": [3, 4, 5],
+//	  "players": ["Player", "solver"],
+//	  "moves": [
+//	    {"player": 0, "takes": [{"pile": 2, "stones": 3}]},
+//	    {"player": 1, "takes": [{"pile": 1, "stones": 1}, {"pile": 3, "stones": 2}]}
+//	  ],
+//	  "result": {"winner": 1}
+//	}
+//
+// rules uses the syntax of the -rules flag and piles are the starting piles.
+// players[0] moves first and player in a move is an index into players.
+// Piles in takes are numbered from 1 as on screen; a move has several takes
+// only in Moore's Nim. result is omitted while the game is unfinished.
+type GameRecord struct {
+	Format  string         `json:"format"`
+	Rules   string         `json:"rules"`
+	Piles   []int          `json:"piles"`
+	Players [2]string      `json:"players"`
+	Moves   []RecordedMove `json:"moves"`
+	Result  *RecordResult  `json:"result,omitempty"`
+}
+
+// RecordedMove is one move of a GameRecord.
+type RecordedMove struct {
+	Player int    `json:"player"`
+	Takes  []Take `json:"takes"`
+}
+
+// Take removes Stones stones from pile Pile, counted from 1.
+type Take struct {
+	Pile   int `json:"pile"`
+	Stones int `json:"stones"`
+}
+
+// RecordResult is the outcome of a finished game.
+type RecordResult struct {
+	Winner int `json:"winner"`
+}
+
+// newRecord starts a record of a game that begins in the position of g,
+// played by the players with the given names.
+func newRecord(g *Game, names [2]string) *GameRecord {
+	return &GameRecord{
+		Format:  recordFormat,
+		Rules:   g.rules.String(),
+		Piles:   append([]int(nil), g.piles...),
+		Players: names,
+	}
+}
+
+// takesBetween lists the takes that turn piles from into piles to.
+func takesBetween(from, to []int) []Take {
+	var takes []Take
+	for i := range from {
+		if taken := from[i] - to[i]; taken > 0 {
+			takes = append(takes, Take{Pile: i + 1, Stones: taken})
+		}
+	}
+	return takes
+}
+
+// applyTakes returns the piles after the takes of a recorded move, checking
+// that the move is legal under the rules.
+func applyTakes(rules Ruleset, piles []int, takes []Take) ([]int, error) {
+	if len(takes) == 0 {
+		return nil, fmt.Errorf("move takes no stones")
+	}
+	next := append([]int(nil), piles...)
+	for _, take := range takes {
+		if err := rules.checkTake(next, take.Pile-1, take.Stones); err != nil {
+			return nil, err
+		}
+		next[take.Pile-1] -= take.Stones
+	}
+	current := Game{piles: piles, rules: rules}
+	if !current.isSuccessor(next) {
+		return nil, fmt.Errorf("takes %v are not a single legal move", takes)
+	}
+	return next, nil
+}
+
+// save writes the record as indented JSON to path.
+func (r *GameRecord) save(path string) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// loadRecord reads a record from path and replays it to check that it is
+// consistent. It returns the record and the game in its final position.
+func loadRecord(path string) (*GameRecord, *Game, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not read record: %w", err)
+	}
+	var r GameRecord
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, nil, fmt.Errorf("could not decode record: %w", err)
+	}
+	replay, err := newReplay(&r)
+	if err != nil {
+		return nil, nil, err
+	}
+	for replay.hasNext() {
+		if _, err := replay.step(); err != nil {
+			return nil, nil, err
+		}
+	}
+	g := replay.game
+	if r.Result != nil {
+		if !g.isGameOver() {
+			return nil, nil, fmt.Errorf("record has a result but the game is not over")
+		}
+		if expected := g.winner(); r.Result.Winner != expected {
+			return nil, nil, fmt.Errorf("record names player %d as winner, but player %d won", r.Result.Winner, expected)
+		}
+	}
+	return &r, &g, nil
+}
+
+// Replay steps through a recorded game move by move.
+type Replay struct {
+	record *GameRecord
+	game   Game
+	next   int // index of the next move to play
+}
+
+// newReplay validates the header of r and positions a replay at the start.
+func newReplay(r *GameRecord) (*Replay, error) {
+	if r.Format != recordFormat {
+		return nil, fmt.Errorf("unsupported record format %q (want %q)", r.Format, recordFormat)
+	}
+	rules, err := parseRuleset(r.Rules)
+	if err != nil {
+		return nil, err
+	}
+	for _, pile := range r.Piles {
+		if pile < 0 {
+			return nil, fmt.Errorf("record starts with a negative pile")
+		}
+	}
+	return &Replay{record: r, game: Game{piles: append([]int(nil), r.Piles...), rules: rules}}, nil
+}
+
+// hasNext reports whether there are moves left to replay.
+func (r *Replay) hasNext() bool {
+	return r.next < len(r.record.Moves)
+}
+
+// step plays the next recorded move and returns it.
+func (r *Replay) step() (RecordedMove, error) {
+	move := r.record.Moves[r.next]
+	if move.Player != r.game.turn {
+		return move, fmt.Errorf("move %d: player %d moved out of turn", r.next+1, move.Player)
+	}
+	if r.game.isGameOver() {
+		return move, fmt.Errorf("move %d: the game is already over", r.next+1)
+	}
+	next, err := applyTakes(r.game.rules, r.game.piles, move.Takes)
+	if err != nil {
+		return move, fmt.Errorf("move %d: %w", r.next+1, err)
+	}
+	r.game.piles = next
+	r.game.turn = 1 - r.game.turn
+	r.next++
+	return move, nil
+}
+
+// showReplay prints the recorded game move by move to out. When in is not
+// nil, it waits for a line from in before every move.
+func showReplay(r *GameRecord, in io.Reader, out io.Writer) error {
+	replay, err := newReplay(r)
+	if err != nil {
+		return err
+	}
+	var lines *bufio.Scanner
+	if in != nil {
+		lines = bufio.NewScanner(in)
+	}
+	fmt.Fprintf(out, "Replaying %s vs %s, rules: %s\n", r.Players[0], r.Players[1], r.Rules)
+	replay.game.displayPiles(out)
+	for replay.hasNext() {
+		if lines != nil {
+			fmt.Fprint(out, "Press Enter for the next move...")
+			lines.Scan()
+		}
+		before := append([]int(nil), replay.game.piles...)
+		move, err := replay.step()
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "Move %d, %s: %s\n", replay.next, r.Players[move.Player], describeMove(before, replay.game.piles))
+		replay.game.displayPiles(out)
+	}
+	if r.Result != nil {
+		fmt.Fprintf(out, "Game over! %s wins.\n", r.Players[r.Result.Winner])
+	} else {
+		fmt.Fprintln(out, "The recorded game is unfinished.")
+	}
+	return nil
+}
+
+// checkRecord replays r and asks player for a move wherever the recorded
+// player of the same name was to move. It returns a description of every
+// move where the choice differs, so a saved game pins the AI's decisions.
+func checkRecord(r *GameRecord, player Player) ([]string, error) {
+	replay, err := newReplay(r)
+	if err != nil {
+		return nil, err
+	}
+	var differences []string
+	for replay.hasNext() {
+		move := r.Moves[replay.next]
+		before := append([]int(nil), replay.game.piles...)
+		var chosen []int
+		if r.Players[move.Player] == player.Name() {
+			if chosen, err = player.Move(&replay.game); err != nil {
+				return nil, err
+			}
+		}
+		if _, err := replay.step(); err != nil {
+			return nil, err
+		}
+		if chosen != nil && fmt.Sprint(chosen) != fmt.Sprint(replay.game.piles) {
+			differences = append(differences, fmt.Sprintf("move %d from %v: recorded %q, %s plays %q",
+				replay.next, before, describeMove(before, replay.game.piles), player.Name(), describeMove(before, chosen)))
+		}
+	}
+	return differences, nil
+}
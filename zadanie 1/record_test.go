@@ -0,0 +1,119 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// pinnedGames are games of greedy against the solver from piles 3, 4, 5. The
+// recorded solver moves pin its decisions, so a change to the solver that
+// alters its play shows up here.
+var pinnedGames = []struct {
+	rules string
+	moves string
+}{
+	{"misere,max-take=3", `[
+		{"player": 0, "takes": [{"pile": 3, "stones": 3}]},
+		{"player": 1, "takes": [{"pile": 1, "stones": 1}]},
+		{"player": 0, "takes": [{"pile": 2, "stones": 3}]},
+		{"player": 1, "takes": [{"pile": 2, "stones": 1}]},
+		{"player": 0, "takes": [{"pile": 1, "stones": 2}]},
+		{"player": 1, "takes": [{"pile": 3, "stones": 1}]},
+		{"player": 0, "takes": [{"pile": 3, "stones": 1}]}
+	]`},
+	{"max-piles=2", `[
+		{"player": 0, "takes": [{"pile": 3, "stones": 5}]},
+		{"player": 1, "takes": [{"pile": 1, "stones": 3}, {"pile": 2, "stones": 4}]}
+	]`},
+}
+
+// playRecorded plays greedy against the solver under rules and returns the record.
+func playRecorded(t *testing.T, rules Ruleset) *GameRecord {
+	t.Helper()
+	greedy, err := newPlayer("greedy", rules, aiOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	solver, err := newPlayer("solver", rules, aiOptions{seed: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := &Game{piles: []int{3, 4, 5}, rules: rules}
+	g.record = newRecord(g, [2]string{greedy.Name(), solver.Name()})
+	if _, _, err := g.play([2]Player{greedy, solver}, io.Discard); err != nil {
+		t.Fatal(err)
+	}
+	return g.record
+}
+
+func TestRecordPinsSolverMoves(t *testing.T) {
+	for _, pinned := range pinnedGames {
+		rules, err := parseRuleset(pinned.rules)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var expected []RecordedMove
+		if err := json.Unmarshal([]byte(pinned.moves), &expected); err != nil {
+			t.Fatal(err)
+		}
+
+		played := playRecorded(t, rules)
+		if got, want := mustMarshal(t, played.Moves), mustMarshal(t, expected); got != want {
+			t.Errorf("rules %s: expected moves %s, found %s", pinned.rules, want, got)
+		}
+		if played.Result == nil || played.Result.Winner != 1 {
+			t.Errorf("rules %s: expected the solver to win, found result %+v", pinned.rules, played.Result)
+		}
+
+		path := filepath.Join(t.TempDir(), "game.json")
+		if err := played.save(path); err != nil {
+			t.Fatal(err)
+		}
+		loaded, g, err := loadRecord(path)
+		if err != nil {
+			t.Fatalf("rules %s: loadRecord: %v", pinned.rules, err)
+		}
+		if !g.isGameOver() {
+			t.Errorf("rules %s: expected the loaded game to be over, found piles %v", pinned.rules, g.piles)
+		}
+		solver, _ := newPlayer("solver", rules, aiOptions{seed: 1})
+		differences, err := checkRecord(loaded, solver)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(differences) != 0 {
+			t.Errorf("rules %s: expected the solver to replay its own moves, found %q", pinned.rules, differences)
+		}
+	}
+}
+
+func TestCheckRecordReportsDifferences(t *testing.T) {
+	rules, _ := parseRuleset(pinnedGames[0].rules)
+	r := playRecorded(t, rules)
+	// The solver took 1 stone from pile 1 in its first move; a record that
+	// takes it from pile 2 instead must be flagged.
+	r.Moves[1].Takes = []Take{{Pile: 2, Stones: 1}}
+	r.Moves = r.Moves[:2]
+	r.Result = nil
+	solver, _ := newPlayer("solver", rules, aiOptions{seed: 1})
+	differences, err := checkRecord(r, solver)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(differences) != 1 || !strings.HasPrefix(differences[0], "move 2 ") {
+		t.Errorf("expected one difference at move 2, found %q", differences)
+	}
+}
+
+// mustMarshal encodes v as compact JSON for comparison.
+func mustMarshal(t *testing.T, v any) string {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
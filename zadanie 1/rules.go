@@ -0,0 +1,166 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Ruleset describes which Nim variant is played. The zero value is classic
+// normal-play Nim: take any number of stones from one pile, last stone wins.
+type Ruleset struct {
+	// Misere makes the player who takes the last stone lose.
+	Misere bool
+	// MaxTake limits how many stones may be taken from a pile in one move
+	// (subtraction game). Zero means no limit.
+	MaxTake int
+	// MaxPiles allows a move to take stones from up to MaxPiles different
+	// piles at once (Moore's Nim). Zero or one means a single pile.
+	MaxPiles int
+}
+
+// parseRuleset reads a comma separated rules specification such as
+// "misere,max-take=3" or "max-piles=2". "normal" and "" select classic Nim.
+func parseRuleset(spec string) (Ruleset, error) {
+	var r Ruleset
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		name, value, hasValue := strings.Cut(part, "=")
+		switch {
+		case part == "" || part == "normal":
+		case part == "misere":
+			r.Misere = true
+		case hasValue && (name == "max-take" || name == "max-piles"):
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return Ruleset{}, fmt.Errorf("invalid value in %q: want a positive integer", part)
+			}
+			if name == "max-take" {
+				r.MaxTake = n
+			} else {
+				r.MaxPiles = n
+			}
+		default:
+			return Ruleset{}, fmt.Errorf("unknown rule %q (want normal, misere, max-take=K or max-piles=N)", part)
+		}
+	}
+	return r, nil
+}
+
+// String returns the ruleset in the format accepted by parseRuleset.
+func (r Ruleset) String() string {
+	var parts []string
+	if r.Misere {
+		parts = append(parts, "misere")
+	}
+	if r.MaxTake > 0 {
+		parts = append(parts, "max-take="+strconv.Itoa(r.MaxTake))
+	}
+	if r.MaxPiles > 1 {
+		parts = append(parts, "max-piles="+strconv.Itoa(r.MaxPiles))
+	}
+	if len(parts) == 0 {
+		return "normal"
+	}
+	return strings.Join(parts, ",")
+}
+
+// takeLimit returns how many stones may be taken from a pile of the given size.
+func (r Ruleset) takeLimit(pile int) int {
+	if r.MaxTake > 0 && r.MaxTake < pile {
+		return r.MaxTake
+	}
+	return pile
+}
+
+// pileLimit returns how many piles a single move may touch.
+func (r Ruleset) pileLimit() int {
+	if r.MaxPiles > 1 {
+		return r.MaxPiles
+	}
+	return 1
+}
+
+// checkTake validates taking stones from pile pileIndex of state.
+func (r Ruleset) checkTake(state []int, pileIndex, stones int) error {
+	if pileIndex < 0 || pileIndex >= len(state) {
+		return fmt.Errorf("pile %d does not exist", pileIndex+1)
+	}
+	if stones <= 0 || stones > r.takeLimit(state[pileIndex]) {
+		return fmt.Errorf("can take 1 to %d stone(s) from pile %d", r.takeLimit(state[pileIndex]), pileIndex+1)
+	}
+	return nil
+}
+
+// isMove reports whether state can be turned into next by one legal move:
+// between one and pileLimit piles shrink, none by more than takeLimit, and
+// the other piles stay as they are.
+func (r Ruleset) isMove(state, next []int) bool {
+	if len(next) != len(state) {
+		return false
+	}
+	changed := 0
+	for i := range state {
+		switch taken := state[i] - next[i]; {
+		case taken == 0:
+		case taken < 0 || taken > r.takeLimit(state[i]):
+			return false
+		default:
+			changed++
+		}
+	}
+	return changed >= 1 && changed <= r.pileLimit()
+}
+
+// moveCount returns the number of legal moves from state, i.e. the length of
+// successors(state), without generating them.
+func (r Ruleset) moveCount(state []int) int {
+	// ways[k] is the number of ways to take from exactly k of the piles seen so far.
+	ways := make([]int, r.pileLimit()+1)
+	ways[0] = 1
+	for _, pile := range state {
+		for k := len(ways) - 1; k >= 1; k-- {
+			ways[k] += ways[k-1] * r.takeLimit(pile)
+		}
+	}
+	count := 0
+	for _, n := range ways[1:] {
+		count += n
+	}
+	return count
+}
+
+// lastMoverWins reports whether the player who empties the table wins.
+func (r Ruleset) lastMoverWins() bool {
+	return !r.Misere
+}
+
+// successors returns all states reachable from state in one legal move.
+func (r Ruleset) successors(state []int) [][]int {
+	var result [][]int
+	next := make([]int, len(state))
+	copy(next, state)
+
+	// take removes stones from piles with index >= from, touching at most
+	// left more piles; touched says whether the move already took something.
+	var take func(from, left int, touched bool)
+	take = func(from, left int, touched bool) {
+		if touched {
+			successor := make([]int, len(next))
+			copy(successor, next)
+			result = append(result, successor)
+		}
+		if left == 0 {
+			return
+		}
+		for i := from; i < len(state); i++ {
+			for stones := 1; stones <= r.takeLimit(state[i]); stones++ {
+				next[i] = state[i] - stones
+				take(i+1, left-1, true)
+			}
+			next[i] = state[i]
+		}
+	}
+	take(0, r.pileLimit(), false)
+	return result
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+// TestIsMoveMatchesSuccessors checks the arithmetic move check and the move
+// count against the enumerated successors of every small position.
+func TestIsMoveMatchesSuccessors(t *testing.T) {
+	for _, rules := range verifiedRulesets {
+		state := make([]int, 3)
+		for nextConfiguration(state, 3) {
+			successors := make(map[string]bool)
+			for _, next := range rules.successors(state) {
+				successors[fmt.Sprint(next)] = true
+			}
+			if count := rules.moveCount(state); count != len(successors) {
+				t.Errorf("rules %v: expected %d moves from %v, found %d", rules, len(successors), state, count)
+			}
+			next := make([]int, 3)
+			for {
+				if rules.isMove(state, next) != successors[fmt.Sprint(next)] {
+					t.Errorf("rules %v: isMove(%v, %v) = %v", rules, state, next, !successors[fmt.Sprint(next)])
+				}
+				if !nextConfiguration(next, 3) {
+					break
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"math"
+	"time"
+)
+
+// Action is a single move of a GameState.
+type Action interface {
+	String() string
+}
+
+// GameState is a position of a two-player, turn based, zero-sum game with
+// perfect information. Implementations must be immutable: Apply returns a new
+// state and leaves the receiver untouched.
+type GameState interface {
+	// Moves returns the legal moves in a fixed order.
+	Moves() []Action
+	// Apply returns the state after playing a, which must be one of Moves().
+	Apply(a Action) GameState
+	// Terminal reports whether the game is over.
+	Terminal() bool
+	// Utility returns the result of a terminal state for player:
+	// 1 for a win, -1 for a loss and 0 for a draw.
+	Utility(player int) int
+	// CurrentPlayer returns the player to move, 0 or 1.
+	CurrentPlayer() int
+	// Key returns a canonical encoding of the state. States with equal keys
+	// must have the same value for the player to move.
+	Key() string
+}
+
+// Evaluator can be implemented by a GameState to estimate the value of a
+// non-terminal state for the player to move when the search runs out of depth.
+// The estimate should lie strictly between -winScore and winScore.
+type Evaluator interface {
+	Evaluate() int
+}
+
+// winScore is the search value of a won terminal state.
+const winScore = 1000
+
+// bound tells how a transposition table value relates to the true value.
+type bound int
+
+const (
+	exact bound = iota
+	lower
+	upper
+)
+
+// ttEntry is a transposition table entry.
+type ttEntry struct {
+	depth  int   // remaining depth the value was searched with
+	value  int   // value for the player to move
+	bound  bound // whether value is exact or a bound from a cutoff
+	solved bool  // the value does not depend on the depth limit
+	best   int   // index of the best move, tried first on revisits
+}
+
+// Searcher finds moves with negamax (minimax from the point of view of the
+// player to move) with alpha-beta pruning. It deepens iteratively up to
+// MaxDepth plies or until TimeLimit runs out and remembers positions in a
+// transposition table keyed on GameState.Key, which survives between moves.
+type Searcher struct {
+	MaxDepth  int           // depth limit, 0 means unlimited
+	TimeLimit time.Duration // time limit per move, 0 means unlimited
+	Nodes     int           // states visited by the last BestMove
+
+	table     map[string]ttEntry
+	deadline  time.Time
+	stoppable bool // the time limit applies, which it does once depth 1 is searched
+	timedOut  bool
+	horizon   bool // the current iteration was cut off by the depth limit
+}
+
+// NewSearcher returns a searcher with an empty transposition table.
+func NewSearcher(maxDepth int, timeLimit time.Duration) *Searcher {
+	return &Searcher{MaxDepth: maxDepth, TimeLimit: timeLimit, table: make(map[string]ttEntry)}
+}
+
+// BestMove returns the best move found for the player to move together with
+// its value (winScore for a proven win, -winScore for a proven loss).
+// It returns a nil Action for terminal states. The search to depth 1 always
+// completes, even after TimeLimit, so the move returned has been searched.
+func (s *Searcher) BestMove(state GameState) (Action, int) {
+	moves := state.Moves()
+	if state.Terminal() || len(moves) == 0 {
+		return nil, 0
+	}
+	s.Nodes = 0
+	s.stoppable = false
+	s.timedOut = false
+	if s.TimeLimit > 0 {
+		s.deadline = time.Now().Add(s.TimeLimit)
+	}
+
+	// The root is searched here rather than in negamax because transposition
+	// table entries are shared between states with the same key, whose moves
+	// may be listed in a different order.
+	best, bestValue := 0, math.MinInt
+	for depth := 1; s.MaxDepth == 0 || depth <= s.MaxDepth; depth++ {
+		s.horizon = false
+		alpha := -winScore - 1
+		iterBest, iterValue := best, math.MinInt
+		for _, i := range append([]int{best}, indicesExcept(len(moves), best)...) {
+			value := -s.negamax(state.Apply(moves[i]), depth-1, -winScore-1, -alpha)
+			if value > iterValue {
+				iterBest, iterValue = i, value
+			}
+			if value > alpha {
+				alpha = value
+			}
+			if value == winScore {
+				break
+			}
+		}
+		if s.timedOut {
+			break
+		}
+		best, bestValue = iterBest, iterValue
+		s.stoppable = s.TimeLimit > 0
+		if !s.horizon || bestValue == winScore || bestValue == -winScore {
+			break
+		}
+	}
+	return moves[best], bestValue
+}
+
+// indicesExcept returns 0..n-1 without skip.
+func indicesExcept(n, skip int) []int {
+	indices := make([]int, 0, n)
+	for i := 0; i < n; i++ {
+		if i != skip {
+			indices = append(indices, i)
+		}
+	}
+	return indices
+}
+
+// negamax returns the value of state for the player to move, searching depth
+// more plies within the (alpha, beta) window.
+func (s *Searcher) negamax(state GameState, depth, alpha, beta int) int {
+	s.Nodes++
+	if state.Terminal() {
+		return winScore * state.Utility(state.CurrentPlayer())
+	}
+	if s.stoppable && s.Nodes%1024 == 0 && time.Now().After(s.deadline) {
+		s.timedOut = true
+	}
+	if s.timedOut {
+		return 0
+	}
+
+	key := state.Key()
+	entry, found := s.table[key]
+	if found && (entry.solved || entry.depth >= depth) {
+		switch {
+		case entry.bound == exact,
+			entry.bound == lower && entry.value >= beta,
+			entry.bound == upper && entry.value <= alpha:
+			if !entry.solved {
+				s.horizon = true
+			}
+			return entry.value
+		}
+	}
+	if depth == 0 {
+		s.horizon = true
+		if e, ok := state.(Evaluator); ok {
+			return e.Evaluate()
+		}
+		return 0
+	}
+
+	moves := state.Moves()
+	if len(moves) == 0 {
+		return 0
+	}
+	order := indicesExcept(len(moves), -1)
+	if found && entry.best < len(moves) {
+		order = append([]int{entry.best}, indicesExcept(len(moves), entry.best)...)
+	}
+
+	outerHorizon := s.horizon
+	s.horizon = false
+	originalAlpha := alpha
+	bestValue, bestIndex := math.MinInt, order[0]
+	for _, i := range order {
+		value := -s.negamax(state.Apply(moves[i]), depth-1, -beta, -alpha)
+		if value > bestValue {
+			bestValue, bestIndex = value, i
+		}
+		if value > alpha {
+			alpha = value
+		}
+		if alpha >= beta {
+			break
+		}
+	}
+	if s.timedOut {
+		return 0
+	}
+
+	entry = ttEntry{depth: depth, value: bestValue, solved: !s.horizon, best: bestIndex}
+	switch {
+	case bestValue <= originalAlpha:
+		entry.bound = upper
+	case bestValue >= beta:
+		entry.bound = lower
+	default:
+		entry.bound = exact
+	}
+	s.table[key] = entry
+	s.horizon = s.horizon || outerHorizon
+	return bestValue
+}
@@ -0,0 +1,17 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestBestMoveFinishesDepthOne(t *testing.T) {
+	// A single pile of 1500 stones has more moves than the searcher visits
+	// between two looks at the clock, and only taking all of them wins.
+	s := NewSearcher(0, time.Nanosecond)
+	move, value := s.BestMove(nimState{piles: []int{1500}})
+	if move == nil || fmt.Sprint(move.(nimAction).to) != "[0]" || value != winScore {
+		t.Errorf("expected the winning move to [0] with value %d, found %v with %d", winScore, move, value)
+	}
+}
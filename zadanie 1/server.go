@@ -0,0 +1,411 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// server lets remote clients play Nim against the AI over HTTP. Every game is
+// a session in which the client is player 0 and the AI player 1.
+//
+//	POST /games                 create a game, body newGameRequest
+//	GET  /games/{id}            current state
+//	POST /games/{id}/moves      play the client's move, body moveRequest
+//	POST /games/{id}/ai-move    let the AI play its move
+//	GET  /games/{id}/ws         WebSocket pushing the state after every change
+//
+// All responses carry a gameState or an errorResponse as JSON. Games left
+// without requests for sessionTTL are deleted.
+type server struct {
+	opts aiOptions
+	mux  *http.ServeMux
+
+	mu       sync.Mutex
+	sessions map[string]*session
+}
+
+// Limits of the games a server accepts, so that neither the AI nor move
+// validation can be made to run for long. Moves and positions are counted
+// from the starting position, which has the most of them in the game.
+const (
+	maxServerPiles     = 10     // piles in a game
+	maxServerStones    = 100    // stones in a pile
+	maxServerMovePiles = 3      // piles touched by one move (max-piles)
+	maxServerMoves     = 1000   // legal moves from a position
+	maxServerPositions = 100000 // positions the solver may search
+)
+
+// sessionTTL is how long a game is kept after its last request.
+const sessionTTL = 30 * time.Minute
+
+// serverThink is the time limit per move of alphabeta and mcts players when
+// the server is started without -think.
+const serverThink = 2 * time.Second
+
+// session is one game of the server. Its mutex serialises moves, so two
+// requests for the same game cannot both play from the same position.
+type session struct {
+	id         string
+	clientSeat int       // index of the remote client in the game, 1 when the AI moves first
+	lastUsed   time.Time // guarded by server.mu
+
+	thinking sync.Mutex // held while the AI searches for a move, without mu
+	mu       sync.Mutex
+	game     *Game
+	ai       Player
+	watchers map[chan struct{}]bool // signalled after every move
+}
+
+// newGameRequest is the body of POST /games. Empty fields take the defaults
+// of the console game; with AIFirst the AI moves first.
+type newGameRequest struct {
+	Piles   []int  `json:"piles"`
+	Rules   string `json:"rules"`
+	AI      string `json:"ai"`
+	AIFirst bool   `json:"ai_first"`
+}
+
+// moveRequest is the body of POST /games/{id}/moves.
+type moveRequest struct {
+	Takes []Take `json:"takes"`
+}
+
+// gameState is the JSON view of a session.
+type gameState struct {
+	ID      string         `json:"id"`
+	Rules   string         `json:"rules"`
+	Piles   []int          `json:"piles"`
+	Turn    int            `json:"turn"`
+	Players [2]string      `json:"players"`
+	Moves   []RecordedMove `json:"moves"`
+	Over    bool           `json:"over"`
+	Winner  *int           `json:"winner,omitempty"`
+}
+
+// errorResponse is the JSON body of a failed request.
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// errConflict marks requests that are valid but not in the current state of
+// the game, e.g. a move out of turn.
+var errConflict = errors.New("conflict")
+
+// newServer returns a server creating AI players with opts, limited to
+// serverThink per move unless opts sets a time limit.
+func newServer(opts aiOptions) *server {
+	if opts.think == 0 {
+		opts.think = serverThink
+	}
+	s := &server{opts: opts, mux: http.NewServeMux(), sessions: make(map[string]*session)}
+	s.mux.HandleFunc("POST /games", s.handleCreate)
+	s.mux.HandleFunc("GET /games/{id}", s.withSession(s.handleState))
+	s.mux.HandleFunc("POST /games/{id}/moves", s.withSession(s.handleMove))
+	s.mux.HandleFunc("POST /games/{id}/ai-move", s.withSession(s.handleAIMove))
+	s.mux.HandleFunc("GET /games/{id}/ws", s.withSession(s.handleWebSocket))
+	return s
+}
+
+// ServeHTTP makes server an http.Handler.
+func (s *server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// withSession looks up the session named in the path before calling handler.
+// Expired sessions are evicted first, so they are not found.
+func (s *server) withSession(handler func(http.ResponseWriter, *http.Request, *session)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		now := time.Now()
+		s.mu.Lock()
+		s.evictExpired(now)
+		sess, ok := s.sessions[r.PathValue("id")]
+		if ok {
+			sess.lastUsed = now
+		}
+		s.mu.Unlock()
+		if !ok {
+			writeJSON(w, http.StatusNotFound, errorResponse{"game not found"})
+			return
+		}
+		handler(w, r, sess)
+	}
+}
+
+// handleCreate starts a new session.
+func (s *server) handleCreate(w http.ResponseWriter, r *http.Request) {
+	var req newGameRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{"invalid request body: " + err.Error()})
+		return
+	}
+	if req.Piles == nil {
+		req.Piles = []int{3, 4, 5}
+	}
+	if req.AI == "" {
+		req.AI = "solver"
+	}
+	rules, err := parseRuleset(req.Rules)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{err.Error()})
+		return
+	}
+	if err := checkServerGame(req.Piles, rules, req.AI); err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{err.Error()})
+		return
+	}
+	ai, err := newPlayer(req.AI, rules, s.opts)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{err.Error()})
+		return
+	}
+
+	game := &Game{piles: append([]int(nil), req.Piles...), rules: rules}
+	sess := &session{id: newSessionID(), game: game, ai: ai, watchers: make(map[chan struct{}]bool)}
+	names := [2]string{"client", ai.Name()}
+	if req.AIFirst {
+		sess.clientSeat = 1
+		names = [2]string{ai.Name(), "client"}
+	}
+	game.record = newRecord(game, names)
+	s.mu.Lock()
+	s.evictExpired(time.Now())
+	sess.lastUsed = time.Now()
+	s.sessions[sess.id] = sess
+	s.mu.Unlock()
+
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	writeJSON(w, http.StatusCreated, sess.state())
+}
+
+// checkServerGame rejects starting positions outside the server limits and
+// those without stones, in which the game would be over before it began. The
+// solver, which has no time limit, may search the game tree only for small
+// positions of rulesets without a closed form.
+func checkServerGame(piles []int, rules Ruleset, ai string) error {
+	if len(piles) > maxServerPiles {
+		return fmt.Errorf("expected at most %d piles, found %d", maxServerPiles, len(piles))
+	}
+	stones := 0
+	for _, pile := range piles {
+		if pile < 0 || pile > maxServerStones {
+			return fmt.Errorf("expected pile sizes between 0 and %d, found %d", maxServerStones, pile)
+		}
+		stones += pile
+	}
+	if stones == 0 {
+		return errors.New("expected at least one stone, found a game that is already over")
+	}
+	if rules.MaxPiles > maxServerMovePiles {
+		return fmt.Errorf("expected max-piles of at most %d, found %d", maxServerMovePiles, rules.MaxPiles)
+	}
+	if moves := rules.moveCount(piles); moves > maxServerMoves {
+		return fmt.Errorf("the starting position allows %d moves, the server accepts at most %d", moves, maxServerMoves)
+	}
+	if ai == "solver" && !rules.closedForm() {
+		positions := 1
+		for _, pile := range piles {
+			if positions *= pile + 1; positions > maxServerPositions {
+				return fmt.Errorf("the starting position is too large for the solver to search, the server accepts at most %d positions", maxServerPositions)
+			}
+		}
+	}
+	return nil
+}
+
+// evictExpired deletes the sessions unused for sessionTTL, except those
+// watched over a WebSocket. The caller must hold s.mu.
+func (s *server) evictExpired(now time.Time) {
+	for id, sess := range s.sessions {
+		if now.Sub(sess.lastUsed) < sessionTTL {
+			continue
+		}
+		sess.mu.Lock()
+		watched := len(sess.watchers) > 0
+		sess.mu.Unlock()
+		if !watched {
+			delete(s.sessions, id)
+		}
+	}
+}
+
+// handleState returns the current state of a session.
+func (s *server) handleState(w http.ResponseWriter, r *http.Request, sess *session) {
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	writeJSON(w, http.StatusOK, sess.state())
+}
+
+// handleMove plays the client's move.
+func (s *server) handleMove(w http.ResponseWriter, r *http.Request, sess *session) {
+	var req moveRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{"invalid request body: " + err.Error()})
+		return
+	}
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	err := sess.play(sess.clientSeat, func(g *Game) ([]int, error) {
+		return applyTakes(g.rules, g.piles, req.Takes)
+	})
+	sess.respond(w, err)
+}
+
+// handleAIMove lets the AI play its move. The AI searches on a copy of the
+// position without holding sess.mu, so the state stays readable meanwhile;
+// sess.thinking keeps a second AI move from starting before the first is played.
+func (s *server) handleAIMove(w http.ResponseWriter, r *http.Request, sess *session) {
+	sess.thinking.Lock()
+	defer sess.thinking.Unlock()
+	seat := 1 - sess.clientSeat
+
+	sess.mu.Lock()
+	err := sess.checkTurn(seat)
+	position := &Game{piles: append([]int(nil), sess.game.piles...), rules: sess.game.rules, turn: sess.game.turn}
+	sess.mu.Unlock()
+	var next []int
+	if err == nil {
+		next, err = sess.ai.Move(position)
+	}
+
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	if err == nil {
+		err = sess.play(seat, func(g *Game) ([]int, error) { return next, nil })
+	}
+	sess.respond(w, err)
+}
+
+// handleWebSocket pushes the state of the session to a WebSocket client,
+// first immediately and then after moves, until the client disconnects. A
+// slow client may skip intermediate states but always gets the latest one.
+func (s *server) handleWebSocket(w http.ResponseWriter, r *http.Request, sess *session) {
+	conn, err := upgradeWebSocket(w, r)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	updates := make(chan struct{}, 1)
+	sess.mu.Lock()
+	initial, _ := json.Marshal(sess.state())
+	sess.watchers[updates] = true
+	sess.mu.Unlock()
+	defer func() {
+		sess.mu.Lock()
+		delete(sess.watchers, updates)
+		sess.mu.Unlock()
+	}()
+
+	closed := make(chan struct{})
+	go func() {
+		conn.readUntilClose()
+		close(closed)
+	}()
+	if err := conn.writeText(initial); err != nil {
+		return
+	}
+	for {
+		select {
+		case <-updates:
+			sess.mu.Lock()
+			message, _ := json.Marshal(sess.state())
+			sess.mu.Unlock()
+			if err := conn.writeText(message); err != nil {
+				return
+			}
+		case <-closed:
+			return
+		}
+	}
+}
+
+// checkTurn reports an errConflict unless seat is to move in a game that is
+// not over. The caller must hold sess.mu.
+func (sess *session) checkTurn(seat int) error {
+	if sess.game.isGameOver() {
+		return fmt.Errorf("%w: the game is over", errConflict)
+	}
+	if sess.game.turn != seat {
+		return fmt.Errorf("%w: it is not this player's turn", errConflict)
+	}
+	return nil
+}
+
+// play lets seat move with choose if it is its turn and notifies watchers.
+// The caller must hold sess.mu.
+func (sess *session) play(seat int, choose func(g *Game) ([]int, error)) error {
+	if err := sess.checkTurn(seat); err != nil {
+		return err
+	}
+	g := sess.game
+	next, err := choose(g)
+	if err != nil {
+		return err
+	}
+	if !g.isSuccessor(next) {
+		return fmt.Errorf("illegal move %v -> %v", g.piles, next)
+	}
+	g.apply(next)
+
+	for watcher := range sess.watchers {
+		select {
+		case watcher <- struct{}{}:
+		default:
+			// A signal is already pending; the watcher will read the latest state.
+		}
+	}
+	return nil
+}
+
+// respond writes the state after a move or the error that prevented it.
+// The caller must hold sess.mu.
+func (sess *session) respond(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, errConflict):
+		writeJSON(w, http.StatusConflict, errorResponse{err.Error()})
+	case err != nil:
+		writeJSON(w, http.StatusBadRequest, errorResponse{err.Error()})
+	default:
+		writeJSON(w, http.StatusOK, sess.state())
+	}
+}
+
+// state returns the JSON view of the session. The caller must hold sess.mu.
+func (sess *session) state() gameState {
+	g := sess.game
+	state := gameState{
+		ID:      sess.id,
+		Rules:   g.rules.String(),
+		Piles:   append([]int(nil), g.piles...),
+		Turn:    g.turn,
+		Players: g.record.Players,
+		Moves:   append([]RecordedMove{}, g.record.Moves...),
+		Over:    g.isGameOver(),
+	}
+	if state.Over {
+		winner := g.winner()
+		state.Winner = &winner
+	}
+	return state
+}
+
+// newSessionID returns a random identifier that is hard to guess.
+func newSessionID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// writeJSON writes v as a JSON response with the given status code.
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// request sends body as JSON to the test server and decodes the gameState
+// of the response, which is left zero for errors.
+func request(t *testing.T, server *httptest.Server, method, path, body string) (int, gameState) {
+	t.Helper()
+	req, err := http.NewRequest(method, server.URL+path, bytes.NewBufferString(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := server.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var state gameState
+	if resp.StatusCode < 300 {
+		if err := json.NewDecoder(resp.Body).Decode(&state); err != nil {
+			t.Fatalf("%s %s: %v", method, path, err)
+		}
+	}
+	return resp.StatusCode, state
+}
+
+func TestServerGame(t *testing.T) {
+	server := httptest.NewServer(newServer(aiOptions{seed: 1}))
+	defer server.Close()
+
+	status, state := request(t, server, "POST", "/games", `{"piles": [3, 4, 5], "rules": "misere,max-take=3"}`)
+	if status != http.StatusCreated || state.Turn != 0 || state.Players != [2]string{"client", "solver"} {
+		t.Fatalf("create: expected status 201 with the client to move, found %d %+v", status, state)
+	}
+	game := "/games/" + state.ID
+
+	if status, _ := request(t, server, "POST", game+"/ai-move", ""); status != http.StatusConflict {
+		t.Errorf("AI move out of turn: expected status 409, found %d", status)
+	}
+	if status, _ := request(t, server, "POST", game+"/moves", `{"takes": [{"pile": 3, "stones": 4}]}`); status != http.StatusBadRequest {
+		t.Errorf("illegal move: expected status 400, found %d", status)
+	}
+
+	status, state = request(t, server, "POST", game+"/moves", `{"takes": [{"pile": 3, "stones": 3}]}`)
+	if status != http.StatusOK || state.Turn != 1 || fmt.Sprint(state.Piles) != "[3 4 2]" {
+		t.Fatalf("move: expected status 200 with piles [3 4 2] and the AI to move, found %d %+v", status, state)
+	}
+	if status, _ := request(t, server, "POST", game+"/moves", `{"takes": [{"pile": 1, "stones": 1}]}`); status != http.StatusConflict {
+		t.Errorf("client move out of turn: expected status 409, found %d", status)
+	}
+
+	status, state = request(t, server, "POST", game+"/ai-move", "")
+	if status != http.StatusOK || state.Turn != 0 || fmt.Sprint(state.Piles) != "[2 4 2]" || len(state.Moves) != 2 {
+		t.Fatalf("AI move: expected status 200 with piles [2 4 2] after two moves, found %d %+v", status, state)
+	}
+
+	status, state = request(t, server, "GET", game, "")
+	if status != http.StatusOK || fmt.Sprint(state.Piles) != "[2 4 2]" {
+		t.Errorf("state: expected status 200 with piles [2 4 2], found %d %+v", status, state)
+	}
+	if status, _ := request(t, server, "GET", "/games/unknown", ""); status != http.StatusNotFound {
+		t.Errorf("unknown game: expected status 404, found %d", status)
+	}
+}
+
+func TestServerAIFirst(t *testing.T) {
+	server := httptest.NewServer(newServer(aiOptions{seed: 1}))
+	defer server.Close()
+
+	_, state := request(t, server, "POST", "/games", `{"piles": [1, 2], "ai": "greedy", "ai_first": true}`)
+	game := "/games/" + state.ID
+	if status, _ := request(t, server, "POST", game+"/moves", `{"takes": [{"pile": 1, "stones": 1}]}`); status != http.StatusConflict {
+		t.Errorf("client move before the AI: expected status 409, found %d", status)
+	}
+	status, state := request(t, server, "POST", game+"/ai-move", "")
+	if status != http.StatusOK || fmt.Sprint(state.Piles) != "[1 0]" || state.Turn != 1 {
+		t.Errorf("AI move: expected status 200 with piles [1 0], found %d %+v", status, state)
+	}
+}
+
+func TestServerRejectsLargeGames(t *testing.T) {
+	server := httptest.NewServer(newServer(aiOptions{}))
+	defer server.Close()
+
+	for _, body := range []string{
+		`{"piles": [20000000]}`,
+		`{"piles": [-1, 3]}`,
+		`{"piles": [1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1]}`,
+		`{"piles": [100, 100, 100, 100], "rules": "max-piles=3"}`,
+		`{"piles": [3, 4, 5], "rules": "max-piles=4"}`,
+		`{"piles": [30, 30, 30, 30], "rules": "misere,max-take=3"}`,
+		`{"ai": "nobody"}`,
+		`{"piles": [0, 0]}`,
+		`{"piles": []}`,
+	} {
+		if status, _ := request(t, server, "POST", "/games", body); status != http.StatusBadRequest {
+			t.Errorf("create %s: expected status 400, found %d", body, status)
+		}
+	}
+	if status, _ := request(t, server, "POST", "/games", `{"piles": [30, 30, 30, 30], "rules": "misere,max-take=3", "ai": "greedy"}`); status != http.StatusCreated {
+		t.Errorf("large game against greedy: expected status 201, found %d", status)
+	}
+}
+
+func TestServerEvictsExpiredGames(t *testing.T) {
+	s := newServer(aiOptions{})
+	server := httptest.NewServer(s)
+	defer server.Close()
+
+	_, state := request(t, server, "POST", "/games", `{}`)
+	s.mu.Lock()
+	s.evictExpired(time.Now().Add(sessionTTL))
+	s.mu.Unlock()
+	if status, _ := request(t, server, "GET", "/games/"+state.ID, ""); status != http.StatusNotFound {
+		t.Errorf("expired game: expected status 404, found %d", status)
+	}
+
+	// A lookup evicts expired games too, without a new game being created.
+	_, state = request(t, server, "POST", "/games", `{}`)
+	s.mu.Lock()
+	s.sessions[state.ID].lastUsed = time.Now().Add(-sessionTTL)
+	s.mu.Unlock()
+	if status, _ := request(t, server, "POST", "/games/"+state.ID+"/moves", `{"takes": [{"pile": 1, "stones": 1}]}`); status != http.StatusNotFound {
+		t.Errorf("move in an expired game: expected status 404, found %d", status)
+	}
+}
+
+// dialWebSocket opens a WebSocket to path on the test server. The returned
+// wsConn reads the unmasked frames of the server with readFrame.
+func dialWebSocket(t *testing.T, server *httptest.Server, path string) *wsConn {
+	t.Helper()
+	conn, err := net.Dial("tcp", server.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.SetDeadline(time.Now().Add(10 * time.Second))
+	key := "dGhlIHNhbXBsZSBub25jZQ=="
+	fmt.Fprintf(conn, "GET %s HTTP/1.1\r\nHost: %s\r\nConnection: Upgrade\r\nUpgrade: websocket\r\nSec-WebSocket-Key: %s\r\nSec-WebSocket-Version: 13\r\n\r\n",
+		path, server.Listener.Addr(), key)
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols || resp.Header.Get("Sec-WebSocket-Accept") != websocketAccept(key) {
+		t.Fatalf("expected status 101 with the accept key, found %d %v", resp.StatusCode, resp.Header)
+	}
+	return &wsConn{conn: conn, reader: reader}
+}
+
+// readState reads the next text frame of the server as a gameState.
+func readState(t *testing.T, ws *wsConn) gameState {
+	t.Helper()
+	opcode, payload, err := ws.readFrame()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var state gameState
+	if opcode != opText {
+		t.Fatalf("expected a text frame, found opcode %d", opcode)
+	}
+	if err := json.Unmarshal(payload, &state); err != nil {
+		t.Fatal(err)
+	}
+	return state
+}
+
+func TestServerWebSocket(t *testing.T) {
+	server := httptest.NewServer(newServer(aiOptions{seed: 1}))
+	defer server.Close()
+
+	_, created := request(t, server, "POST", "/games", `{"piles": [3, 4, 5]}`)
+	game := "/games/" + created.ID
+	ws := dialWebSocket(t, server, game+"/ws")
+	defer ws.Close()
+
+	if state := readState(t, ws); state.ID != created.ID || fmt.Sprint(state.Piles) != "[3 4 5]" || len(state.Moves) != 0 {
+		t.Fatalf("initial frame: expected piles [3 4 5] without moves, found %+v", state)
+	}
+	status, moved := request(t, server, "POST", game+"/moves", `{"takes": [{"pile": 2, "stones": 4}]}`)
+	if status != http.StatusOK {
+		t.Fatalf("move: expected status 200, found %d", status)
+	}
+	if state := readState(t, ws); fmt.Sprint(state.Piles) != "[3 0 5]" || state.Turn != 1 || len(state.Moves) != 1 || fmt.Sprint(state) != fmt.Sprint(moved) {
+		t.Errorf("pushed frame: expected the state after the move %+v, found %+v", moved, state)
+	}
+}
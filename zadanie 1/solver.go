@@ -0,0 +1,298 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+)
+
+// Fallback selects what the solver does in a lost position, i.e. when
+// every move hands the opponent a win.
+type Fallback int
+
+const (
+	// FallbackStall removes a single stone from the largest pile, which keeps
+	// the game as long as possible and gives the opponent the most chances to err.
+	FallbackStall Fallback = iota
+	// FallbackGreedy empties the largest pile.
+	FallbackGreedy
+	// FallbackRandom plays a uniformly random legal move.
+	FallbackRandom
+)
+
+// fallbackNames maps command line names to fallback strategies.
+var fallbackNames = map[string]Fallback{
+	"stall":  FallbackStall,
+	"greedy": FallbackGreedy,
+	"random": FallbackRandom,
+}
+
+// parseFallback converts a strategy name such as "stall" into a Fallback.
+func parseFallback(name string) (Fallback, error) {
+	f, ok := fallbackNames[name]
+	if !ok {
+		return 0, fmt.Errorf("unknown fallback strategy %q (want stall, greedy or random)", name)
+	}
+	return f, nil
+}
+
+// Solver plays every Nim variant of its ruleset perfectly. Classic Nim is
+// solved with the nim-sum (XOR of all pile sizes): a position is lost for the
+// player to move exactly when the nim-sum is 0, so from every other position
+// there is a move that makes it 0 again. Variants with a known closed form
+// use it as well, the rest fall back to a memoised game tree search.
+type Solver struct {
+	Fallback Fallback
+	rules    Ruleset
+	rng      *rand.Rand
+	won      map[string]bool
+	grundy   *GrundyCalculator
+}
+
+// NewSolver returns a solver for the given rules that uses the given fallback
+// in lost positions. The seed only matters for FallbackRandom.
+func NewSolver(rules Ruleset, fallback Fallback, seed int64) *Solver {
+	return &Solver{
+		Fallback: fallback,
+		rules:    rules,
+		rng:      rand.New(rand.NewSource(seed)),
+		won:      make(map[string]bool),
+		grundy:   NewGrundyCalculator(),
+	}
+}
+
+// nimSum returns the XOR of all pile sizes.
+func nimSum(piles []int) int {
+	sum := 0
+	for _, pile := range piles {
+		sum ^= pile
+	}
+	return sum
+}
+
+// isWon reports whether the player to move can force a win from state.
+func (s *Solver) isWon(state []int) bool {
+	r := s.rules
+	switch {
+	case r.MaxPiles <= 1 && !r.Misere:
+		// The piles are independent impartial games, so the position is a
+		// sum of games and is lost exactly when the XOR of the Grundy values
+		// of the piles is 0. In classic Nim that is the nim-sum itself, in a
+		// subtraction game with limit k a pile is worth pile mod (k+1).
+		sum := make(Sum, len(state))
+		for i, pile := range state {
+			sum[i] = pileGame{stones: pile, rules: r}
+		}
+		return s.grundy.Value(sum) != 0
+	case r.MaxPiles <= 1 && r.MaxTake == 0:
+		// Misère Nim is played like normal Nim until only piles of one stone
+		// remain; then the player to move wins with an even number of them.
+		big, ones := false, 0
+		for _, pile := range state {
+			big = big || pile > 1
+			if pile == 1 {
+				ones++
+			}
+		}
+		if !big {
+			return ones%2 == 0
+		}
+		return nimSum(state) != 0
+	case r.MaxPiles > 1 && r.MaxTake == 0 && !r.Misere:
+		// Moore's Nim: lost exactly when, for every binary digit, the number
+		// of piles with that digit set is divisible by MaxPiles+1.
+		for bit := 1; ; bit <<= 1 {
+			count, higher := 0, false
+			for _, pile := range state {
+				if pile&bit != 0 {
+					count++
+				}
+				higher = higher || pile >= bit
+			}
+			if !higher {
+				return false
+			}
+			if count%(r.MaxPiles+1) != 0 {
+				return true
+			}
+		}
+	}
+	return s.searchWon(state)
+}
+
+// closedForm reports whether isWon classifies positions of the ruleset by a
+// formula. For the other rulesets it searches the game tree.
+func (r Ruleset) closedForm() bool {
+	return r.MaxPiles <= 1 && (!r.Misere || r.MaxTake == 0) || r.MaxPiles > 1 && r.MaxTake == 0 && !r.Misere
+}
+
+// searchWon classifies state by game tree search, memoised on the sorted
+// pile sizes since the order of piles does not matter.
+func (s *Solver) searchWon(state []int) bool {
+	canonical := make([]int, len(state))
+	copy(canonical, state)
+	sort.Ints(canonical)
+	key := fmt.Sprint(canonical)
+	if result, ok := s.won[key]; ok {
+		return result
+	}
+	result := s.rules.Misere
+	if !isEmpty(state) {
+		result = false
+		for _, successor := range s.rules.successors(state) {
+			if !s.isWon(successor) {
+				result = true
+				break
+			}
+		}
+	}
+	s.won[key] = result
+	return result
+}
+
+// winningMoves returns every state reachable in one move that is lost for the
+// opponent. The result is empty when the position itself is lost.
+func (s *Solver) winningMoves(piles []int) [][]int {
+	if !s.isWon(piles) {
+		return nil
+	}
+	var moves [][]int
+	for _, successor := range s.rules.successors(piles) {
+		if !s.isWon(successor) {
+			moves = append(moves, successor)
+		}
+	}
+	return moves
+}
+
+// nextState returns the piles after the solver's move. It must not be called
+// when all piles are already empty.
+func (s *Solver) nextState(piles []int) []int {
+	if moves := s.winningMoves(piles); len(moves) > 0 {
+		return moves[0]
+	}
+	return s.fallbackState(piles)
+}
+
+// fallbackState picks a move in a lost position according to s.Fallback.
+func (s *Solver) fallbackState(piles []int) []int {
+	next := make([]int, len(piles))
+	copy(next, piles)
+	largest := 0
+	for i, pile := range piles {
+		if pile > piles[largest] {
+			largest = i
+		}
+	}
+	switch s.Fallback {
+	case FallbackGreedy:
+		next[largest] -= s.rules.takeLimit(piles[largest])
+	case FallbackRandom:
+		successors := s.rules.successors(piles)
+		return successors[s.rng.Intn(len(successors))]
+	default:
+		next[largest]--
+	}
+	return next
+}
+
+// verifiedRulesets lists the variants checked by verifySolver.
+var verifiedRulesets = []Ruleset{
+	{},
+	{Misere: true},
+	{MaxTake: 3},
+	{Misere: true, MaxTake: 2},
+	{MaxPiles: 2},
+	{MaxPiles: 2, MaxTake: 2},
+	{Misere: true, MaxPiles: 2},
+}
+
+// verifySolver brute-forces every configuration of up to maxPiles piles with at
+// most maxStones stones each and checks, for every ruleset in verifiedRulesets,
+// that the solver wins every won position. Positions are classified
+// independently of the solver by plain game tree search: a position is won
+// when some move leads to a lost one.
+func verifySolver(maxPiles, maxStones int) error {
+	for _, rules := range verifiedRulesets {
+		solver := NewSolver(rules, FallbackStall, 1)
+		won := make(map[string]bool)
+
+		var isWon func(state []int) bool
+		isWon = func(state []int) bool {
+			key := fmt.Sprint(state)
+			if result, ok := won[key]; ok {
+				return result
+			}
+			result := rules.Misere
+			if !isEmpty(state) {
+				result = false
+				for _, successor := range rules.successors(state) {
+					if !isWon(successor) {
+						result = true
+						break
+					}
+				}
+			}
+			won[key] = result
+			return result
+		}
+
+		checked := 0
+		for piles := 1; piles <= maxPiles; piles++ {
+			state := make([]int, piles)
+			for {
+				if !isEmpty(state) {
+					if isWon(state) != solver.isWon(state) {
+						return fmt.Errorf("rules %v: solver misclassified %v", rules, state)
+					}
+					if isWon(state) {
+						next := solver.nextState(state)
+						if isWon(next) {
+							return fmt.Errorf("rules %v: solver missed a win: %v -> %v", rules, state, next)
+						}
+					}
+					checked++
+				}
+				if !nextConfiguration(state, maxStones) {
+					break
+				}
+			}
+		}
+		if err := verifySearcher(rules, isWon); err != nil {
+			return err
+		}
+		fmt.Printf("Rules %v: solver verified on %d positions (up to %d piles of %d stones).\n", rules, checked, maxPiles, maxStones)
+	}
+	return nil
+}
+
+// verifySearcher checks that alpha-beta search plays a winning move from every
+// won position with up to three piles of four stones, given the brute-force
+// classification isWon.
+func verifySearcher(rules Ruleset, isWon func(state []int) bool) error {
+	searcher := NewSearcher(0, 0)
+	state := make([]int, 3)
+	for nextConfiguration(state, 4) {
+		if !isWon(state) {
+			continue
+		}
+		move, value := searcher.BestMove(nimState{piles: state, rules: rules})
+		if next := move.(nimAction).to; value != winScore || isWon(next) {
+			return fmt.Errorf("rules %v: alpha-beta missed a win: %v -> %v", rules, state, next)
+		}
+	}
+	return nil
+}
+
+// nextConfiguration advances state like an odometer in base maxStones+1 and
+// reports false once every configuration has been visited.
+func nextConfiguration(state []int, maxStones int) bool {
+	for i := len(state) - 1; i >= 0; i-- {
+		if state[i] < maxStones {
+			state[i]++
+			return true
+		}
+		state[i] = 0
+	}
+	return false
+}
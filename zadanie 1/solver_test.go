@@ -0,0 +1,15 @@
+package main
+
+import "testing"
+
+// TestVerifySolver runs the -verify brute-force check, on smaller positions
+// with -short.
+func TestVerifySolver(t *testing.T) {
+	maxPiles, maxStones := 4, 6
+	if testing.Short() {
+		maxPiles, maxStones = 3, 4
+	}
+	if err := verifySolver(maxPiles, maxStones); err != nil {
+		t.Fatal(err)
+	}
+}
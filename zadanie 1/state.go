@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// nimState adapts a Nim position to the GameState interface so that the
+// generic searches can play it.
+type nimState struct {
+	piles  []int
+	rules  Ruleset
+	player int
+}
+
+// nimAction is a Nim move, stored as the piles it leads to.
+type nimAction struct {
+	from, to []int
+}
+
+// String lists the stones taken from each pile, e.g. "take 2 from pile 1".
+func (a nimAction) String() string {
+	return describeMove(a.from, a.to)
+}
+
+// describeMove describes the move that turns piles from into piles to.
+func describeMove(from, to []int) string {
+	var takes []string
+	for i := range from {
+		if taken := from[i] - to[i]; taken > 0 {
+			takes = append(takes, fmt.Sprintf("take %d from pile %d", taken, i+1))
+		}
+	}
+	return strings.Join(takes, ", ")
+}
+
+// Moves returns every successor allowed by the ruleset.
+func (s nimState) Moves() []Action {
+	successors := s.rules.successors(s.piles)
+	moves := make([]Action, len(successors))
+	for i, next := range successors {
+		moves[i] = nimAction{from: s.piles, to: next}
+	}
+	return moves
+}
+
+// Apply plays a and passes the turn to the other player.
+func (s nimState) Apply(a Action) GameState {
+	return nimState{piles: a.(nimAction).to, rules: s.rules, player: 1 - s.player}
+}
+
+// Terminal reports whether all piles are empty.
+func (s nimState) Terminal() bool {
+	return isEmpty(s.piles)
+}
+
+// Utility scores the empty table: the player who is to move did not take the
+// last stone, which is a loss in normal play and a win in misère play.
+func (s nimState) Utility(player int) int {
+	toMoveWins := !s.rules.lastMoverWins()
+	if (player == s.player) == toMoveWins {
+		return 1
+	}
+	return -1
+}
+
+// CurrentPlayer returns the player to move.
+func (s nimState) CurrentPlayer() int {
+	return s.player
+}
+
+// Key returns the sorted pile sizes. Pile order and the identity of the player
+// to move do not change the value of a Nim position.
+func (s nimState) Key() string {
+	canonical := make([]int, len(s.piles))
+	copy(canonical, s.piles)
+	sort.Ints(canonical)
+	return fmt.Sprint(canonical)
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"text/tabwriter"
+)
+
+// TournamentConfig describes a series of games between two players.
+type TournamentConfig struct {
+	Games     int     // number of games
+	Piles     int     // number of piles in every game
+	MaxStones int     // piles start with 1 to MaxStones stones
+	Rules     Ruleset // variant played
+	Seed      int64   // seed for the starting piles
+}
+
+// TournamentReport summarises a tournament. Players take turns at moving
+// first, so the first player advantage can be told apart from playing strength.
+type TournamentReport struct {
+	Players            [2]string  `json:"players"`
+	Rules              string     `json:"rules"`
+	Games              int        `json:"games"`
+	Wins               [2]int     `json:"wins"`
+	WinRates           [2]float64 `json:"win_rates"`
+	AverageMoves       float64    `json:"average_moves"`
+	FirstPlayerWins    int        `json:"first_player_wins"`
+	FirstPlayerWinRate float64    `json:"first_player_win_rate"`
+}
+
+// runTournament plays config.Games games between the players from random
+// starting piles. players[0] moves first in even games, players[1] in odd ones.
+func runTournament(players [2]Player, config TournamentConfig) (*TournamentReport, error) {
+	if config.Games < 1 || config.Piles < 1 || config.MaxStones < 1 {
+		return nil, fmt.Errorf("tournament needs at least one game, pile and stone")
+	}
+	rng := rand.New(rand.NewSource(config.Seed))
+	report := &TournamentReport{
+		Players: [2]string{players[0].Name(), players[1].Name()},
+		Rules:   config.Rules.String(),
+		Games:   config.Games,
+	}
+	totalMoves := 0
+	for i := 0; i < config.Games; i++ {
+		piles := make([]int, config.Piles)
+		for j := range piles {
+			piles[j] = 1 + rng.Intn(config.MaxStones)
+		}
+		first := i % 2
+		order := [2]Player{players[first], players[1-first]}
+		game := Game{piles: piles, rules: config.Rules}
+		winner, moves, err := game.play(order, io.Discard)
+		if err != nil {
+			return nil, fmt.Errorf("game %d: %w", i+1, err)
+		}
+		if winner == 0 {
+			report.FirstPlayerWins++
+		}
+		if winner == 0 && first == 0 || winner == 1 && first == 1 {
+			report.Wins[0]++
+		} else {
+			report.Wins[1]++
+		}
+		totalMoves += moves
+	}
+	for i := range report.Wins {
+		report.WinRates[i] = float64(report.Wins[i]) / float64(config.Games)
+	}
+	report.AverageMoves = float64(totalMoves) / float64(config.Games)
+	report.FirstPlayerWinRate = float64(report.FirstPlayerWins) / float64(config.Games)
+	return report, nil
+}
+
+// writeTable prints the report as an aligned table.
+func (r *TournamentReport) writeTable(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "Rules:\t%s\n", r.Rules)
+	fmt.Fprintf(tw, "Games:\t%d\n", r.Games)
+	fmt.Fprintf(tw, "Average game length:\t%.1f moves\n", r.AverageMoves)
+	fmt.Fprintf(tw, "First player wins:\t%d (%.1f%%)\n", r.FirstPlayerWins, 100*r.FirstPlayerWinRate)
+	fmt.Fprintln(tw)
+	fmt.Fprintln(tw, "Player\tWins\tWin rate")
+	for i, name := range r.Players {
+		fmt.Fprintf(tw, "%s\t%d\t%.1f%%\n", name, r.Wins[i], 100*r.WinRates[i])
+	}
+	return tw.Flush()
+}
+
+// writeJSON prints the report as indented JSON.
+func (r *TournamentReport) writeJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(r)
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+// tournament plays greedy against a seeded random player.
+func tournament(t *testing.T, config TournamentConfig) *TournamentReport {
+	t.Helper()
+	greedy, _ := newPlayer("greedy", config.Rules, aiOptions{})
+	random, _ := newPlayer("random", config.Rules, aiOptions{seed: 5})
+	report, err := runTournament([2]Player{greedy, random}, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return report
+}
+
+func TestRunTournament(t *testing.T) {
+	config := TournamentConfig{Games: 25, Piles: 3, MaxStones: 6, Rules: Ruleset{Misere: true}, Seed: 3}
+	report := tournament(t, config)
+	if report.Wins[0]+report.Wins[1] != config.Games {
+		t.Errorf("expected %d wins in total, found %v", config.Games, report.Wins)
+	}
+	if report.FirstPlayerWins < 0 || report.FirstPlayerWins > config.Games {
+		t.Errorf("expected between 0 and %d first player wins, found %d", config.Games, report.FirstPlayerWins)
+	}
+	if sum := report.WinRates[0] + report.WinRates[1]; sum < 1-1e-9 || sum > 1+1e-9 {
+		t.Errorf("expected win rates adding up to 1, found %v", report.WinRates)
+	}
+	if report.AverageMoves < 3 {
+		t.Errorf("expected at least one move per pile on average, found %g", report.AverageMoves)
+	}
+
+	// The seed fixes the starting piles and the moves of the random player.
+	if report.Wins != [2]int{14, 11} || report.FirstPlayerWins != 16 || report.AverageMoves != 4.04 {
+		t.Errorf("expected wins [14 11], 16 first player wins and 4.04 moves per game, found %+v", report)
+	}
+	if again := tournament(t, config); !reflect.DeepEqual(report, again) {
+		t.Errorf("expected the same report for the same seed, found %+v and %+v", report, again)
+	}
+
+	for _, bad := range []TournamentConfig{{Games: 0, Piles: 3, MaxStones: 5}, {Games: 1, Piles: 0, MaxStones: 5}, {Games: 1, Piles: 3, MaxStones: 0}} {
+		if _, err := runTournament([2]Player{greedyPlayer{}, greedyPlayer{}}, bad); err == nil {
+			t.Errorf("expected an error for %+v", bad)
+		}
+	}
+}
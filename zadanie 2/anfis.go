@@ -0,0 +1,477 @@
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Dataset to zbiór uczący: wartości wejść X (po jednym wierszu na rekord) i oczekiwane wyjście Y
+type Dataset struct {
+	Inputs []string
+	Target string
+	X      [][]float64
+	Y      []float64
+}
+
+// LoadDataset wczytuje plik CSV z nagłówkiem; ostatnia kolumna jest wyjściem,
+// pozostałe wejściami
+func LoadDataset(path string) (*Dataset, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	r := csv.NewReader(f)
+	header, err := r.Read()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if len(header) < 2 {
+		return nil, fmt.Errorf("%s: need at least one input and the target column", path)
+	}
+	d := &Dataset{Target: strings.TrimSpace(header[len(header)-1])}
+	for _, name := range header[:len(header)-1] {
+		d.Inputs = append(d.Inputs, strings.TrimSpace(name))
+	}
+	for line := 2; ; line++ {
+		record, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		row := make([]float64, len(record))
+		for i, field := range record {
+			if row[i], err = strconv.ParseFloat(strings.TrimSpace(field), 64); err != nil {
+				return nil, fmt.Errorf("%s: line %d: invalid value of %q: %v", path, line, header[i], err)
+			}
+		}
+		d.X = append(d.X, row[:len(row)-1])
+		d.Y = append(d.Y, row[len(row)-1])
+	}
+	if len(d.Y) == 0 {
+		return nil, fmt.Errorf("%s: no records", path)
+	}
+	return d, nil
+}
+
+// split losowo dzieli rekordy na część uczącą i walidacyjną (ułamek validation)
+func (d *Dataset) split(validation float64, rng *rand.Rand) (train, valid *Dataset) {
+	train = &Dataset{Inputs: d.Inputs, Target: d.Target}
+	valid = &Dataset{Inputs: d.Inputs, Target: d.Target}
+	nValid := int(math.Round(validation * float64(len(d.Y))))
+	for i, j := range rng.Perm(len(d.Y)) {
+		part := train
+		if i < nValid {
+			part = valid
+		}
+		part.X = append(part.X, d.X[j])
+		part.Y = append(part.Y, d.Y[j])
+	}
+	return train, valid
+}
+
+// ANFIS to adaptacyjny system neuronowo-rozmyty (Jang, 1993): system Sugeno
+// pierwszego rzędu z gaussowskimi terminami wejść w podziale siatkowym
+// (reguła dla każdej kombinacji terminów) i iloczynem jako koniunkcją
+type ANFIS struct {
+	Inputs   []string
+	Output   string
+	Min, Max []float64   // dziedziny wejść
+	Means    [][]float64 // [wejście][termin]
+	Sigmas   [][]float64 // [wejście][termin]
+	Rules    [][]int     // [reguła][wejście] indeks terminu
+	Coef     [][]float64 // [reguła] stała, potem współczynniki kolejnych wejść
+}
+
+// NewANFIS tworzy sieć o terms terminach na wejście, rozłożonych równomiernie
+// w zakresie wartości zbioru d
+func NewANFIS(d *Dataset, terms int) *ANFIS {
+	n := len(d.Inputs)
+	a := &ANFIS{Inputs: d.Inputs, Output: d.Target, Min: make([]float64, n), Max: make([]float64, n)}
+	for i := range d.Inputs {
+		a.Min[i], a.Max[i] = math.Inf(1), math.Inf(-1)
+		for _, x := range d.X {
+			a.Min[i] = math.Min(a.Min[i], x[i])
+			a.Max[i] = math.Max(a.Max[i], x[i])
+		}
+		if a.Min[i] == a.Max[i] {
+			a.Max[i] = a.Min[i] + 1
+		}
+		width := a.Max[i] - a.Min[i]
+		means := linspace(a.Min[i], a.Max[i], terms)
+		sigmas := make([]float64, terms)
+		for k := range sigmas {
+			sigmas[k] = width / float64(2*(terms-1))
+		}
+		a.Means = append(a.Means, means)
+		a.Sigmas = append(a.Sigmas, sigmas)
+	}
+	// wszystkie kombinacje terminów, jak liczby w systemie o podstawie terms
+	total := 1
+	for range d.Inputs {
+		total *= terms
+	}
+	for r := 0; r < total; r++ {
+		rule := make([]int, n)
+		for i, rest := n-1, r; i >= 0; i-- {
+			rule[i], rest = rest%terms, rest/terms
+		}
+		a.Rules = append(a.Rules, rule)
+		a.Coef = append(a.Coef, make([]float64, n+1))
+	}
+	return a
+}
+
+// memberships zwraca stopnie przynależności x do terminów: [wejście][termin]
+func (a *ANFIS) memberships(x []float64) [][]float64 {
+	mu := make([][]float64, len(a.Inputs))
+	for i := range mu {
+		mu[i] = make([]float64, len(a.Means[i]))
+		for k := range mu[i] {
+			mu[i][k] = Gaussian{a.Means[i][k], a.Sigmas[i][k]}.Degree(x[i])
+		}
+	}
+	return mu
+}
+
+// strengths zwraca siły odpalenia reguł (iloczyn przesłanek) i ich sumę
+func (a *ANFIS) strengths(mu [][]float64) ([]float64, float64) {
+	w := make([]float64, len(a.Rules))
+	sum := 0.0
+	for r, rule := range a.Rules {
+		w[r] = 1
+		for i, k := range rule {
+			w[r] *= mu[i][k]
+		}
+		sum += w[r]
+	}
+	return w, sum
+}
+
+// consequent liczy wyjście reguły r dla x
+func (a *ANFIS) consequent(r int, x []float64) float64 {
+	f := a.Coef[r][0]
+	for i, xi := range x {
+		f += a.Coef[r][i+1] * xi
+	}
+	return f
+}
+
+// Predict liczy wyjście sieci: średnią wyjść reguł ważoną siłami odpalenia
+func (a *ANFIS) Predict(x []float64) float64 {
+	w, sum := a.strengths(a.memberships(x))
+	if sum == 0 {
+		return 0
+	}
+	y := 0.0
+	for r := range a.Rules {
+		y += w[r] * a.consequent(r, x)
+	}
+	return y / sum
+}
+
+// RMSE to pierwiastek błędu średniokwadratowego na zbiorze d
+func (a *ANFIS) RMSE(d *Dataset) float64 {
+	sum := 0.0
+	for j, x := range d.X {
+		e := a.Predict(x) - d.Y[j]
+		sum += e * e
+	}
+	return math.Sqrt(sum / float64(len(d.Y)))
+}
+
+// fitConsequents wyznacza współczynniki wyjść reguł metodą najmniejszych
+// kwadratów (przy ustalonych przesłankach wyjście jest w nich liniowe).
+// Mała regularyzacja ridge stabilizuje układ, gdy reguł jest więcej niż danych
+func (a *ANFIS) fitConsequents(d *Dataset, ridge float64) error {
+	n := len(a.Inputs) + 1
+	size := len(a.Rules) * n
+	ata := make([][]float64, size)
+	for i := range ata {
+		ata[i] = make([]float64, size)
+		ata[i][i] = ridge
+	}
+	aty := make([]float64, size)
+	row := make([]float64, size)
+	for j, x := range d.X {
+		w, sum := a.strengths(a.memberships(x))
+		if sum == 0 {
+			continue
+		}
+		for r := range a.Rules {
+			nw := w[r] / sum
+			row[r*n] = nw
+			for i, xi := range x {
+				row[r*n+i+1] = nw * xi
+			}
+		}
+		for p, rp := range row {
+			if rp == 0 {
+				continue
+			}
+			aty[p] += rp * d.Y[j]
+			for q, rq := range row {
+				ata[p][q] += rp * rq
+			}
+		}
+	}
+	coef, err := solveLinear(ata, aty)
+	if err != nil {
+		return err
+	}
+	for r := range a.Rules {
+		copy(a.Coef[r], coef[r*n:(r+1)*n])
+	}
+	return nil
+}
+
+// solveLinear rozwiązuje układ m x = b eliminacją Gaussa z wyborem elementu głównego.
+// Macierz m i wektor b są modyfikowane
+func solveLinear(m [][]float64, b []float64) ([]float64, error) {
+	n := len(b)
+	for c := 0; c < n; c++ {
+		pivot := c
+		for r := c + 1; r < n; r++ {
+			if math.Abs(m[r][c]) > math.Abs(m[pivot][c]) {
+				pivot = r
+			}
+		}
+		if math.Abs(m[pivot][c]) < 1e-12 {
+			return nil, errors.New("least squares system is singular")
+		}
+		m[c], m[pivot] = m[pivot], m[c]
+		b[c], b[pivot] = b[pivot], b[c]
+		for r := c + 1; r < n; r++ {
+			f := m[r][c] / m[c][c]
+			if f == 0 {
+				continue
+			}
+			for k := c; k < n; k++ {
+				m[r][k] -= f * m[c][k]
+			}
+			b[r] -= f * b[c]
+		}
+	}
+	x := make([]float64, n)
+	for r := n - 1; r >= 0; r-- {
+		s := b[r]
+		for k := r + 1; k < n; k++ {
+			s -= m[r][k] * x[k]
+		}
+		x[r] = s / m[r][r]
+	}
+	return x, nil
+}
+
+// gradientStep przesuwa środki i szerokości terminów wejść w kierunku
+// przeciwnym do gradientu błędu średniokwadratowego (wsteczna propagacja
+// przy ustalonych wyjściach reguł)
+func (a *ANFIS) gradientStep(d *Dataset, rate float64) {
+	gradMean := make([][]float64, len(a.Inputs))
+	gradSigma := make([][]float64, len(a.Inputs))
+	for i := range a.Inputs {
+		gradMean[i] = make([]float64, len(a.Means[i]))
+		gradSigma[i] = make([]float64, len(a.Means[i]))
+	}
+	f := make([]float64, len(a.Rules))
+	for j, x := range d.X {
+		mu := a.memberships(x)
+		w, sum := a.strengths(mu)
+		if sum == 0 {
+			continue
+		}
+		y := 0.0
+		for r := range a.Rules {
+			f[r] = a.consequent(r, x)
+			y += w[r] * f[r]
+		}
+		y /= sum
+		e := y - d.Y[j]
+		for r, rule := range a.Rules {
+			// dE/dw_r = e * (f_r - y) / suma sił
+			dw := e * (f[r] - y) / sum
+			for i, k := range rule {
+				// dw_r/dmu = iloczyn stopni pozostałych przesłanek reguły
+				others := 1.0
+				for i2, k2 := range rule {
+					if i2 != i {
+						others *= mu[i2][k2]
+					}
+				}
+				m, s := a.Means[i][k], a.Sigmas[i][k]
+				g := dw * others * mu[i][k]
+				gradMean[i][k] += g * (x[i] - m) / (s * s)
+				gradSigma[i][k] += g * (x[i] - m) * (x[i] - m) / (s * s * s)
+			}
+		}
+	}
+	scale := 2 * rate / float64(len(d.Y))
+	for i := range a.Inputs {
+		width := a.Max[i] - a.Min[i]
+		for k := range a.Means[i] {
+			a.Means[i][k] = clamp(a.Means[i][k]-scale*gradMean[i][k]*width*width, a.Min[i], a.Max[i])
+			a.Sigmas[i][k] = math.Max(a.Sigmas[i][k]-scale*gradSigma[i][k]*width*width, width/100)
+		}
+	}
+}
+
+// TrainConfig to parametry uczenia
+type TrainConfig struct {
+	Terms      int     // liczba terminów na wejście
+	Epochs     int     // liczba epok
+	Rate       float64 // początkowy krok gradientu (względem szerokości dziedziny)
+	Validation float64 // ułamek rekordów odłożonych do walidacji
+	Ridge      float64 // regularyzacja najmniejszych kwadratów
+	Seed       int64   // ziarno podziału danych
+}
+
+// DefaultTrainConfig zwraca parametry sprawdzone na syntetycznych danych
+// attendance.csv (sposób ich wygenerowania opisuje README)
+func DefaultTrainConfig() TrainConfig {
+	return TrainConfig{Terms: 3, Epochs: 60, Rate: 2, Validation: 0.25, Ridge: 1, Seed: 1}
+}
+
+// TrainingHistory to błędy RMSE na zbiorze uczącym i walidacyjnym po każdej epoce
+type TrainingHistory struct {
+	Train, Validation []float64
+}
+
+// TrainANFIS uczy sieć metodą hybrydową: w każdej epoce współczynniki wyjść reguł
+// są wyznaczane najmniejszymi kwadratami, a parametry terminów wejść poprawiane
+// krokiem gradientu. Krok rośnie, gdy błąd maleje, i maleje, gdy błąd rośnie.
+// Zwracana jest sieć z epoki o najmniejszym błędzie walidacyjnym. Dziedziny
+// wejść wyznacza tylko część ucząca, żeby rekordy walidacyjne nie wpływały na sieć
+func TrainANFIS(d *Dataset, cfg TrainConfig) (*ANFIS, TrainingHistory, error) {
+	var h TrainingHistory
+	if err := cfg.check(); err != nil {
+		return nil, h, err
+	}
+	train, valid := d.split(cfg.Validation, rand.New(rand.NewSource(cfg.Seed)))
+	if len(train.Y) == 0 {
+		return nil, h, errors.New("no records left for training")
+	}
+	a := NewANFIS(train, cfg.Terms)
+	var best *ANFIS
+	bestErr := math.Inf(1)
+	rate := cfg.Rate
+	for epoch := 0; epoch < cfg.Epochs; epoch++ {
+		if err := a.fitConsequents(train, cfg.Ridge); err != nil {
+			return nil, h, fmt.Errorf("epoch %d: %w", epoch+1, err)
+		}
+		trainErr := a.RMSE(train)
+		validErr := trainErr
+		if len(valid.Y) > 0 {
+			validErr = a.RMSE(valid)
+		}
+		if n := len(h.Train); n > 0 && trainErr > h.Train[n-1] {
+			rate *= 0.7
+		} else {
+			rate *= 1.05
+		}
+		h.Train = append(h.Train, trainErr)
+		h.Validation = append(h.Validation, validErr)
+		if validErr < bestErr {
+			best, bestErr = a.clone(), validErr
+		}
+		a.gradientStep(train, rate)
+	}
+	if best == nil {
+		return nil, h, errors.New("training diverged, no epoch has a finite validation error")
+	}
+	return best, h, nil
+}
+
+// check odrzuca parametry, z którymi uczenie nie przebiegłoby ani jednej epoki
+// albo nie miałoby sensu
+func (cfg TrainConfig) check() error {
+	switch {
+	case cfg.Terms < 2:
+		return fmt.Errorf("need at least 2 terms per input, got %d", cfg.Terms)
+	case cfg.Epochs < 1:
+		return fmt.Errorf("need at least 1 epoch, got %d", cfg.Epochs)
+	case !(cfg.Rate > 0) || math.IsInf(cfg.Rate, 0):
+		return fmt.Errorf("learning rate must be positive, got %g", cfg.Rate)
+	case !(cfg.Validation >= 0 && cfg.Validation < 1):
+		return fmt.Errorf("validation fraction must be in [0, 1), got %g", cfg.Validation)
+	case !(cfg.Ridge >= 0) || math.IsInf(cfg.Ridge, 0):
+		return fmt.Errorf("ridge must be non-negative, got %g", cfg.Ridge)
+	}
+	return nil
+}
+
+// clone zwraca głęboką kopię sieci
+func (a *ANFIS) clone() *ANFIS {
+	c := &ANFIS{Inputs: a.Inputs, Output: a.Output, Min: a.Min, Max: a.Max, Rules: a.Rules}
+	for i := range a.Means {
+		c.Means = append(c.Means, append([]float64(nil), a.Means[i]...))
+		c.Sigmas = append(c.Sigmas, append([]float64(nil), a.Sigmas[i]...))
+	}
+	for _, coef := range a.Coef {
+		c.Coef = append(c.Coef, append([]float64(nil), coef...))
+	}
+	return c
+}
+
+// termNames nazywa terminy wejścia zależnie od ich liczby
+func termNames(n int) []string {
+	switch n {
+	case 2:
+		return []string{"low", "high"}
+	case 3:
+		return []string{"low", "medium", "high"}
+	}
+	names := make([]string, n)
+	for i := range names {
+		names[i] = fmt.Sprintf("mf%d", i+1)
+	}
+	return names
+}
+
+// System zamienia nauczoną sieć na system Sugeno pierwszego rzędu, który można
+// wyliczać, rysować i zapisać do pliku FCL
+func (a *ANFIS) System() *System {
+	s := NewSystem(a.Output+"_anfis", Sugeno)
+	s.Operators = Operators{And: ProductAnd, Or: ProbOr}
+	for i, name := range a.Inputs {
+		v := &Variable{Name: name, Min: a.Min[i], Max: a.Max[i]}
+		for k, term := range termNames(len(a.Means[i])) {
+			v.Terms = append(v.Terms, Term{Name: term, MF: Gaussian{a.Means[i][k], a.Sigmas[i][k]}})
+		}
+		s.Inputs = append(s.Inputs, v)
+	}
+	out := &Variable{Name: a.Output, Min: math.Inf(1), Max: math.Inf(-1)}
+	for r, rule := range a.Rules {
+		term := fmt.Sprintf("r%d", r+1)
+		sugeno := &SugenoOutput{Constant: a.Coef[r][0], Coefficients: make(map[string]float64)}
+		var cond And
+		for i, k := range rule {
+			sugeno.Coefficients[a.Inputs[i]] = a.Coef[r][i+1]
+			cond = append(cond, Is{a.Inputs[i], s.Inputs[i].Terms[k].Name})
+		}
+		out.Terms = append(out.Terms, Term{Name: term, Sugeno: sugeno})
+		s.Rules = append(s.Rules, Rule{If: cond, Then: []Is{{a.Output, term}}, Weight: 1})
+	}
+	// dziedzina wyjścia obejmuje wartości sieci w środkach terminów wszystkich reguł
+	for _, c := range a.Rules {
+		x := make([]float64, len(c))
+		for i, k := range c {
+			x[i] = a.Means[i][k]
+		}
+		y := a.Predict(x)
+		out.Min, out.Max = math.Min(out.Min, y), math.Max(out.Max, y)
+	}
+	if out.Min == out.Max {
+		out.Max = out.Min + 1
+	}
+	s.Outputs = []*Variable{out}
+	return s
+}
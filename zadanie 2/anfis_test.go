@@ -0,0 +1,48 @@
+package main
+
+import "testing"
+
+// lineDataset to prosty zbiór y = 2x na odcinku [0, 1]
+func lineDataset() *Dataset {
+	d := &Dataset{Inputs: []string{"x"}, Target: "y"}
+	for i := 0; i <= 20; i++ {
+		x := float64(i) / 20
+		d.X = append(d.X, []float64{x})
+		d.Y = append(d.Y, 2*x)
+	}
+	return d
+}
+
+func TestTrainANFISRejectsConfig(t *testing.T) {
+	for name, change := range map[string]func(*TrainConfig){
+		"zero epochs":     func(c *TrainConfig) { c.Epochs = 0 },
+		"negative epochs": func(c *TrainConfig) { c.Epochs = -3 },
+		"one term":        func(c *TrainConfig) { c.Terms = 1 },
+		"zero rate":       func(c *TrainConfig) { c.Rate = 0 },
+		"validation 1":    func(c *TrainConfig) { c.Validation = 1 },
+		"validation < 0":  func(c *TrainConfig) { c.Validation = -0.1 },
+		"negative ridge":  func(c *TrainConfig) { c.Ridge = -1 },
+	} {
+		cfg := DefaultTrainConfig()
+		change(&cfg)
+		model, _, err := TrainANFIS(lineDataset(), cfg)
+		if err == nil || model != nil {
+			t.Errorf("%s: expected an error and no model, found %v, %v", name, model, err)
+		}
+	}
+}
+
+func TestTrainANFISFitsLine(t *testing.T) {
+	cfg := DefaultTrainConfig()
+	cfg.Epochs, cfg.Ridge = 5, 1e-6
+	model, history, err := TrainANFIS(lineDataset(), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history.Train) != cfg.Epochs || len(history.Validation) != cfg.Epochs {
+		t.Errorf("expected %d epochs of history, found %d and %d", cfg.Epochs, len(history.Train), len(history.Validation))
+	}
+	if rmse := model.RMSE(lineDataset()); rmse > 0.05 {
+		t.Errorf("expected the model to fit y = 2x, found RMSE %.4f", rmse)
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+// AttendanceSystem buduje system Mamdaniego oceniający sens pójścia na zajęcia.
+// Wejścia:
+// - hours: liczba godzin zajęć w danym dniu (0 - 12), terminy few i many
+// - importance: subiektywna ważność zajęć (0 - 5), terminy low, medium, high
+// - distance: dystans do pokonania w km (0 - 100), terminy near i far
+// Wyjście attendance (0 - 5) ma terminy skip, maybe i go.
+// Na ważne zajęcia warto iść, a gdy są daleko i trwają dłużej, może warto.
+// Średnio ważne zajęcia są warte pójścia, gdy są blisko albo trwają krótko,
+// a mało ważne zwykle lepiej opuścić. Mniej godzin, większa ważność i mniejszy
+// dystans zwiększają więc ocenę, a dzień z wieloma godzinami mniej niż bardzo
+// ważnych zajęć przemawia za opuszczeniem
+func AttendanceSystem() *System {
+	s := NewSystem("attendance", Mamdani)
+	s.Inputs = []*Variable{
+		{Name: "hours", Min: 0, Max: 12, Terms: []Term{
+			{Name: "few", MF: Trapezoidal{0, 0, 2, 5}},
+			{Name: "many", MF: Trapezoidal{7, 10, 12, 12}},
+		}},
+		{Name: "importance", Min: 0, Max: 5, Terms: []Term{
+			{Name: "low", MF: Trapezoidal{0, 0, 1, 2.5}},
+			{Name: "medium", MF: Gaussian{Mean: 2.5, Sigma: 0.7}},
+			{Name: "high", MF: Sigmoid{Slope: 4, Center: 3.5}},
+		}},
+		{Name: "distance", Min: 0, Max: 100, Terms: []Term{
+			{Name: "near", MF: Trapezoidal{0, 0, 10, 30}},
+			{Name: "far", MF: Trapezoidal{45, 70, 100, 100}},
+		}},
+	}
+	s.Outputs = []*Variable{
+		{Name: "attendance", Min: 0, Max: 5, Terms: []Term{
+			{Name: "skip", MF: Trapezoidal{0, 0, 1, 2}},
+			{Name: "maybe", MF: Triangular{1, 2.5, 4}},
+			{Name: "go", MF: Trapezoidal{3, 4, 5, 5}},
+		}},
+	}
+	s.Rules = attendanceRules()
+	return s
+}
+
+// AttendanceSugenoSystem to ten sam problem jako system Sugeno zerowego rzędu:
+// każdy termin wyjścia jest stałą oceną
+func AttendanceSugenoSystem() *System {
+	s := AttendanceSystem()
+	s.Type = Sugeno
+	s.Operators = Operators{And: ProductAnd, Or: ProbOr}
+	s.Outputs = []*Variable{
+		{Name: "attendance", Min: 0, Max: 5, Terms: []Term{
+			{Name: "skip", Sugeno: &SugenoOutput{Constant: 0.5}},
+			{Name: "maybe", Sugeno: &SugenoOutput{Constant: 2.5}},
+			{Name: "go", Sugeno: &SugenoOutput{Constant: 4.5}},
+		}},
+	}
+	return s
+}
+
+// AttendanceType2System to system Mamdaniego, w którym subiektywna ważność
+// zajęć jest opisana przedziałowymi zbiorami typu 2: różne osoby różnie
+// rozumieją "ważne zajęcia", więc każdy termin ma dolną i górną funkcję
+// przynależności. Pozostałe zmienne pozostają zbiorami typu 1
+func AttendanceType2System() *System {
+	s := AttendanceSystem()
+	s.Name = "attendance_type2"
+	importance, _ := s.Input("importance")
+	importance.Terms = []Term{
+		{Name: "low", MF: IntervalMF{Upper: Trapezoidal{0, 0, 1.5, 3}, Lower: Trapezoidal{0, 0, 0.5, 2}}},
+		{Name: "medium", MF: IntervalMF{Upper: Gaussian{Mean: 2.5, Sigma: 0.9}, Lower: Gaussian{Mean: 2.5, Sigma: 0.5}}},
+		{Name: "high", MF: IntervalMF{Upper: Sigmoid{Slope: 4, Center: 3.2}, Lower: Sigmoid{Slope: 4, Center: 3.8}}},
+	}
+	return s
+}
+
+// attendanceRules zwraca bazę reguł wspólną dla wszystkich wariantów systemu.
+// Reguły z "hours IS NOT few" i "hours IS many" obejmują wszystkie dni z więcej
+// niż kilkoma godzinami, więc dłuższy dzień nigdy nie wypada z bazy reguł
+// i nie dostaje przez to wyższej oceny niż krótszy
+func attendanceRules() []Rule {
+	then := func(term string) []Is { return []Is{{"attendance", term}} }
+	return []Rule{
+		{If: And{Is{"importance", "high"}, Not{Is{"distance", "far"}}}, Then: then("go"), Weight: 1},
+		{If: And{Is{"importance", "high"}, Is{"hours", "few"}}, Then: then("go"), Weight: 1},
+		{If: And{Is{"importance", "high"}, Is{"distance", "far"}, Not{Is{"hours", "few"}}}, Then: then("maybe"), Weight: 1},
+		{If: And{Is{"importance", "medium"}, Or{Is{"distance", "near"}, Is{"hours", "few"}}}, Then: then("go"), Weight: 1},
+		{If: And{Is{"importance", "medium"}, Not{Is{"hours", "few"}}}, Then: then("maybe"), Weight: 1},
+		{If: And{Is{"importance", "low"}, Is{"distance", "near"}, Is{"hours", "few"}}, Then: then("maybe"), Weight: 1},
+		{If: Is{"importance", "low"}, Then: then("skip"), Weight: 1},
+		{If: And{Is{"hours", "many"}, Not{Is{"importance", "high"}}}, Then: then("skip"), Weight: 1},
+	}
+}
@@ -0,0 +1,29 @@
+package main
+
+import "testing"
+
+// TestAttendanceHoursMonotone sprawdza na siatce wejść, że więcej godzin zajęć
+// nie podnosi oceny, z tolerancją na wahania defuzyfikacji środkiem ciężkości
+func TestAttendanceHoursMonotone(t *testing.T) {
+	const tolerance = 0.1
+	for _, build := range []func() *System{AttendanceSystem, AttendanceSugenoSystem, AttendanceType2System} {
+		s := build()
+		for importance := 0.0; importance <= 5; importance += 0.5 {
+			for distance := 0.0; distance <= 100; distance += 10 {
+				prev := 0.0
+				for hours := 0.0; hours <= 12; hours += 0.5 {
+					result, _, err := s.Evaluate(map[string]float64{"hours": hours, "importance": importance, "distance": distance})
+					if err != nil {
+						t.Fatalf("%s: %v", s.Name, err)
+					}
+					x := result["attendance"]
+					if hours > 0 && x > prev+tolerance {
+						t.Errorf("%s: importance %g, distance %g: attendance rises from %.2f to %.2f at %g hours",
+							s.Name, importance, distance, prev, x, hours)
+					}
+					prev = x
+				}
+			}
+		}
+	}
+}
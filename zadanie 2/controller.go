@@ -0,0 +1,253 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Sample to próbka wejść systemu z chwilą pomiaru (Time) i chwilą odebrania (Received)
+type Sample struct {
+	Time     time.Time
+	Received time.Time
+	Inputs   map[string]float64
+}
+
+// ControlOutput to wynik jednego kroku sterownika
+type ControlOutput struct {
+	Tick    time.Time
+	Sample  Sample
+	Outputs Result
+	Err     error
+	Eval    time.Duration // czas samego wnioskowania
+	Latency time.Duration // czas od odebrania próbki do wyznaczenia wyjścia
+}
+
+// LatencyStats zbiera czasy wnioskowania i opóźnienia kolejnych kroków
+type LatencyStats struct {
+	Steps, Errors int
+	eval, latency []time.Duration
+}
+
+// add dopisuje wynik kroku do statystyk
+func (s *LatencyStats) add(out ControlOutput) {
+	s.Steps++
+	if out.Err != nil {
+		s.Errors++
+	}
+	s.eval = append(s.eval, out.Eval)
+	s.latency = append(s.latency, out.Latency)
+}
+
+// summary opisuje minimum, średnią, 95. percentyl i maksimum czasów
+func summary(ds []time.Duration) string {
+	if len(ds) == 0 {
+		return "no data"
+	}
+	sorted := append([]time.Duration(nil), ds...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	var sum time.Duration
+	for _, d := range sorted {
+		sum += d
+	}
+	p95 := sorted[(len(sorted)*95+99)/100-1]
+	return fmt.Sprintf("min %v  mean %v  p95 %v  max %v", sorted[0], sum/time.Duration(len(sorted)), p95, sorted[len(sorted)-1])
+}
+
+// Print wypisuje statystyki opóźnień
+func (s *LatencyStats) Print(w io.Writer) {
+	fmt.Fprintf(w, "steps: %d, errors: %d\n", s.Steps, s.Errors)
+	fmt.Fprintf(w, "evaluation: %s\n", summary(s.eval))
+	fmt.Fprintf(w, "latency:    %s\n", summary(s.latency))
+}
+
+// Controller wylicza wyjścia systemu rozmytego w stałym takcie Tick,
+// zawsze dla najnowszej odebranej próbki
+type Controller struct {
+	System *System
+	Tick   time.Duration
+	Stats  LatencyStats
+}
+
+// NewController tworzy sterownik o podanym takcie; Run wymaga dodatniego taktu,
+// a przy krokach wywoływanych ręcznie przez Step takt może być 0
+func NewController(system *System, tick time.Duration) *Controller {
+	return &Controller{System: system, Tick: tick}
+}
+
+// Step wylicza wyjścia dla próbki w chwili now i dopisuje wynik do statystyk
+func (c *Controller) Step(sample Sample, now time.Time) ControlOutput {
+	out := ControlOutput{Tick: now, Sample: sample}
+	start := time.Now()
+	out.Outputs, _, out.Err = c.System.Evaluate(sample.Inputs)
+	out.Eval = time.Since(start)
+	out.Latency = now.Sub(sample.Received) + out.Eval
+	c.Stats.add(out)
+	return out
+}
+
+// Run odbiera próbki z kanału i w każdym takcie przekazuje do emit wynik dla
+// najnowszej z nich. Próbka jest przetwarzana raz; takty bez nowej próbki są
+// pomijane. Run kończy się po zamknięciu kanału (przetworzywszy ostatnią próbkę)
+// albo po anulowaniu ctx. Takt musi być dodatni
+func (c *Controller) Run(ctx context.Context, samples <-chan Sample, emit func(ControlOutput)) error {
+	if c.Tick <= 0 {
+		return fmt.Errorf("expected a positive controller tick, found %v", c.Tick)
+	}
+	ticker := time.NewTicker(c.Tick)
+	defer ticker.Stop()
+	var latest Sample
+	pending := false
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case s, ok := <-samples:
+			if !ok {
+				if pending {
+					emit(c.Step(latest, time.Now()))
+				}
+				return nil
+			}
+			latest, pending = s, true
+		case now := <-ticker.C:
+			if pending {
+				emit(c.Step(latest, now))
+				pending = false
+			}
+		}
+	}
+}
+
+// readSamples czyta próbki w formacie CSV z nagłówkiem "time,wejście1,wejście2,..."
+// i wysyła je do out. Czas to sekundy (np. uniksowe) albo RFC 3339; pusty oznacza
+// chwilę odebrania. Przy follow koniec danych nie kończy czytania: funkcja czeka
+// na dopisanie kolejnych linii, jak tail -f
+func readSamples(ctx context.Context, r io.Reader, follow bool, out chan<- Sample) error {
+	defer close(out)
+	lines := bufio.NewReader(r)
+	var header []string
+	lineNo := 0
+	partial := ""
+	for {
+		chunk, err := lines.ReadString('\n')
+		partial += chunk
+		if errors.Is(err, io.EOF) && follow {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(100 * time.Millisecond):
+			}
+			continue
+		}
+		if err != nil && !errors.Is(err, io.EOF) {
+			return err
+		}
+		line := strings.TrimSpace(partial)
+		partial = ""
+		if line != "" {
+			lineNo++
+			fields, perr := csv.NewReader(strings.NewReader(line)).Read()
+			if perr != nil {
+				return fmt.Errorf("line %d: %v", lineNo, perr)
+			}
+			if header == nil {
+				if len(fields) < 2 || strings.TrimSpace(fields[0]) != "time" {
+					return fmt.Errorf("line %d: header must be time followed by input names", lineNo)
+				}
+				header = fields
+			} else {
+				sample, serr := parseSample(header, fields)
+				if serr != nil {
+					return fmt.Errorf("line %d: %v", lineNo, serr)
+				}
+				select {
+				case out <- sample:
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+	}
+}
+
+// parseSample buduje próbkę z pól linii CSV
+func parseSample(header, fields []string) (Sample, error) {
+	if len(fields) != len(header) {
+		return Sample{}, fmt.Errorf("expected %d fields, got %d", len(header), len(fields))
+	}
+	now := time.Now()
+	s := Sample{Time: now, Received: now, Inputs: make(map[string]float64, len(header)-1)}
+	if ts := strings.TrimSpace(fields[0]); ts != "" {
+		t, err := parseTimestamp(ts)
+		if err != nil {
+			return Sample{}, err
+		}
+		s.Time = t
+	}
+	for i := 1; i < len(header); i++ {
+		x, err := strconv.ParseFloat(strings.TrimSpace(fields[i]), 64)
+		if err != nil {
+			return Sample{}, fmt.Errorf("invalid value of %q: %v", header[i], err)
+		}
+		s.Inputs[strings.TrimSpace(header[i])] = x
+	}
+	return s, nil
+}
+
+// parseTimestamp czyta czas jako liczbę sekund albo w formacie RFC 3339
+func parseTimestamp(s string) (time.Time, error) {
+	if secs, err := strconv.ParseFloat(s, 64); err == nil {
+		return time.Unix(0, int64(secs*float64(time.Second))), nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid timestamp %q (want seconds or RFC 3339)", s)
+	}
+	return t, nil
+}
+
+// writeControlCSV wypisuje wynik kroku jako linię CSV: czas próbki, wejścia, wyjścia i opóźnienie w µs
+func writeControlCSV(w *csv.Writer, system *System, out ControlOutput) {
+	record := []string{out.Sample.Time.Format(time.RFC3339Nano)}
+	for _, v := range system.Inputs {
+		record = append(record, strconv.FormatFloat(out.Sample.Inputs[v.Name], 'g', -1, 64))
+	}
+	for _, v := range system.Outputs {
+		if out.Err != nil {
+			record = append(record, "")
+		} else {
+			record = append(record, strconv.FormatFloat(out.Outputs[v.Name], 'f', 3, 64))
+		}
+	}
+	record = append(record, strconv.FormatInt(out.Latency.Microseconds(), 10))
+	if out.Err != nil {
+		record = append(record, out.Err.Error())
+	} else {
+		record = append(record, "")
+	}
+	w.Write(record)
+	w.Flush()
+}
+
+// controlCSVHeader zwraca nagłówek linii wypisywanych przez writeControlCSV
+func controlCSVHeader(system *System) []string {
+	header := []string{"time"}
+	for _, v := range system.Inputs {
+		header = append(header, v.Name)
+	}
+	for _, v := range system.Outputs {
+		header = append(header, v.Name)
+	}
+	return append(header, "latency_us", "error")
+}
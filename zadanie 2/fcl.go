@@ -0,0 +1,931 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Parser języka FCL (Fuzzy Control Language, IEC 61131-7) budujący System.
+// Obsługiwany podzbiór:
+//
+//	FUNCTION_BLOCK nazwa
+//	VAR_INPUT / VAR_OUTPUT: deklaracje "nazwa : REAL;" zakończone END_VAR
+//	FUZZIFY / DEFUZZIFY: RANGE := (min .. max); oraz terminy
+//	  RANGE := (min .. max) REJECT | CLAMP;  polityka wejścia spoza dziedziny (tylko FUZZIFY)
+//	  TERM t := (x, y) (x, y) ...;  łamana
+//	  TERM t := trian a b c;  trape a b c d;  gauss m s;  sigm s c;
+//	  TERM t := UPPER funkcja LOWER funkcja;  zbiór przedziałowy typu 2
+//	  TERM t := 2.5;  singleton (wyjście Sugeno, METHOD : COGS)
+//	  TERM t := 1 + 0.2 * hours - 0.01 * distance;  wyjście Sugeno pierwszego rzędu
+//	  METHOD : COG | COGS | BOA | MOM | LM | RM;  DEFAULT := wartość;  ACCU : MAX;
+//	RULEBLOCK: AND : MIN | PROD;  OR : MAX | ASUM;  ACT : MIN;  ACCU : MAX;
+//	  RULE n : IF warunek THEN zmienna IS termin [, ...] [WITH waga];
+//
+// Komentarze zapisuje się jako (* ... *) lub // do końca linii.
+// Słowa kluczowe nie rozróżniają wielkości liter, nazwy zmiennych i terminów tak
+
+// FCLError to błąd w pliku FCL wskazujący numer linii
+type FCLError struct {
+	Line int
+	Msg  string
+}
+
+func (e *FCLError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Msg)
+}
+
+// LoadFCL wczytuje system z pliku FCL
+func LoadFCL(path string) (*System, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	s, err := ParseFCL(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return s, nil
+}
+
+// ParseFCL czyta definicję systemu w języku FCL
+func ParseFCL(r io.Reader) (*System, error) {
+	src, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	tokens, err := lexFCL(string(src))
+	if err != nil {
+		return nil, err
+	}
+	p := &fclParser{tokens: tokens, declared: make(map[string]int)}
+	if err := p.parse(); err != nil {
+		return nil, err
+	}
+	return p.system, nil
+}
+
+// fclToken to pojedynczy element pliku FCL: nazwa, liczba albo symbol
+type fclToken struct {
+	kind byte // 'i' nazwa, 'n' liczba, 'p' symbol, 0 koniec pliku
+	text string
+	line int
+}
+
+// lexFCL dzieli tekst na tokeny, pomijając komentarze
+func lexFCL(src string) ([]fclToken, error) {
+	var tokens []fclToken
+	line := 1
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == '\n':
+			line++
+			i++
+		case c == ' ' || c == '\t' || c == '\r':
+			i++
+		case strings.HasPrefix(src[i:], "(*"):
+			start := line
+			end := strings.Index(src[i+2:], "*)")
+			if end < 0 {
+				return nil, &FCLError{start, "unterminated comment"}
+			}
+			line += strings.Count(src[i:i+2+end], "\n")
+			i += end + 4
+		case strings.HasPrefix(src[i:], "//"):
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+		case c == '_' || unicode.IsLetter(rune(c)):
+			j := i
+			for j < len(src) && (src[j] == '_' || unicode.IsLetter(rune(src[j])) || unicode.IsDigit(rune(src[j]))) {
+				j++
+			}
+			tokens = append(tokens, fclToken{'i', src[i:j], line})
+			i = j
+		case unicode.IsDigit(rune(c)) || c == '.' && i+1 < len(src) && unicode.IsDigit(rune(src[i+1])):
+			j := i
+			for j < len(src) && unicode.IsDigit(rune(src[j])) {
+				j++
+			}
+			// kropka należy do liczby, o ile nie zaczyna operatora zakresu ".."
+			if j+1 < len(src) && src[j] == '.' && src[j+1] != '.' {
+				j++
+				for j < len(src) && unicode.IsDigit(rune(src[j])) {
+					j++
+				}
+			}
+			if j < len(src) && (src[j] == 'e' || src[j] == 'E') {
+				k := j + 1
+				if k < len(src) && (src[k] == '+' || src[k] == '-') {
+					k++
+				}
+				if k < len(src) && unicode.IsDigit(rune(src[k])) {
+					for k < len(src) && unicode.IsDigit(rune(src[k])) {
+						k++
+					}
+					j = k
+				}
+			}
+			tokens = append(tokens, fclToken{'n', src[i:j], line})
+			i = j
+		case strings.HasPrefix(src[i:], ":="), strings.HasPrefix(src[i:], ".."):
+			tokens = append(tokens, fclToken{'p', src[i : i+2], line})
+			i += 2
+		case strings.ContainsRune(":;(),+-*", rune(c)):
+			tokens = append(tokens, fclToken{'p', string(c), line})
+			i++
+		default:
+			return nil, &FCLError{line, fmt.Sprintf("unexpected character %q", c)}
+		}
+	}
+	return append(tokens, fclToken{line: line}), nil
+}
+
+// fclTerm to termin zmiennej razem z linią, w której go zdefiniowano
+type fclTerm struct {
+	term Term
+	line int
+	args []float64 // parametry funkcji przynależności do sprawdzenia z dziedziną
+}
+
+// fclParser przechowuje stan parsowania jednego bloku funkcyjnego
+type fclParser struct {
+	tokens   []fclToken
+	pos      int
+	system   *System
+	declared map[string]int // linia deklaracji zmiennej w VAR_INPUT/VAR_OUTPUT
+	method   *fclToken      // pierwsza deklaracja METHOD
+	andOp    *fclToken      // pierwsza deklaracja AND w RULEBLOCK
+	orOp     *fclToken      // pierwsza deklaracja OR w RULEBLOCK
+}
+
+func (p *fclParser) peek() fclToken {
+	return p.tokens[p.pos]
+}
+
+func (p *fclParser) next() fclToken {
+	t := p.tokens[p.pos]
+	if t.kind != 0 {
+		p.pos++
+	}
+	return t
+}
+
+// errorf zwraca błąd wskazujący linię tokenu t
+func errorf(t fclToken, format string, args ...any) error {
+	return &FCLError{t.line, fmt.Sprintf(format, args...)}
+}
+
+// describe opisuje token w komunikacie o błędzie
+func (t fclToken) describe() string {
+	if t.kind == 0 {
+		return "end of file"
+	}
+	return strconv.Quote(t.text)
+}
+
+// isKeyword sprawdza, czy token jest danym słowem kluczowym
+func (t fclToken) isKeyword(kw string) bool {
+	return t.kind == 'i' && strings.EqualFold(t.text, kw)
+}
+
+func (p *fclParser) keyword(kw string) error {
+	if t := p.next(); !t.isKeyword(kw) {
+		return errorf(t, "expected %s, found %s", kw, t.describe())
+	}
+	return nil
+}
+
+func (p *fclParser) symbol(sym string) error {
+	if t := p.next(); t.kind != 'p' || t.text != sym {
+		return errorf(t, "expected %q, found %s", sym, t.describe())
+	}
+	return nil
+}
+
+func (p *fclParser) ident() (fclToken, error) {
+	t := p.next()
+	if t.kind != 'i' {
+		return t, errorf(t, "expected a name, found %s", t.describe())
+	}
+	return t, nil
+}
+
+// number czyta liczbę, być może poprzedzoną znakiem
+func (p *fclParser) number() (float64, error) {
+	sign := 1.0
+	if t := p.peek(); t.kind == 'p' && (t.text == "-" || t.text == "+") {
+		p.next()
+		if t.text == "-" {
+			sign = -1
+		}
+	}
+	t := p.next()
+	if t.kind != 'n' {
+		return 0, errorf(t, "expected a number, found %s", t.describe())
+	}
+	x, err := strconv.ParseFloat(t.text, 64)
+	if err != nil {
+		return 0, errorf(t, "invalid number %q", t.text)
+	}
+	return sign * x, nil
+}
+
+// parse czyta cały blok funkcyjny i sprawdza jego spójność
+func (p *fclParser) parse() error {
+	if err := p.keyword("FUNCTION_BLOCK"); err != nil {
+		return err
+	}
+	name, err := p.ident()
+	if err != nil {
+		return err
+	}
+	p.system = NewSystem(name.text, Mamdani)
+	for {
+		t := p.next()
+		switch {
+		case t.isKeyword("END_FUNCTION_BLOCK"):
+			return p.finish(t)
+		case t.isKeyword("VAR_INPUT"):
+			err = p.parseVars(&p.system.Inputs)
+		case t.isKeyword("VAR_OUTPUT"):
+			err = p.parseVars(&p.system.Outputs)
+		case t.isKeyword("FUZZIFY"):
+			err = p.parseVariable(p.system.Inputs, "FUZZIFY", "END_FUZZIFY")
+		case t.isKeyword("DEFUZZIFY"):
+			err = p.parseVariable(p.system.Outputs, "DEFUZZIFY", "END_DEFUZZIFY")
+		case t.isKeyword("RULEBLOCK"):
+			err = p.parseRuleBlock()
+		default:
+			return errorf(t, "unexpected %s, expected a section or END_FUNCTION_BLOCK", t.describe())
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// parseVars czyta deklaracje zmiennych aż do END_VAR
+func (p *fclParser) parseVars(vars *[]*Variable) error {
+	for !p.peek().isKeyword("END_VAR") {
+		name, err := p.ident()
+		if err != nil {
+			return err
+		}
+		if line, ok := p.declared[name.text]; ok {
+			return errorf(name, "variable %q already declared in line %d", name.text, line)
+		}
+		if err := p.symbol(":"); err != nil {
+			return err
+		}
+		if t := p.next(); !t.isKeyword("REAL") {
+			return errorf(t, "variable %q: only REAL variables are supported, found %s", name.text, t.describe())
+		}
+		if err := p.symbol(";"); err != nil {
+			return err
+		}
+		p.declared[name.text] = name.line
+		// dziedzina jest nieznana do czasu RANGE; NaN oznacza jej brak
+		*vars = append(*vars, &Variable{Name: name.text, Min: math.NaN(), Max: math.NaN()})
+	}
+	p.next()
+	return nil
+}
+
+// parseVariable czyta blok FUZZIFY lub DEFUZZIFY zmiennej z listy vars
+func (p *fclParser) parseVariable(vars []*Variable, section, end string) error {
+	name, err := p.ident()
+	if err != nil {
+		return err
+	}
+	v, ok := findVariable(vars, name.text)
+	if !ok {
+		kind := "VAR_INPUT"
+		if section == "DEFUZZIFY" {
+			kind = "VAR_OUTPUT"
+		}
+		return errorf(name, "%s of variable %q not declared in %s", section, name.text, kind)
+	}
+	if len(v.Terms) > 0 {
+		return errorf(name, "variable %q already has a %s block", name.text, section)
+	}
+	var terms []fclTerm
+	for {
+		t := p.next()
+		switch {
+		case t.isKeyword(end):
+			return p.checkTerms(v, name, terms)
+		case t.isKeyword("RANGE"):
+			err = p.parseRange(v, section)
+		case t.isKeyword("TERM"):
+			var term fclTerm
+			term, err = p.parseTerm(v, section)
+			terms = append(terms, term)
+		case section == "DEFUZZIFY" && t.isKeyword("METHOD"):
+			err = p.parseMethod()
+		case section == "DEFUZZIFY" && t.isKeyword("DEFAULT"):
+			err = p.parseDefault(v)
+		case section == "DEFUZZIFY" && t.isKeyword("ACCU"):
+			err = p.parseOption("ACCU", "MAX")
+		default:
+			return errorf(t, "unexpected %s in %s block of %q", t.describe(), section, name.text)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// parseRange czyta "RANGE := (min .. max) [REJECT | CLAMP];"; politykę
+// dziedziny można podać tylko dla wejścia
+func (p *fclParser) parseRange(v *Variable, section string) error {
+	if err := p.symbol(":="); err != nil {
+		return err
+	}
+	if err := p.symbol("("); err != nil {
+		return err
+	}
+	start := p.peek()
+	lo, err := p.number()
+	if err != nil {
+		return err
+	}
+	if err := p.symbol(".."); err != nil {
+		return err
+	}
+	hi, err := p.number()
+	if err != nil {
+		return err
+	}
+	if lo >= hi {
+		return errorf(start, "empty range (%g .. %g) of variable %q", lo, hi, v.Name)
+	}
+	v.Min, v.Max = lo, hi
+	if err := p.symbol(")"); err != nil {
+		return err
+	}
+	if t := p.peek(); t.kind == 'i' {
+		p.next()
+		policy, ok := map[string]RangePolicy{"REJECT": Reject, "CLAMP": Clamp}[strings.ToUpper(t.text)]
+		if !ok {
+			return errorf(t, "unknown range policy %q (want REJECT or CLAMP)", t.text)
+		}
+		if section != "FUZZIFY" {
+			return errorf(t, "range policy of output %q; only inputs have one", v.Name)
+		}
+		v.Policy = policy
+	}
+	return p.symbol(";")
+}
+
+// parseTerm czyta "TERM nazwa := definicja;"; wyjście Sugeno zamiast funkcji
+// przynależności wolno podać tylko w bloku DEFUZZIFY
+func (p *fclParser) parseTerm(v *Variable, section string) (fclTerm, error) {
+	name, err := p.ident()
+	if err != nil {
+		return fclTerm{}, err
+	}
+	if _, ok := v.Term(name.text); ok {
+		return fclTerm{}, errorf(name, "term %q of variable %q defined twice", name.text, v.Name)
+	}
+	if err := p.symbol(":="); err != nil {
+		return fclTerm{}, err
+	}
+	term := fclTerm{term: Term{Name: name.text}, line: name.line}
+	t := p.peek()
+	switch {
+	case t.isKeyword("upper"):
+		p.next()
+		upper, args, err := p.parseMF(name.text)
+		if err != nil {
+			return term, err
+		}
+		if err := p.keyword("lower"); err != nil {
+			return term, err
+		}
+		lower, lowerArgs, err := p.parseMF(name.text)
+		if err != nil {
+			return term, err
+		}
+		term.term.MF, term.args = IntervalMF{Upper: upper, Lower: lower}, append(args, lowerArgs...)
+	case t.kind == 'p' && t.text == "(", t.isKeyword("trian"), t.isKeyword("trape"), t.isKeyword("gauss"), t.isKeyword("sigm"):
+		if term.term.MF, term.args, err = p.parseMF(name.text); err != nil {
+			return term, err
+		}
+	case section != "DEFUZZIFY":
+		return term, errorf(t, "term %q of input %q needs a membership function (points, trian, trape, gauss or sigm), found %s", name.text, v.Name, t.describe())
+	default:
+		out, err := p.parseLinear()
+		if err != nil {
+			return term, err
+		}
+		term.term.Sugeno = out
+	}
+	v.Terms = append(v.Terms, term.term)
+	return term, p.symbol(";")
+}
+
+// parseMF czyta funkcję przynależności terminu name: łamaną albo trian, trape, gauss, sigm.
+// Zwraca też parametry do sprawdzenia z dziedziną zmiennej
+func (p *fclParser) parseMF(name string) (MembershipFunc, []float64, error) {
+	t := p.peek()
+	if t.kind == 'p' && t.text == "(" {
+		var points PiecewiseLinear
+		var xs []float64
+		for p.peek().kind == 'p' && p.peek().text == "(" {
+			p.next()
+			x, err := p.number()
+			if err != nil {
+				return nil, nil, err
+			}
+			if err := p.symbol(","); err != nil {
+				return nil, nil, err
+			}
+			y, err := p.number()
+			if err != nil {
+				return nil, nil, err
+			}
+			if err := p.symbol(")"); err != nil {
+				return nil, nil, err
+			}
+			if y < 0 || y > 1 {
+				return nil, nil, errorf(t, "term %q: membership degree %g out of range [0, 1]", name, y)
+			}
+			if len(points) > 0 && x < points[len(points)-1].X {
+				return nil, nil, errorf(t, "term %q: points must be ordered by x", name)
+			}
+			points = append(points, Point{x, y})
+			xs = append(xs, x)
+		}
+		return points, xs, nil
+	}
+	n, ok := map[string]int{"TRIAN": 3, "TRAPE": 4, "GAUSS": 2, "SIGM": 2}[strings.ToUpper(t.text)]
+	if t.kind != 'i' || !ok {
+		return nil, nil, errorf(t, "expected a membership function (points, trian, trape, gauss or sigm), found %s", t.describe())
+	}
+	p.next()
+	args := make([]float64, n)
+	for i := range args {
+		var err error
+		if args[i], err = p.number(); err != nil {
+			return nil, nil, err
+		}
+	}
+	switch strings.ToUpper(t.text) {
+	case "TRIAN":
+		if args[0] > args[1] || args[1] > args[2] {
+			return nil, nil, errorf(t, "term %q: triangle parameters must satisfy a <= b <= c", name)
+		}
+		return Triangular{args[0], args[1], args[2]}, args, nil
+	case "TRAPE":
+		if args[0] > args[1] || args[1] > args[2] || args[2] > args[3] {
+			return nil, nil, errorf(t, "term %q: trapezoid parameters must satisfy a <= b <= c <= d", name)
+		}
+		return Trapezoidal{args[0], args[1], args[2], args[3]}, args, nil
+	case "GAUSS":
+		if args[1] <= 0 {
+			return nil, nil, errorf(t, "term %q: gaussian width %g must be positive", name, args[1])
+		}
+		return Gaussian{args[0], args[1]}, args[:1], nil
+	default:
+		if args[0] == 0 {
+			return nil, nil, errorf(t, "term %q: sigmoid slope must not be zero", name)
+		}
+		return Sigmoid{args[0], args[1]}, args[1:], nil
+	}
+}
+
+// parseLinear czyta wyjście Sugeno: stałą lub sumę składników "c * wejście"
+func (p *fclParser) parseLinear() (*SugenoOutput, error) {
+	out := &SugenoOutput{}
+	for first := true; ; first = false {
+		sign := 1.0
+		t := p.peek()
+		if t.kind == 'p' && (t.text == "+" || t.text == "-") {
+			p.next()
+			if t.text == "-" {
+				sign = -1
+			}
+		} else if !first {
+			return out, nil
+		}
+		t = p.next()
+		coef, input := 1.0, ""
+		switch t.kind {
+		case 'n':
+			coef, _ = strconv.ParseFloat(t.text, 64)
+			if star := p.peek(); star.kind == 'p' && star.text == "*" {
+				p.next()
+				name, err := p.ident()
+				if err != nil {
+					return nil, err
+				}
+				input = name.text
+			}
+		case 'i':
+			if n := p.peek(); n.kind == 'n' {
+				return nil, errorf(t, "unknown membership function %q (want points, trian, trape, gauss or sigm)", t.text)
+			}
+			input = t.text
+		default:
+			return nil, errorf(t, "expected a membership function or a number, found %s", t.describe())
+		}
+		if input == "" {
+			out.Constant += sign * coef
+			continue
+		}
+		if _, ok := p.system.Input(input); !ok {
+			return nil, errorf(t, "undefined input variable %q in linear term", input)
+		}
+		if out.Coefficients == nil {
+			out.Coefficients = make(map[string]float64)
+		}
+		out.Coefficients[input] += sign * coef
+	}
+}
+
+// parseMethod czyta metodę wyostrzania; COGS oznacza wnioskowanie Sugeno
+func (p *fclParser) parseMethod() error {
+	if err := p.symbol(":"); err != nil {
+		return err
+	}
+	t, err := p.ident()
+	if err != nil {
+		return err
+	}
+	methods := map[string]Defuzzifier{"COG": Centroid, "COGS": Centroid, "BOA": Bisector, "MOM": MOM, "LM": SOM, "RM": LOM}
+	method, ok := methods[strings.ToUpper(t.text)]
+	if !ok {
+		return errorf(t, "unknown defuzzification method %q (want COG, COGS, BOA, MOM, LM or RM)", t.text)
+	}
+	if p.method != nil && !strings.EqualFold(p.method.text, t.text) {
+		return errorf(t, "method %s differs from %s in line %d; all outputs must use one method", t.text, p.method.text, p.method.line)
+	}
+	p.method = &t
+	p.system.Defuzzify = method
+	if strings.EqualFold(t.text, "COGS") {
+		p.system.Type = Sugeno
+	}
+	return p.symbol(";")
+}
+
+// parseDefault czyta wartość używaną, gdy żadna reguła nie odpaliła
+func (p *fclParser) parseDefault(v *Variable) error {
+	if err := p.symbol(":="); err != nil {
+		return err
+	}
+	if t := p.peek(); t.isKeyword("NC") {
+		// NC (no change) nie ma sensu bez stanu, traktujemy go jak brak wartości
+		p.next()
+		return p.symbol(";")
+	}
+	x, err := p.number()
+	if err != nil {
+		return err
+	}
+	v.Default = &x
+	return p.symbol(";")
+}
+
+// parseOption czyta "NAZWA : wartość;" dla opcji, która ma jedną obsługiwaną wartość
+func (p *fclParser) parseOption(option, supported string) error {
+	if err := p.symbol(":"); err != nil {
+		return err
+	}
+	t, err := p.ident()
+	if err != nil {
+		return err
+	}
+	if !strings.EqualFold(t.text, supported) {
+		return errorf(t, "%s : %s is not supported (only %s)", option, t.text, supported)
+	}
+	return p.symbol(";")
+}
+
+// parseOperator czyta normę AND lub OR bloku reguł
+func (p *fclParser) parseOperator(op string, first **fclToken, norms map[string]func(a, b float64) float64) error {
+	if err := p.symbol(":"); err != nil {
+		return err
+	}
+	t, err := p.ident()
+	if err != nil {
+		return err
+	}
+	norm, ok := norms[strings.ToUpper(t.text)]
+	if !ok {
+		names := make([]string, 0, len(norms))
+		for name := range norms {
+			names = append(names, name)
+		}
+		return errorf(t, "unknown %s operator %q (want %s)", op, t.text, strings.Join(names, " or "))
+	}
+	if *first != nil && !strings.EqualFold((*first).text, t.text) {
+		return errorf(t, "%s : %s differs from %s in line %d; all rule blocks must use one operator", op, t.text, (*first).text, (*first).line)
+	}
+	*first = &t
+	if op == "AND" {
+		p.system.Operators.And = norm
+	} else {
+		p.system.Operators.Or = norm
+	}
+	return p.symbol(";")
+}
+
+// parseRuleBlock czyta blok reguł aż do END_RULEBLOCK
+func (p *fclParser) parseRuleBlock() error {
+	if _, err := p.ident(); err != nil {
+		return err
+	}
+	for {
+		t := p.next()
+		var err error
+		switch {
+		case t.isKeyword("END_RULEBLOCK"):
+			return nil
+		case t.isKeyword("AND"):
+			err = p.parseOperator("AND", &p.andOp, map[string]func(a, b float64) float64{"MIN": MinNorm, "PROD": ProductAnd})
+		case t.isKeyword("OR"):
+			err = p.parseOperator("OR", &p.orOp, map[string]func(a, b float64) float64{"MAX": MaxNorm, "ASUM": ProbOr})
+		case t.isKeyword("ACT"):
+			err = p.parseOption("ACT", "MIN")
+		case t.isKeyword("ACCU"):
+			err = p.parseOption("ACCU", "MAX")
+		case t.isKeyword("RULE"):
+			err = p.parseRule()
+		default:
+			return errorf(t, "unexpected %s in RULEBLOCK", t.describe())
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// parseRule czyta "RULE n : IF warunek THEN konkluzje [WITH waga];"
+func (p *fclParser) parseRule() error {
+	if t := p.next(); t.kind != 'n' && t.kind != 'i' {
+		return errorf(t, "expected a rule number, found %s", t.describe())
+	}
+	if err := p.symbol(":"); err != nil {
+		return err
+	}
+	if err := p.keyword("IF"); err != nil {
+		return err
+	}
+	cond, err := p.parseOr()
+	if err != nil {
+		return err
+	}
+	if err := p.keyword("THEN"); err != nil {
+		return err
+	}
+	rule := Rule{If: cond, Weight: 1}
+	for {
+		is, _, err := p.parseIs(p.system.Outputs, "output")
+		if err != nil {
+			return err
+		}
+		rule.Then = append(rule.Then, is)
+		if t := p.peek(); t.kind != 'p' || t.text != "," {
+			break
+		}
+		p.next()
+	}
+	if p.peek().isKeyword("WITH") {
+		p.next()
+		t := p.peek()
+		if rule.Weight, err = p.number(); err != nil {
+			return err
+		}
+		if rule.Weight < 0 || rule.Weight > 1 {
+			return errorf(t, "rule weight %g out of range [0, 1]", rule.Weight)
+		}
+	}
+	p.system.Rules = append(p.system.Rules, rule)
+	return p.symbol(";")
+}
+
+// parseOr czyta alternatywę koniunkcji (OR wiąże słabiej niż AND)
+func (p *fclParser) parseOr() (Expr, error) {
+	x, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	or := Or{x}
+	for p.peek().isKeyword("OR") {
+		p.next()
+		if x, err = p.parseAnd(); err != nil {
+			return nil, err
+		}
+		or = append(or, x)
+	}
+	if len(or) == 1 {
+		return or[0], nil
+	}
+	return or, nil
+}
+
+// parseAnd czyta koniunkcję warunków
+func (p *fclParser) parseAnd() (Expr, error) {
+	x, err := p.parseFactor()
+	if err != nil {
+		return nil, err
+	}
+	and := And{x}
+	for p.peek().isKeyword("AND") {
+		p.next()
+		if x, err = p.parseFactor(); err != nil {
+			return nil, err
+		}
+		and = append(and, x)
+	}
+	if len(and) == 1 {
+		return and[0], nil
+	}
+	return and, nil
+}
+
+// parseFactor czyta warunek, jego negację albo wyrażenie w nawiasach
+func (p *fclParser) parseFactor() (Expr, error) {
+	t := p.peek()
+	switch {
+	case t.isKeyword("NOT"):
+		p.next()
+		x, err := p.parseFactor()
+		if err != nil {
+			return nil, err
+		}
+		return Not{x}, nil
+	case t.kind == 'p' && t.text == "(":
+		p.next()
+		x, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		return x, p.symbol(")")
+	}
+	is, negated, err := p.parseIs(p.system.Inputs, "input")
+	if err != nil {
+		return nil, err
+	}
+	if negated {
+		return Not{is}, nil
+	}
+	return is, nil
+}
+
+// parseIs czyta "zmienna IS [NOT] termin" i sprawdza, czy zmienna i termin istnieją
+func (p *fclParser) parseIs(vars []*Variable, kind string) (Is, bool, error) {
+	name, err := p.ident()
+	if err != nil {
+		return Is{}, false, err
+	}
+	if err := p.keyword("IS"); err != nil {
+		return Is{}, false, err
+	}
+	negated := p.peek().isKeyword("NOT")
+	if negated {
+		if kind == "output" {
+			return Is{}, false, errorf(p.peek(), "NOT is not allowed in a conclusion")
+		}
+		p.next()
+	}
+	term, err := p.ident()
+	if err != nil {
+		return Is{}, false, err
+	}
+	v, ok := findVariable(vars, name.text)
+	if !ok {
+		return Is{}, false, errorf(name, "undefined %s variable %q", kind, name.text)
+	}
+	if _, ok := v.Term(term.text); !ok {
+		return Is{}, false, errorf(term, "undefined term %q of %s variable %q", term.text, kind, name.text)
+	}
+	return Is{name.text, term.text}, negated, nil
+}
+
+// checkTerms sprawdza po zamknięciu bloku, czy zmienna ma dziedzinę
+// i czy parametry wszystkich terminów w niej leżą
+func (p *fclParser) checkTerms(v *Variable, block fclToken, terms []fclTerm) error {
+	if math.IsNaN(v.Min) {
+		return errorf(block, "variable %q has no RANGE", v.Name)
+	}
+	if len(terms) == 0 {
+		return errorf(block, "variable %q has no terms", v.Name)
+	}
+	for _, t := range terms {
+		for _, x := range t.args {
+			if x < v.Min || x > v.Max {
+				return &FCLError{t.line, fmt.Sprintf("term %q: parameter %g out of range [%g, %g] of variable %q",
+					t.term.Name, x, v.Min, v.Max, v.Name)}
+			}
+		}
+		if m, ok := t.term.MF.(IntervalMF); ok {
+			if err := m.check(v.Min, v.Max); err != nil {
+				return &FCLError{t.line, fmt.Sprintf("term %q: %v", t.term.Name, err)}
+			}
+		}
+	}
+	return nil
+}
+
+// finish sprawdza, czy każda zadeklarowana zmienna została zdefiniowana
+// i czy terminy wyjść pasują do metody wyostrzania
+func (p *fclParser) finish(end fclToken) error {
+	for _, v := range append(append([]*Variable(nil), p.system.Inputs...), p.system.Outputs...) {
+		if len(v.Terms) == 0 {
+			return &FCLError{p.declared[v.Name], fmt.Sprintf("variable %q is declared but has no FUZZIFY/DEFUZZIFY block", v.Name)}
+		}
+	}
+	if len(p.system.Rules) == 0 {
+		return errorf(end, "function block %q has no rules", p.system.Name)
+	}
+	line := end.line
+	if p.method != nil {
+		line = p.method.line
+	}
+	if err := p.system.checkType2(); err != nil {
+		return &FCLError{line, err.Error()}
+	}
+	if err := p.system.Validate(); err != nil {
+		if p.system.Type == Sugeno {
+			return &FCLError{line, fmt.Sprintf("METHOD : COGS needs singleton or linear output terms: %v", err)}
+		}
+		return &FCLError{line, fmt.Sprintf("singleton output terms need METHOD : COGS: %v", err)}
+	}
+	return nil
+}
+
+// WriteFCL zapisuje system w języku FCL w postaci czytanej przez ParseFCL
+func WriteFCL(w io.Writer, s *System) error {
+	b := &strings.Builder{}
+	fmt.Fprintf(b, "FUNCTION_BLOCK %s\n\n", s.Name)
+	for _, section := range []struct {
+		name string
+		vars []*Variable
+	}{{"VAR_INPUT", s.Inputs}, {"VAR_OUTPUT", s.Outputs}} {
+		fmt.Fprintln(b, section.name)
+		for _, v := range section.vars {
+			fmt.Fprintf(b, "    %s : REAL;\n", v.Name)
+		}
+		fmt.Fprint(b, "END_VAR\n\n")
+	}
+	for _, v := range s.Inputs {
+		fmt.Fprintf(b, "FUZZIFY %s\n", v.Name)
+		writeFCLTerms(b, v, true)
+		fmt.Fprint(b, "END_FUZZIFY\n\n")
+	}
+	methods := map[Defuzzifier]string{Centroid: "COG", Bisector: "BOA", MOM: "MOM", SOM: "LM", LOM: "RM"}
+	for _, v := range s.Outputs {
+		fmt.Fprintf(b, "DEFUZZIFY %s\n", v.Name)
+		writeFCLTerms(b, v, false)
+		method := methods[s.Defuzzify]
+		if s.Type == Sugeno {
+			method = "COGS"
+		}
+		fmt.Fprintf(b, "    METHOD : %s;\n", method)
+		if v.Default != nil {
+			fmt.Fprintf(b, "    DEFAULT := %g;\n", *v.Default)
+		}
+		fmt.Fprint(b, "END_DEFUZZIFY\n\n")
+	}
+	fmt.Fprintln(b, "RULEBLOCK rules")
+	// normy rozpoznajemy po wartości dla 0.5 i 0.5: min 0.5, iloczyn 0.25, max 0.5, suma probabilistyczna 0.75
+	and, or := "MIN", "MAX"
+	if s.Operators.And != nil && s.Operators.And(0.5, 0.5) != 0.5 {
+		and = "PROD"
+	}
+	if s.Operators.Or != nil && s.Operators.Or(0.5, 0.5) != 0.5 {
+		or = "ASUM"
+	}
+	fmt.Fprintf(b, "    AND : %s;\n    OR : %s;\n", and, or)
+	for i, rule := range s.Rules {
+		fmt.Fprintf(b, "    RULE %d : %s;\n", i+1, rule)
+	}
+	fmt.Fprint(b, "END_RULEBLOCK\n\nEND_FUNCTION_BLOCK\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// writeFCLTerms zapisuje dziedzinę, politykę dziedziny wejścia i terminy zmiennej
+func writeFCLTerms(b *strings.Builder, v *Variable, input bool) {
+	policy := ""
+	if input && v.Policy == Clamp {
+		policy = " CLAMP"
+	}
+	fmt.Fprintf(b, "    RANGE := (%g .. %g)%s;\n", v.Min, v.Max, policy)
+	for _, term := range v.Terms {
+		switch {
+		case term.MF != nil:
+			fmt.Fprintf(b, "    TERM %s := %v;\n", term.Name, term.MF)
+		case term.Sugeno != nil:
+			fmt.Fprintf(b, "    TERM %s := %v;\n", term.Name, term.Sugeno)
+		}
+	}
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"testing"
+)
+
+// baseFCL to najmniejszy poprawny system: wejście x z terminami lo i hi
+// steruje wyjściem y z terminami off i on; do jego linii odnoszą się numery
+// linii w TestParseFCLErrors
+const baseFCL = `FUNCTION_BLOCK t
+VAR_INPUT
+    x : REAL;
+END_VAR
+VAR_OUTPUT
+    y : REAL;
+END_VAR
+FUZZIFY x
+    RANGE := (0 .. 10);
+    TERM lo := trian 0 0 10;
+    TERM hi := trian 0 10 10;
+END_FUZZIFY
+DEFUZZIFY y
+    RANGE := (0 .. 1);
+    TERM off := trian 0 0 1;
+    TERM on := trian 0 1 1;
+    METHOD : COG;
+END_DEFUZZIFY
+RULEBLOCK rules
+    RULE 1 : IF x IS lo THEN y IS off;
+    RULE 2 : IF x IS hi THEN y IS on;
+END_RULEBLOCK
+END_FUNCTION_BLOCK
+`
+
+// fclVariant zwraca baseFCL z zamienionymi fragmentami (pary: stary, nowy)
+func fclVariant(t *testing.T, replacements ...string) string {
+	t.Helper()
+	src := baseFCL
+	for i := 0; i < len(replacements); i += 2 {
+		if !strings.Contains(src, replacements[i]) {
+			t.Fatalf("baseFCL does not contain %q", replacements[i])
+		}
+		src = strings.Replace(src, replacements[i], replacements[i+1], 1)
+	}
+	return src
+}
+
+func TestParseFCL(t *testing.T) {
+	// środek ciężkości liczony jest na siatce próbek, stąd tolerancja 0.01
+	tests := []struct {
+		name     string
+		src      []string // zamiany w baseFCL
+		x        float64
+		expected float64
+	}{
+		{"base low", nil, 0, 1.0 / 3},
+		{"base high", nil, 10, 2.0 / 3},
+		{"keywords in any case and comments", []string{
+			"FUZZIFY x", "fuzzify x (* komentarz *)",
+			"METHOD : COG;", "method : cog; // komentarz",
+		}, 10, 2.0 / 3},
+		{"clamped input", []string{"(0 .. 10);", "(0 .. 10) clamp;"}, 25, 2.0 / 3},
+		{"points", []string{"TERM hi := trian 0 10 10;", "TERM hi := (0, 0) (10, 1);"}, 10, 2.0 / 3},
+		{"full weight", []string{"THEN y IS on;", "THEN y IS on WITH 1;"}, 10, 2.0 / 3},
+		{"rule disabled with weight 0", []string{
+			"THEN y IS on;", "THEN y IS on WITH 0;",
+			"METHOD : COG;", "METHOD : COG;\n    DEFAULT := 0.25;",
+		}, 10, 0.25},
+		{"Sugeno", []string{
+			"TERM off := trian 0 0 1;", "TERM off := 0;",
+			"TERM on := trian 0 1 1;", "TERM on := 0.5 + 0.05 * x;",
+			"METHOD : COG;", "METHOD : COGS;",
+		}, 10, 1},
+		{"Sugeno with both rules firing", []string{
+			"TERM off := trian 0 0 1;", "TERM off := 0;",
+			"TERM on := trian 0 1 1;", "TERM on := 0.5 + 0.05 * x;",
+			"METHOD : COG;", "METHOD : COGS;",
+		}, 5, 0.375},
+	}
+	for _, test := range tests {
+		s, err := ParseFCL(strings.NewReader(fclVariant(t, test.src...)))
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		result, _, err := s.Evaluate(map[string]float64{"x": test.x})
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		if math.Abs(result["y"]-test.expected) > 0.01 {
+			t.Errorf("%s: expected y = %.4f for x = %g, found %.4f", test.name, test.expected, test.x, result["y"])
+		}
+	}
+}
+
+func TestParseFCLErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		src  []string // zamiany w baseFCL
+		line int
+		msg  string
+	}{
+		{"unexpected character", []string{"x : REAL;", "x : REAL; @"}, 3, "unexpected character"},
+		{"unterminated comment", []string{"VAR_OUTPUT", "(* VAR_OUTPUT"}, 5, "unterminated comment"},
+		{"no function block", []string{"FUNCTION_BLOCK t", "BLOCK t"}, 1, "expected FUNCTION_BLOCK"},
+		{"unknown section", []string{"RULEBLOCK rules", "RULES rules"}, 19, "expected a section"},
+		{"variable declared twice", []string{"    y : REAL;", "    x : REAL;"}, 6, `variable "x" already declared in line 3`},
+		{"non-REAL variable", []string{"x : REAL;", "x : INT;"}, 3, "only REAL variables"},
+		{"undeclared variable", []string{"FUZZIFY x", "FUZZIFY z"}, 8, `"z" not declared in VAR_INPUT`},
+		{"output in FUZZIFY", []string{"FUZZIFY x", "FUZZIFY y"}, 8, `"y" not declared in VAR_INPUT`},
+		{"second block", []string{"DEFUZZIFY y", "FUZZIFY x\n    TERM a := trian 0 1 2;\nEND_FUZZIFY\nDEFUZZIFY y"}, 13, "already has a FUZZIFY block"},
+		{"METHOD in FUZZIFY", []string{"    RANGE := (0 .. 10);", "    RANGE := (0 .. 10);\n    METHOD : COG;"}, 10, "unexpected \"METHOD\" in FUZZIFY block"},
+		{"empty range", []string{"(0 .. 10)", "(10 .. 0)"}, 9, "empty range"},
+		{"unknown range policy", []string{"(0 .. 10);", "(0 .. 10) WRAP;"}, 9, `unknown range policy "WRAP"`},
+		{"output range policy", []string{"(0 .. 1);", "(0 .. 1) CLAMP;"}, 14, `range policy of output "y"`},
+		{"term defined twice", []string{"TERM hi :=", "TERM lo :="}, 11, `term "lo" of variable "x" defined twice`},
+		{"constant input term", []string{"TERM lo := trian 0 0 10;", "TERM lo := 3;"}, 10, `term "lo" of input "x" needs a membership function`},
+		{"linear input term", []string{"TERM lo := trian 0 0 10;", "TERM lo := 1 + 0.5 * x;"}, 10, `term "lo" of input "x" needs a membership function`},
+		{"degree above 1", []string{"TERM hi := trian 0 10 10;", "TERM hi := (0, 0) (10, 2);"}, 11, "membership degree 2 out of range"},
+		{"unordered points", []string{"TERM hi := trian 0 10 10;", "TERM hi := (5, 0) (1, 1);"}, 11, "ordered by x"},
+		{"triangle order", []string{"trian 0 10 10", "trian 0 10 5"}, 11, "a <= b <= c"},
+		{"trapezoid order", []string{"trian 0 10 10", "trape 0 10 5 10"}, 11, "a <= b <= c <= d"},
+		{"gaussian width", []string{"trian 0 10 10", "gauss 5 0"}, 11, "gaussian width 0 must be positive"},
+		{"sigmoid slope", []string{"trian 0 10 10", "sigm 0 5"}, 11, "sigmoid slope must not be zero"},
+		{"unknown output function", []string{"TERM on := trian 0 1 1;", "TERM on := triangle 0 1 1;"}, 16, `unknown membership function "triangle"`},
+		{"parameter outside range", []string{"trian 0 10 10", "trian 0 10 20"}, 11, "parameter 20 out of range [0, 10]"},
+		{"no range", []string{"    RANGE := (0 .. 10);\n", ""}, 8, `variable "x" has no RANGE`},
+		{"no terms", []string{"    TERM off := trian 0 0 1;\n    TERM on := trian 0 1 1;\n", "", "THEN y IS off", "THEN y IS x", "THEN y IS on", "THEN y IS x"}, 13, `variable "y" has no terms`},
+		{"unknown method", []string{"METHOD : COG;", "METHOD : MEAN;"}, 17, "unknown defuzzification method"},
+		{"default not a number", []string{"METHOD : COG;", "METHOD : COG;\n    DEFAULT := high;"}, 18, "expected a number"},
+		{"unsupported ACCU", []string{"METHOD : COG;", "METHOD : COG;\n    ACCU : SUM;"}, 18, "ACCU : SUM is not supported"},
+		{"unknown AND", []string{"RULEBLOCK rules", "RULEBLOCK rules\n    AND : MAX;"}, 20, `unknown AND operator "MAX"`},
+		{"undefined input in rule", []string{"IF x IS lo", "IF z IS lo"}, 20, `undefined input variable "z"`},
+		{"undefined term in rule", []string{"IF x IS lo", "IF x IS mid"}, 20, `undefined term "mid" of input variable "x"`},
+		{"NOT in conclusion", []string{"THEN y IS off", "THEN y IS NOT off"}, 20, "NOT is not allowed in a conclusion"},
+		{"weight above 1", []string{"THEN y IS on;", "THEN y IS on WITH 2;"}, 21, "rule weight 2 out of range"},
+		{"negative weight", []string{"THEN y IS on;", "THEN y IS on WITH -0.5;"}, 21, "rule weight -0.5 out of range"},
+		{"no FUZZIFY block", []string{"    x : REAL;", "    x : REAL;\n    z : REAL;"}, 4, `variable "z" is declared but has no FUZZIFY/DEFUZZIFY block`},
+		{"no rules", []string{"    RULE 1 : IF x IS lo THEN y IS off;\n    RULE 2 : IF x IS hi THEN y IS on;\n", ""}, 21, "has no rules"},
+		{"singleton without COGS", []string{"TERM off := trian 0 0 1;", "TERM off := 0;"}, 17, "singleton output terms need METHOD : COGS"},
+		{"undefined input in linear term", []string{"TERM on := trian 0 1 1;", "TERM on := 0.5 * z;"}, 16, `undefined input variable "z" in linear term`},
+	}
+	for _, test := range tests {
+		_, err := ParseFCL(strings.NewReader(fclVariant(t, test.src...)))
+		var fclErr *FCLError
+		if !errors.As(err, &fclErr) {
+			t.Errorf("%s: expected an FCLError, found %v", test.name, err)
+			continue
+		}
+		if fclErr.Line != test.line || !strings.Contains(fclErr.Msg, test.msg) {
+			t.Errorf("%s: expected line %d: ...%s..., found %v", test.name, test.line, test.msg, err)
+		}
+	}
+}
+
+func TestWriteFCLRoundTrip(t *testing.T) {
+	src := fclVariant(t, "THEN y IS on;", "THEN y IS on WITH 0;", "THEN y IS off;", "THEN y IS off WITH 0.5;", "(0 .. 10);", "(0 .. 10) CLAMP;")
+	s, err := ParseFCL(strings.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b strings.Builder
+	if err := WriteFCL(&b, s); err != nil {
+		t.Fatal(err)
+	}
+	again, err := ParseFCL(strings.NewReader(b.String()))
+	if err != nil {
+		t.Fatalf("%v in\n%s", err, b.String())
+	}
+	for i, rule := range again.Rules {
+		if rule.Weight != s.Rules[i].Weight {
+			t.Errorf("rule %d: expected weight %g after writing, found %g", i+1, s.Rules[i].Weight, rule.Weight)
+		}
+	}
+	if again.Inputs[0].Policy != Clamp {
+		t.Errorf("expected input %q to stay clamped after writing", again.Inputs[0].Name)
+	}
+	if !strings.Contains(b.String(), "WITH 0;") {
+		t.Errorf("expected the disabled rule to keep WITH 0 in\n%s", b.String())
+	}
+}
+
+func TestLoadAttendanceFCL(t *testing.T) {
+	loaded, err := LoadFCL("attendance.fcl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	builtIn := AttendanceSystem()
+	for _, inputs := range []map[string]float64{
+		{"hours": 8, "importance": 2, "distance": 20},
+		{"hours": 2, "importance": 5, "distance": 80},
+		{"hours": 11, "importance": 0.5, "distance": 5},
+	} {
+		a, _, errA := loaded.Evaluate(inputs)
+		b, _, errB := builtIn.Evaluate(inputs)
+		if errA != nil || errB != nil {
+			t.Fatalf("%v: %v, %v", inputs, errA, errB)
+		}
+		if math.Abs(a["attendance"]-b["attendance"]) > 1e-9 {
+			t.Errorf("%v: expected the FCL system to match AttendanceSystem (%.4f), found %.4f", inputs, b["attendance"], a["attendance"])
+		}
+	}
+}
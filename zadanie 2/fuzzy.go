@@ -0,0 +1,680 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+)
+
+// Term to wartość lingwistyczna zmiennej, np. "daleko" dla dystansu.
+// Terminy wejść i wyjść systemu Mamdaniego opisuje funkcja przynależności MF,
+// terminy wyjść systemu Sugeno opisuje funkcja Sugeno
+type Term struct {
+	Name   string
+	MF     MembershipFunc
+	Sugeno *SugenoOutput
+}
+
+// SugenoOutput to wyjście reguły w modelu Sugeno: Constant + suma Coefficients[wejście] * wejście.
+// Bez współczynników jest to model zerowego rzędu (stała), ze współczynnikami pierwszego rzędu
+type SugenoOutput struct {
+	Constant     float64
+	Coefficients map[string]float64
+}
+
+// value oblicza wyjście Sugeno dla podanych wartości wejść
+func (o *SugenoOutput) value(inputs map[string]float64) float64 {
+	z := o.Constant
+	for name, c := range o.Coefficients {
+		z += c * inputs[name]
+	}
+	return z
+}
+
+// String zapisuje wyjście Sugeno jako wyrażenie liniowe w notacji FCL
+func (o *SugenoOutput) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%g", o.Constant)
+	names := make([]string, 0, len(o.Coefficients))
+	for name := range o.Coefficients {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		c := o.Coefficients[name]
+		if c < 0 {
+			fmt.Fprintf(&b, " - %g * %s", -c, name)
+		} else {
+			fmt.Fprintf(&b, " + %g * %s", c, name)
+		}
+	}
+	return b.String()
+}
+
+// Variable to zmienna lingwistyczna o dziedzinie [Min, Max] i zbiorze terminów.
+// Policy decyduje o wartościach wejścia spoza dziedziny, Default to wartość
+// wyjścia, gdy żadna reguła go nie odpaliła (nil oznacza błąd)
+type Variable struct {
+	Name     string
+	Min, Max float64
+	Terms    []Term
+	Policy   RangePolicy
+	Default  *float64
+}
+
+// Term zwraca termin o podanej nazwie
+func (v *Variable) Term(name string) (*Term, bool) {
+	for i := range v.Terms {
+		if v.Terms[i].Name == name {
+			return &v.Terms[i], true
+		}
+	}
+	return nil, false
+}
+
+// Fuzzify zwraca stopnie przynależności x do wszystkich terminów zmiennej
+func (v *Variable) Fuzzify(x float64) map[string]float64 {
+	degrees := make(map[string]float64, len(v.Terms))
+	for _, term := range v.Terms {
+		if term.MF != nil {
+			degrees[term.Name] = term.MF.Degree(x)
+		}
+	}
+	return degrees
+}
+
+// Operators to normy używane do łączenia przesłanek reguł
+type Operators struct {
+	And func(a, b float64) float64
+	Or  func(a, b float64) float64
+}
+
+// Standardowe t-normy i s-normy
+var (
+	MinNorm    = math.Min
+	ProductAnd = func(a, b float64) float64 { return a * b }
+	MaxNorm    = math.Max
+	ProbOr     = func(a, b float64) float64 { return a + b - a*b }
+)
+
+// Expr to wyrażenie w przesłance reguły (część IF)
+type Expr interface {
+	eval(degrees map[string]map[string]float64, ops Operators) float64
+	String() string
+}
+
+// Is to warunek "zmienna IS termin"
+type Is struct {
+	Variable, Term string
+}
+
+func (e Is) eval(degrees map[string]map[string]float64, ops Operators) float64 {
+	return degrees[e.Variable][e.Term]
+}
+
+func (e Is) String() string {
+	return e.Variable + " IS " + e.Term
+}
+
+// And to koniunkcja warunków
+type And []Expr
+
+func (e And) eval(degrees map[string]map[string]float64, ops Operators) float64 {
+	result := 1.0
+	for i, x := range e {
+		if i == 0 {
+			result = x.eval(degrees, ops)
+		} else {
+			result = ops.And(result, x.eval(degrees, ops))
+		}
+	}
+	return result
+}
+
+func (e And) String() string {
+	return joinExprs(e, " AND ")
+}
+
+// Or to alternatywa warunków
+type Or []Expr
+
+func (e Or) eval(degrees map[string]map[string]float64, ops Operators) float64 {
+	result := 0.0
+	for _, x := range e {
+		result = ops.Or(result, x.eval(degrees, ops))
+	}
+	return result
+}
+
+func (e Or) String() string {
+	return joinExprs(e, " OR ")
+}
+
+// Not to negacja warunku (dopełnienie 1 - x)
+type Not struct {
+	X Expr
+}
+
+func (e Not) eval(degrees map[string]map[string]float64, ops Operators) float64 {
+	return 1 - e.X.eval(degrees, ops)
+}
+
+func (e Not) String() string {
+	return "NOT " + parenthesize(e.X)
+}
+
+// joinExprs łączy wyrażenia operatorem, biorąc złożone wyrażenia w nawiasy
+func joinExprs(exprs []Expr, op string) string {
+	parts := make([]string, len(exprs))
+	for i, x := range exprs {
+		parts[i] = parenthesize(x)
+	}
+	return strings.Join(parts, op)
+}
+
+// parenthesize bierze w nawiasy wszystko poza pojedynczym warunkiem
+func parenthesize(x Expr) string {
+	if _, ok := x.(Is); ok {
+		return x.String()
+	}
+	return "(" + x.String() + ")"
+}
+
+// Rule to reguła IF-THEN; Weight z przedziału [0, 1] skaluje siłę odpalenia,
+// więc pełna reguła ma wagę 1, a reguła z wagą 0 nigdy nie odpala
+type Rule struct {
+	If     Expr
+	Then   []Is
+	Weight float64
+}
+
+// String zapisuje regułę w postaci "IF ... THEN ..."
+func (r Rule) String() string {
+	then := make([]string, len(r.Then))
+	for i, c := range r.Then {
+		then[i] = c.String()
+	}
+	s := "IF " + r.If.String() + " THEN " + strings.Join(then, ", ")
+	if r.Weight != 1 {
+		s += fmt.Sprintf(" WITH %g", r.Weight)
+	}
+	return s
+}
+
+// InferenceType to rodzaj wnioskowania
+type InferenceType int
+
+const (
+	// Mamdani łączy zbiory wyjściowe reguł i wyostrza wynik
+	Mamdani InferenceType = iota
+	// Sugeno liczy średnią ważoną wyjść reguł
+	Sugeno
+)
+
+// Defuzzifier to metoda wyostrzania zbioru wyjściowego w modelu Mamdaniego
+type Defuzzifier int
+
+const (
+	// Centroid to środek ciężkości
+	Centroid Defuzzifier = iota
+	// Bisector dzieli pole pod zbiorem na dwie równe części
+	Bisector
+	// MOM to średnia z punktów o największej przynależności
+	MOM
+	// SOM to najmniejszy z punktów o największej przynależności
+	SOM
+	// LOM to największy z punktów o największej przynależności
+	LOM
+)
+
+// System to rozmyty system wnioskujący z wejściami, wyjściami i bazą reguł
+type System struct {
+	Name       string
+	Inputs     []*Variable
+	Outputs    []*Variable
+	Rules      []Rule
+	Type       InferenceType
+	Defuzzify  Defuzzifier
+	Operators  Operators
+	Resolution int // liczba punktów dyskretyzacji wyjścia Mamdaniego, domyślnie 201
+}
+
+// NewSystem tworzy pusty system z operatorami min/max
+func NewSystem(name string, kind InferenceType) *System {
+	return &System{Name: name, Type: kind, Operators: Operators{And: MinNorm, Or: MaxNorm}, Resolution: 201}
+}
+
+// Input zwraca wejście o podanej nazwie
+func (s *System) Input(name string) (*Variable, bool) {
+	return findVariable(s.Inputs, name)
+}
+
+// Output zwraca wyjście o podanej nazwie
+func (s *System) Output(name string) (*Variable, bool) {
+	return findVariable(s.Outputs, name)
+}
+
+func findVariable(vars []*Variable, name string) (*Variable, bool) {
+	for _, v := range vars {
+		if v.Name == name {
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+// Validate sprawdza, czy reguły odwołują się tylko do istniejących zmiennych
+// i terminów, czy terminy wyjść pasują do rodzaju wnioskowania i czy zbiory
+// typu 2 mają poprawne ślady niepewności
+func (s *System) Validate() error {
+	if err := s.checkType2(); err != nil {
+		return err
+	}
+	for _, v := range s.Outputs {
+		for _, term := range v.Terms {
+			if s.Type == Mamdani && term.MF == nil {
+				return fmt.Errorf("output %q: term %q needs a membership function", v.Name, term.Name)
+			}
+			if s.Type == Sugeno && term.Sugeno == nil {
+				return fmt.Errorf("output %q: term %q needs a Sugeno function", v.Name, term.Name)
+			}
+		}
+	}
+	for i, rule := range s.Rules {
+		if !(rule.Weight >= 0 && rule.Weight <= 1) {
+			return fmt.Errorf("rule %d: weight %g out of range [0, 1]", i+1, rule.Weight)
+		}
+		if err := s.checkExpr(rule.If); err != nil {
+			return fmt.Errorf("rule %d: %w", i+1, err)
+		}
+		for _, c := range rule.Then {
+			v, ok := s.Output(c.Variable)
+			if !ok {
+				return fmt.Errorf("rule %d: unknown output variable %q", i+1, c.Variable)
+			}
+			if _, ok := v.Term(c.Term); !ok {
+				return fmt.Errorf("rule %d: output %q has no term %q", i+1, c.Variable, c.Term)
+			}
+		}
+	}
+	return nil
+}
+
+// checkExpr sprawdza zmienne i terminy użyte w przesłance
+func (s *System) checkExpr(x Expr) error {
+	switch e := x.(type) {
+	case Is:
+		v, ok := s.Input(e.Variable)
+		if !ok {
+			return fmt.Errorf("unknown input variable %q", e.Variable)
+		}
+		if _, ok := v.Term(e.Term); !ok {
+			return fmt.Errorf("input %q has no term %q", e.Variable, e.Term)
+		}
+	case And:
+		for _, y := range e {
+			if err := s.checkExpr(y); err != nil {
+				return err
+			}
+		}
+	case Or:
+		for _, y := range e {
+			if err := s.checkExpr(y); err != nil {
+				return err
+			}
+		}
+	case Not:
+		return s.checkExpr(e.X)
+	}
+	return nil
+}
+
+// Błędy zwracane przez Evaluate; konkretne przypadki opakowują je
+// i można je rozpoznać przez errors.Is
+var (
+	// ErrOutOfRange oznacza wartość wejścia spoza dziedziny odrzuconą zgodnie z polityką Reject
+	ErrOutOfRange = errors.New("value out of range")
+	// ErrMissingInput oznacza brak wartości któregoś z wejść
+	ErrMissingInput = errors.New("missing input")
+	// ErrNoRuleFired oznacza, że żadna reguła nie dotyczyła wyjścia w stopniu większym od zera
+	// (a wyjście nie ma wartości domyślnej)
+	ErrNoRuleFired = errors.New("no rule fired")
+)
+
+// RangeError opisuje wartość wejścia spoza jego dziedziny
+type RangeError struct {
+	Variable string
+	Value    float64
+	Min, Max float64
+}
+
+func (e *RangeError) Error() string {
+	return fmt.Sprintf("value %g of input %q out of range [%g, %g]", e.Value, e.Variable, e.Min, e.Max)
+}
+
+// Unwrap pozwala rozpoznać błąd przez errors.Is(err, ErrOutOfRange)
+func (e *RangeError) Unwrap() error {
+	return ErrOutOfRange
+}
+
+// RangePolicy określa, co zrobić z wartością wejścia spoza dziedziny
+type RangePolicy int
+
+const (
+	// Reject zwraca *RangeError (domyślnie)
+	Reject RangePolicy = iota
+	// Clamp przycina wartość do najbliższego końca dziedziny, jak czujnik o ograniczonym zakresie
+	Clamp
+)
+
+// Result to wartości ostre wyjść systemu według nazw
+type Result map[string]float64
+
+// inference przechowuje wyniki pośrednie jednego wnioskowania
+type inference struct {
+	inputs     map[string]float64            // wartości wejść po zastosowaniu polityki dziedziny
+	clamped    []string                      // wejścia przycięte do dziedziny
+	degrees    map[string]map[string]float64 // przynależność wejść do terminów
+	strengths  []float64                     // siła odpalenia każdej reguły
+	aggregated map[string][]float64          // zagregowany zbiór wyjścia Mamdaniego (w typie 2 górna funkcja)
+	outputs    map[string]float64            // wartości ostre wyjść
+
+	// tylko systemy typu 2
+	bounds          map[string]map[string]Interval // przedziały przynależności wejść do terminów
+	firing          []Interval                     // przedziały sił odpalenia reguł
+	lowerAggregated map[string][]float64           // dolna funkcja zagregowanego zbioru Mamdaniego
+	intervals       map[string]Interval            // przedziały wyjść po redukcji typu
+}
+
+// RuleFiring to siła odpalenia reguły w jednym wnioskowaniu. W systemie typu 2
+// siła jest przedziałem Firing, a Strength jego środkiem
+type RuleFiring struct {
+	Rule     Rule
+	Strength float64
+	Firing   Interval
+}
+
+// OutputSet to zagregowany zbiór wyjściowy Mamdaniego: stopnie Mu w punktach X.
+// W systemie typu 2 Mu to górna, a Lower dolna funkcja przynależności
+type OutputSet struct {
+	X, Mu, Lower []float64
+}
+
+// Explanation opisuje przebieg jednego wnioskowania: użyte wartości wejść
+// (po przycięciu), rozmyte wejścia, siły odpalenia reguł, zagregowane zbiory
+// wyjść (tylko Mamdani) i wartości ostre. Systemy typu 2 podają dodatkowo
+// przedziały przynależności wejść (Bounds) i przedziały wyjść po redukcji
+// typu (Intervals), których środkami są wartości ostre
+type Explanation struct {
+	Inputs     map[string]float64
+	Clamped    []string
+	Degrees    map[string]map[string]float64
+	Bounds     map[string]map[string]Interval
+	Rules      []RuleFiring
+	Aggregated map[string]OutputSet
+	Outputs    Result
+	Intervals  map[string]Interval
+}
+
+// Evaluate oblicza wartości ostre wszystkich wyjść dla podanych wejść i zwraca
+// przebieg wnioskowania. Błędy opakowują ErrMissingInput, ErrOutOfRange
+// (jako *RangeError) albo ErrNoRuleFired. System nie jest modyfikowany, więc
+// Evaluate można wołać współbieżnie
+func (s *System) Evaluate(inputs map[string]float64) (Result, *Explanation, error) {
+	inf, err := s.infer(inputs)
+	if err != nil {
+		return nil, nil, err
+	}
+	e := &Explanation{
+		Inputs:     inf.inputs,
+		Clamped:    inf.clamped,
+		Degrees:    inf.degrees,
+		Bounds:     inf.bounds,
+		Rules:      make([]RuleFiring, len(s.Rules)),
+		Aggregated: make(map[string]OutputSet, len(inf.aggregated)),
+		Outputs:    inf.outputs,
+		Intervals:  inf.intervals,
+	}
+	for i, rule := range s.Rules {
+		firing := Interval{inf.strengths[i], inf.strengths[i]}
+		if inf.firing != nil {
+			firing = inf.firing[i]
+		}
+		e.Rules[i] = RuleFiring{Rule: rule, Strength: inf.strengths[i], Firing: firing}
+	}
+	for _, out := range s.Outputs {
+		if mu, ok := inf.aggregated[out.Name]; ok {
+			e.Aggregated[out.Name] = OutputSet{X: s.samples(out), Mu: mu, Lower: inf.lowerAggregated[out.Name]}
+		}
+	}
+	return inf.outputs, e, nil
+}
+
+// Print wypisuje przebieg wnioskowania w postaci tekstowej
+func (e *Explanation) Print(w io.Writer, s *System) {
+	for _, v := range s.Inputs {
+		fmt.Fprintf(w, "%s = %g:", v.Name, e.Inputs[v.Name])
+		for _, term := range v.Terms {
+			if b, ok := e.Bounds[v.Name][term.Name]; ok && b.Lower != b.Upper {
+				fmt.Fprintf(w, " %s [%.2f, %.2f]", term.Name, b.Lower, b.Upper)
+			} else {
+				fmt.Fprintf(w, " %s %.2f", term.Name, e.Degrees[v.Name][term.Name])
+			}
+		}
+		fmt.Fprintln(w)
+	}
+	if len(e.Clamped) > 0 {
+		fmt.Fprintf(w, "clamped to range: %s\n", strings.Join(e.Clamped, ", "))
+	}
+	for i, r := range e.Rules {
+		if e.Intervals != nil {
+			fmt.Fprintf(w, "rule %d: [%.3f, %.3f]  %s\n", i+1, r.Firing.Lower, r.Firing.Upper, r.Rule)
+		} else {
+			fmt.Fprintf(w, "rule %d: %.3f  %s\n", i+1, r.Strength, r.Rule)
+		}
+	}
+	for _, v := range s.Outputs {
+		fmt.Fprintf(w, "%s = %.3f", v.Name, e.Outputs[v.Name])
+		if iv, ok := e.Intervals[v.Name]; ok {
+			fmt.Fprintf(w, " [%.3f, %.3f]", iv.Lower, iv.Upper)
+		}
+		fmt.Fprintln(w)
+	}
+}
+
+// OutputDegrees zwraca dla każdego wyjścia przynależność jego wartości ostrej
+// do terminów. Termin Sugeno nie ma funkcji przynależności, więc jego stopniem
+// jest największa siła odpalenia reguły, która go konkluduje
+func (e *Explanation) OutputDegrees(s *System) map[string]map[string]float64 {
+	degrees := make(map[string]map[string]float64, len(s.Outputs))
+	for _, v := range s.Outputs {
+		terms := make(map[string]float64, len(v.Terms))
+		for _, term := range v.Terms {
+			if term.MF != nil {
+				terms[term.Name] = term.MF.Degree(e.Outputs[v.Name])
+				continue
+			}
+			for _, r := range e.Rules {
+				for _, c := range r.Rule.Then {
+					if c.Variable == v.Name && c.Term == term.Name {
+						terms[term.Name] = math.Max(terms[term.Name], r.Strength)
+					}
+				}
+			}
+		}
+		degrees[v.Name] = terms
+	}
+	return degrees
+}
+
+// input zwraca wartość wejścia v po zastosowaniu jego polityki dziedziny
+// i informację, czy została przycięta
+func (v *Variable) input(x float64) (float64, bool, error) {
+	switch {
+	case math.IsNaN(x):
+		return 0, false, &RangeError{Variable: v.Name, Value: x, Min: v.Min, Max: v.Max}
+	case x >= v.Min && x <= v.Max:
+		return x, false, nil
+	case v.Policy == Clamp:
+		return math.Max(v.Min, math.Min(v.Max, x)), true, nil
+	}
+	return 0, false, &RangeError{Variable: v.Name, Value: x, Min: v.Min, Max: v.Max}
+}
+
+// infer przeprowadza rozmywanie, wnioskowanie, agregację i wyostrzanie
+func (s *System) infer(inputs map[string]float64) (*inference, error) {
+	inf := &inference{
+		inputs:     make(map[string]float64, len(s.Inputs)),
+		degrees:    make(map[string]map[string]float64),
+		aggregated: make(map[string][]float64),
+		outputs:    make(map[string]float64),
+	}
+	for _, v := range s.Inputs {
+		x, ok := inputs[v.Name]
+		if !ok {
+			return nil, fmt.Errorf("%w %q", ErrMissingInput, v.Name)
+		}
+		x, clamped, err := v.input(x)
+		if err != nil {
+			return nil, err
+		}
+		if clamped {
+			inf.clamped = append(inf.clamped, v.Name)
+		}
+		inf.inputs[v.Name] = x
+		inf.degrees[v.Name] = v.Fuzzify(x)
+	}
+	if s.IsType2() {
+		if err := s.inferType2(inf); err != nil {
+			return nil, err
+		}
+		return inf, nil
+	}
+
+	inf.strengths = make([]float64, len(s.Rules))
+	for i, rule := range s.Rules {
+		inf.strengths[i] = rule.If.eval(inf.degrees, s.Operators) * rule.Weight
+	}
+
+	for _, out := range s.Outputs {
+		var value float64
+		var err error
+		if s.Type == Sugeno {
+			value, err = s.sugenoOutput(out, inf.strengths, inf.inputs)
+		} else {
+			inf.aggregated[out.Name] = s.aggregate(out, inf.strengths)
+			value, err = s.defuzzify(out, inf.aggregated[out.Name])
+		}
+		if errors.Is(err, ErrNoRuleFired) && out.Default != nil {
+			value, err = *out.Default, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		inf.outputs[out.Name] = value
+	}
+	return inf, nil
+}
+
+// samples zwraca punkty dyskretyzacji dziedziny wyjścia
+func (s *System) samples(out *Variable) []float64 {
+	n := s.Resolution
+	if n < 2 {
+		n = 201
+	}
+	xs := make([]float64, n)
+	for i := range xs {
+		xs[i] = out.Min + (out.Max-out.Min)*float64(i)/float64(n-1)
+	}
+	return xs
+}
+
+// aggregate buduje zbiór wyjściowy Mamdaniego: każdy termin konkluzji jest
+// obcinany do siły odpalenia reguły (implikacja min), a wyniki łączone przez max
+func (s *System) aggregate(out *Variable, strengths []float64) []float64 {
+	xs := s.samples(out)
+	mu := make([]float64, len(xs))
+	for i, rule := range s.Rules {
+		for _, c := range rule.Then {
+			if c.Variable != out.Name || strengths[i] == 0 {
+				continue
+			}
+			term, _ := out.Term(c.Term)
+			for j, x := range xs {
+				mu[j] = math.Max(mu[j], math.Min(strengths[i], term.MF.Degree(x)))
+			}
+		}
+	}
+	return mu
+}
+
+// defuzzify wyostrza zagregowany zbiór wybraną metodą
+func (s *System) defuzzify(out *Variable, mu []float64) (float64, error) {
+	xs := s.samples(out)
+	area, maxMu := 0.0, 0.0
+	for _, m := range mu {
+		area += m
+		maxMu = math.Max(maxMu, m)
+	}
+	if area == 0 {
+		return 0, fmt.Errorf("%w for output %q", ErrNoRuleFired, out.Name)
+	}
+	switch s.Defuzzify {
+	case Bisector:
+		half, sum := area/2, 0.0
+		for j, m := range mu {
+			sum += m
+			if sum >= half {
+				return xs[j], nil
+			}
+		}
+		return xs[len(xs)-1], nil
+	case MOM, SOM, LOM:
+		var maxima []float64
+		for j, m := range mu {
+			if maxMu-m < 1e-9 {
+				maxima = append(maxima, xs[j])
+			}
+		}
+		sort.Float64s(maxima)
+		switch s.Defuzzify {
+		case SOM:
+			return maxima[0], nil
+		case LOM:
+			return maxima[len(maxima)-1], nil
+		}
+		sum := 0.0
+		for _, x := range maxima {
+			sum += x
+		}
+		return sum / float64(len(maxima)), nil
+	default:
+		moment := 0.0
+		for j, m := range mu {
+			moment += xs[j] * m
+		}
+		return moment / area, nil
+	}
+}
+
+// sugenoOutput liczy średnią wyjść reguł ważoną siłami odpalenia
+func (s *System) sugenoOutput(out *Variable, strengths []float64, inputs map[string]float64) (float64, error) {
+	num, den := 0.0, 0.0
+	for i, rule := range s.Rules {
+		for _, c := range rule.Then {
+			if c.Variable != out.Name {
+				continue
+			}
+			term, _ := out.Term(c.Term)
+			num += strengths[i] * term.Sugeno.value(inputs)
+			den += strengths[i]
+		}
+	}
+	if den == 0 {
+		return 0, fmt.Errorf("%w for output %q", ErrNoRuleFired, out.Name)
+	}
+	return num / den, nil
+}
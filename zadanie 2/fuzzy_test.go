@@ -0,0 +1,72 @@
+package main
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"testing"
+)
+
+// rangeSystem to system z baseFCL; wejście x ma politykę policy, a wyjście y wartość domyślną def
+func rangeSystem(t *testing.T, policy RangePolicy, def *float64) *System {
+	t.Helper()
+	s, err := ParseFCL(strings.NewReader(baseFCL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Inputs[0].Policy = policy
+	s.Outputs[0].Default = def
+	return s
+}
+
+func TestEvaluateRangePolicies(t *testing.T) {
+	reject := rangeSystem(t, Reject, nil)
+	for _, x := range []float64{-1, 10.5, math.NaN(), math.Inf(1)} {
+		_, _, err := reject.Evaluate(map[string]float64{"x": x})
+		var rangeErr *RangeError
+		if !errors.As(err, &rangeErr) || !errors.Is(err, ErrOutOfRange) {
+			t.Errorf("Reject, x = %g: expected a *RangeError wrapping ErrOutOfRange, found %v", x, err)
+			continue
+		}
+		if rangeErr.Variable != "x" || rangeErr.Min != 0 || rangeErr.Max != 10 {
+			t.Errorf("Reject, x = %g: expected the range [0, 10] of x, found %+v", x, rangeErr)
+		}
+	}
+
+	clamp := rangeSystem(t, Clamp, nil)
+	for x, edge := range map[float64]float64{-5: 0, 25: 10} {
+		clamped, _, err := clamp.Evaluate(map[string]float64{"x": x})
+		if err != nil {
+			t.Fatalf("Clamp, x = %g: %v", x, err)
+		}
+		atEdge, _, err := clamp.Evaluate(map[string]float64{"x": edge})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if clamped["y"] != atEdge["y"] {
+			t.Errorf("Clamp, x = %g: expected y = %.4f as for x = %g, found %.4f", x, atEdge["y"], edge, clamped["y"])
+		}
+	}
+	if _, _, err := clamp.Evaluate(map[string]float64{"x": math.NaN()}); !errors.Is(err, ErrOutOfRange) {
+		t.Errorf("Clamp, x = NaN: expected ErrOutOfRange, found %v", err)
+	}
+}
+
+func TestEvaluateMissingInputAndDefault(t *testing.T) {
+	s := rangeSystem(t, Reject, nil)
+	if _, _, err := s.Evaluate(map[string]float64{"z": 1}); !errors.Is(err, ErrMissingInput) {
+		t.Errorf("expected ErrMissingInput, found %v", err)
+	}
+
+	// z wyłączoną regułą 2 dla x = 10 nie odpala żadna reguła
+	s.Rules[1].Weight = 0
+	if _, _, err := s.Evaluate(map[string]float64{"x": 10}); !errors.Is(err, ErrNoRuleFired) {
+		t.Errorf("expected ErrNoRuleFired without a default, found %v", err)
+	}
+	def := 0.25
+	s.Outputs[0].Default = &def
+	result, _, err := s.Evaluate(map[string]float64{"x": 10})
+	if err != nil || result["y"] != def {
+		t.Errorf("expected the default y = %g, found %v, %v", def, result, err)
+	}
+}
@@ -0,0 +1,13 @@
+module zad2
+
+go 1.22.2
+
+require gonum.org/v1/plot v0.0.0-20190515093506-e2840ee46a6b
+
+require (
+	github.com/ajstarks/svgo v0.0.0-20180226025133-644b8db467af // indirect
+	github.com/fogleman/gg v1.3.0 // indirect
+	github.com/golang/freetype v0.0.0-20170609003504-e2365dfdc4a0 // indirect
+	github.com/jung-kurt/gofpdf v1.0.3-0.20190309125859-24315acbbda5 // indirect
+	golang.org/x/image v0.0.0-20190802002840-cff245a6509b // indirect
+)
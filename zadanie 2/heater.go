@@ -0,0 +1,133 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"time"
+)
+
+// HeaterSystem buduje rozmyty regulator ogrzewania pomieszczenia.
+// Wejścia:
+// - error: różnica temperatury zadanej i zmierzonej w °C (-10 - 10), terminy too_hot, ok, cold, very_cold
+// - rate: szybkość zmian temperatury w °C/min (-2 - 2), terminy falling, steady, rising
+// Wyjście power (0 - 100) to moc grzałki w procentach, terminy off, low, medium, high.
+// Wejścia spoza dziedzin są przycinane, jak odczyty czujnika o ograniczonym zakresie
+func HeaterSystem() *System {
+	s := NewSystem("heater", Mamdani)
+	s.Inputs = []*Variable{
+		{Name: "error", Min: -10, Max: 10, Policy: Clamp, Terms: []Term{
+			{Name: "too_hot", MF: Trapezoidal{-10, -10, -2, 0}},
+			{Name: "ok", MF: Triangular{-1, 0, 1}},
+			{Name: "cold", MF: Triangular{0, 2, 5}},
+			{Name: "very_cold", MF: Trapezoidal{3, 6, 10, 10}},
+		}},
+		{Name: "rate", Min: -2, Max: 2, Policy: Clamp, Terms: []Term{
+			{Name: "falling", MF: Trapezoidal{-2, -2, -0.5, 0}},
+			{Name: "steady", MF: Triangular{-0.3, 0, 0.3}},
+			{Name: "rising", MF: Trapezoidal{0, 0.5, 2, 2}},
+		}},
+	}
+	s.Outputs = []*Variable{
+		{Name: "power", Min: 0, Max: 100, Terms: []Term{
+			{Name: "off", MF: Trapezoidal{0, 0, 5, 20}},
+			{Name: "low", MF: Triangular{10, 30, 50}},
+			{Name: "medium", MF: Triangular{35, 55, 75}},
+			{Name: "high", MF: Trapezoidal{60, 80, 100, 100}},
+		}},
+	}
+	then := func(term string) []Is { return []Is{{"power", term}} }
+	s.Rules = []Rule{
+		{If: Is{"error", "very_cold"}, Then: then("high"), Weight: 1},
+		{If: And{Is{"error", "cold"}, Is{"rate", "falling"}}, Then: then("high"), Weight: 1},
+		{If: And{Is{"error", "cold"}, Is{"rate", "steady"}}, Then: then("medium"), Weight: 1},
+		{If: And{Is{"error", "cold"}, Is{"rate", "rising"}}, Then: then("low"), Weight: 1},
+		{If: And{Is{"error", "ok"}, Is{"rate", "falling"}}, Then: then("medium"), Weight: 1},
+		{If: And{Is{"error", "ok"}, Is{"rate", "steady"}}, Then: then("low"), Weight: 1},
+		{If: And{Is{"error", "ok"}, Is{"rate", "rising"}}, Then: then("off"), Weight: 1},
+		{If: Is{"error", "too_hot"}, Then: then("off"), Weight: 1},
+	}
+	return s
+}
+
+// Heater to symulowane pomieszczenie z grzałką:
+// dT/dt = Gain * moc/100 - Loss * (T - Ambient), czas w minutach
+type Heater struct {
+	Temperature float64 // temperatura w pomieszczeniu, °C
+	Ambient     float64 // temperatura na zewnątrz, °C
+	Gain        float64 // przyrost temperatury przy pełnej mocy, °C/min
+	Loss        float64 // współczynnik strat ciepła, 1/min
+}
+
+// Step przesuwa symulację o dt minut przy danej mocy grzałki (0 - 100)
+func (h *Heater) Step(power, dt float64) {
+	h.Temperature += (h.Gain*power/100 - h.Loss*(h.Temperature-h.Ambient)) * dt
+}
+
+// HeaterRun opisuje symulację zamkniętej pętli regulacji
+type HeaterRun struct {
+	Setpoint float64       // temperatura zadana, °C
+	Minutes  float64       // długość symulacji w minutach
+	Step     float64       // krok symulacji w minutach
+	Every    float64       // co ile minut wypisywać stan
+	Pace     time.Duration // czas rzeczywisty na krok symulacji, 0 bez czekania
+}
+
+// clamp ogranicza x do przedziału [lo, hi]
+func clamp(x, lo, hi float64) float64 {
+	if x < lo {
+		return lo
+	}
+	if x > hi {
+		return hi
+	}
+	return x
+}
+
+// runHeater steruje symulowanym pomieszczeniem regulatorem ctrl i wypisuje przebieg.
+// W połowie symulacji na kwadrans otwiera się okno (temperatura zewnętrzna spada
+// o 10 °C), żeby pokazać reakcję regulatora na zakłócenie
+func runHeater(w io.Writer, ctrl *Controller, plant *Heater, run HeaterRun) error {
+	fmt.Fprintf(w, "%8s %8s %8s %8s %8s\n", "minute", "temp", "error", "rate", "power")
+	ambient := plant.Ambient
+	previous, rate := plant.Temperature, 0.0
+	start := time.Now()
+	steps := int(run.Minutes / run.Step)
+	every := int(run.Every / run.Step)
+	if every < 1 {
+		every = 1
+	}
+	for i := 0; i <= steps; i++ {
+		minute := float64(i) * run.Step
+		plant.Ambient = ambient
+		if minute >= run.Minutes/2 && minute < run.Minutes/2+15 {
+			plant.Ambient = ambient - 10
+		}
+		// szybkość zmian wygładzamy filtrem o stałej czasowej 1 minuty, żeby regulator
+		// nie reagował na pojedyncze kroki symulacji
+		alpha := math.Min(1, run.Step)
+		rate += alpha * ((plant.Temperature-previous)/run.Step - rate)
+		sample := Sample{
+			Time:     start.Add(time.Duration(minute * float64(time.Minute))),
+			Received: time.Now(),
+			Inputs: map[string]float64{
+				"error": run.Setpoint - plant.Temperature,
+				"rate":  rate,
+			},
+		}
+		out := ctrl.Step(sample, time.Now())
+		if out.Err != nil {
+			return out.Err
+		}
+		power := out.Outputs["power"]
+		if i%every == 0 {
+			fmt.Fprintf(w, "%8.1f %8.2f %8.2f %8.2f %8.1f\n", minute, plant.Temperature, sample.Inputs["error"], sample.Inputs["rate"], power)
+		}
+		previous = plant.Temperature
+		plant.Step(power, run.Step)
+		if run.Pace > 0 {
+			time.Sleep(run.Pace)
+		}
+	}
+	return nil
+}
@@ -14,73 +14,350 @@
 package main
 
 import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"flag"
 	"fmt"
-	"math"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
 )
 
-// Funkcja odpowiedzialna za rozmycie wartości godzin i przypisanie wartości z przedziału [0, 1]
-// fukncja sprawdza również czy podana wartość jest w prawidłowym zakresie
-func FuzzifyHours(hours float64) (float64, error) {
-	if hours <= 1 {
-		return 1.0, fmt.Errorf("too high value of hours, should be (0 - 12)")
-	} else if hours >= 12 {
-		return 0.0, fmt.Errorf("too low value of hours, should be (0 - 12)")
+// Funkcja oblicza ostateczny wynik
+// Przyjmuje wszytskie trzy zmienne i przekazuje je do systemu rozmytego z attendance.go,
+// który rozmywa je na terminy lingwistyczne, odpala reguły i wyostrza wynik metodą środka ciężkości
+// Dla wartości spoza dziedziny zmiennych zwracany jest *RangeError (errors.Is(err, ErrOutOfRange))
+func CalculateAttendanceScore(hours, importance, distance float64) (float64, error) {
+	outputs, _, err := AttendanceSystem().Evaluate(map[string]float64{
+		"hours":      hours,
+		"importance": importance,
+		"distance":   distance,
+	})
+	if err != nil {
+		return 0, err
 	}
-	return 1 - (hours-1)/11.0, nil
+	return outputs["attendance"], nil
 }
 
-// Funkcja odpowiedzialna za rozmycie wartości "ważności" i przypisanie wartości z przedziału [0, 1]
-// Sprawdzane jest też czy podana wartośc jest z określonego przedziału (0 -5)
-func FuzzifyImportance(importance float64) (float64, error) {
-	if importance <= 0 {
-		return 0.0, fmt.Errorf("too high value of importacne, should be (0 - 5)")
-	} else if importance > 5 {
-		return 1.0, fmt.Errorf("too high value of importacne, should be (0 - 5)")
+// parseInputs zamienia argumenty postaci nazwa=wartość na wartości wejść systemu
+func parseInputs(args []string) (map[string]float64, error) {
+	inputs := make(map[string]float64)
+	for _, arg := range args {
+		name, value, ok := strings.Cut(arg, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid input %q (want name=value)", arg)
+		}
+		x, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value of input %q: %v", name, err)
+		}
+		inputs[name] = x
 	}
-	return importance / 5.0, nil
+	return inputs, nil
 }
 
-// Funkcja działa na podobnej zasadzie co dwie poprzednie, tylko dotyczy dystansu
-func FuzzifyDistance(distance float64) (float64, error) {
-	if distance <= 0 {
-		return 1.0, fmt.Errorf("too high value of importacne, should be (0 - 5)")
-	} else if distance >= 100 {
-		return 0.0, fmt.Errorf("too high value of importacne, should be (0 - 5)")
-	}
-	return 1 - distance/100.0, nil
-}
-// Funkcja oblicza ostateczny wynik
-// Przyjmuje wszytskie trzy zmienne i rodziela po poprzednich fukncjach by otrzymać wartości rozmyte
-// Następnie wyznacza trzy różne kombinacje dla tych wartości i odpowiednio je skaluje
-// Wynikiem ostatecznym jest średnia z tych trzech kombinacji
-func CalculateAttendanceScore(hours, importance, distance float64) float64 {
-	fHours, err := FuzzifyHours(hours)
+// evaluateArgs wypisuje wyjścia systemu dla wejść podanych jako nazwa=wartość,
+// ich przynależność do terminów i reguły, które odpaliły
+func evaluateArgs(system *System, args []string) error {
+	inputs, err := parseInputs(args)
 	if err != nil {
-		fmt.Printf("%v\n", err)
-		return -1.0
+		return err
 	}
-	fImportance, err := FuzzifyImportance(importance)
-	if err != nil {
-		fmt.Printf("%v\n", err)
-		return -1.0
+	if err := checkInputs(system, inputs); err != nil {
+		return err
 	}
-	fDistance, err := FuzzifyDistance(distance)
+	_, e, err := system.Evaluate(inputs)
 	if err != nil {
-		fmt.Printf("%v\n", err)
-		return -1.0
+		return err
 	}
-	score1 := math.Min(math.Min(fHours, fImportance), fDistance) * 5.0
-	score2 := math.Min(fHours, fImportance) * 4.0
-	score3 := math.Min(fImportance, fDistance) * 3.0
-	totalScore := (score1 + score2 + score3) / 3.0
-	return totalScore
+	printEvaluation(os.Stdout, system, e)
+	return nil
 }
 
-func main() {
+// runOnce wypisuje wyjścia dla jednego zestawu wejść: wybranego systemu dla
+// argumentów nazwa=wartość albo przykładowy przypadek systemu obecności
+func runOnce(fclPath, name string, args []string) error {
+	if fclPath != "" || name != "" || len(args) > 0 {
+		system, err := loadSystem(fclPath, name, AttendanceSystem)
+		if err != nil {
+			return err
+		}
+		return evaluateArgs(system, args)
+	}
 	hours := 8.0
 	importance := 2.0
 	distance := 20.0
 
-	score := CalculateAttendanceScore(hours, importance, distance)
+	score, err := CalculateAttendanceScore(hours, importance, distance)
+	if err != nil {
+		return err
+	}
 	fmt.Printf("Attendance worthiness score: %.2f out of 5\n", score)
+
+	// Ten sam przypadek w wariancie Sugeno zerowego rzędu
+	outputs, _, err := AttendanceSugenoSystem().Evaluate(map[string]float64{
+		"hours": hours, "importance": importance, "distance": distance,
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Attendance worthiness score (Sugeno): %.2f out of 5\n", outputs["attendance"])
+	return nil
+}
+
+// loadSystem zwraca system z pliku FCL, wbudowany system o podanej nazwie albo,
+// gdy oba są puste, system domyślny
+func loadSystem(fclPath, name string, fallback func() *System) (*System, error) {
+	switch {
+	case fclPath != "" && name != "":
+		return nil, errors.New("-fcl and -system are mutually exclusive")
+	case fclPath != "":
+		return LoadFCL(fclPath)
+	case name != "":
+		return builtinSystem(name)
+	}
+	return fallback(), nil
+}
+
+// openInput otwiera plik wejściowy; "-" oznacza stdin
+func openInput(path string) (io.ReadCloser, error) {
+	if path == "-" {
+		return io.NopCloser(os.Stdin), nil
+	}
+	return os.Open(path)
+}
+
+// runBatch wylicza wyjścia dla każdego przypadku z pliku CSV i wypisuje je
+// jako CSV na stdout z dopisanymi kolumnami wyjść
+func runBatch(fclPath, name, input string) error {
+	system, err := loadSystem(fclPath, name, AttendanceSystem)
+	if err != nil {
+		return err
+	}
+	r, err := openInput(input)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	return evaluateBatch(system, r, os.Stdout)
+}
+
+// runServe udostępnia system przez HTTP do przerwania (Ctrl+C)
+func runServe(fclPath, name, addr string) error {
+	system, err := loadSystem(fclPath, name, AttendanceSystem)
+	if err != nil {
+		return err
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	srv := &http.Server{Addr: addr, Handler: newService(system)}
+	go func() {
+		<-ctx.Done()
+		shutdown, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		srv.Shutdown(shutdown)
+	}()
+	fmt.Fprintf(os.Stderr, "serving %s on %s\n", system.Name, addr)
+	if err := srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// runStream steruje na bieżąco: czyta próbki CSV z pliku lub stdin, co takt
+// wypisuje wyjścia jako CSV na stdout, a na koniec statystyki opóźnień na stderr
+func runStream(fclPath, name, input string, follow bool, tick time.Duration) error {
+	if tick <= 0 {
+		return fmt.Errorf("expected a positive -tick, found %v", tick)
+	}
+	system, err := loadSystem(fclPath, name, AttendanceSystem)
+	if err != nil {
+		return err
+	}
+	r, err := openInput(input)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	samples := make(chan Sample)
+	readErr := make(chan error, 1)
+	go func() { readErr <- readSamples(ctx, r, follow, samples) }()
+
+	out := csv.NewWriter(os.Stdout)
+	out.Write(controlCSVHeader(system))
+	ctrl := NewController(system, tick)
+	err = ctrl.Run(ctx, samples, func(o ControlOutput) { writeControlCSV(out, system, o) })
+	if err == nil {
+		err = <-readErr
+	}
+	ctrl.Stats.Print(os.Stderr)
+	if errors.Is(err, context.Canceled) {
+		return nil
+	}
+	return err
+}
+
+// plotInputs zwraca wejścia dla wykresów: przykładowy przypadek domyślnego
+// systemu albo środki dziedzin, nadpisane argumentami nazwa=wartość
+func plotInputs(system *System, custom bool, args []string) (map[string]float64, error) {
+	inputs := map[string]float64{"hours": 8, "importance": 2, "distance": 20}
+	if custom {
+		inputs = make(map[string]float64)
+		for _, v := range system.Inputs {
+			inputs[v.Name] = (v.Min + v.Max) / 2
+		}
+	}
+	given, err := parseInputs(args)
+	if err != nil {
+		return nil, err
+	}
+	for name, x := range given {
+		inputs[name] = x
+	}
+	return inputs, nil
+}
+
+// runPlot zapisuje wykresy systemu i wypisuje przebieg wnioskowania
+func runPlot(fclPath, name string, args []string, dir, format string) error {
+	system, err := loadSystem(fclPath, name, AttendanceSystem)
+	if err != nil {
+		return err
+	}
+	inputs, err := plotInputs(system, fclPath != "" || name != "", args)
+	if err != nil {
+		return err
+	}
+	files, err := plotSystem(system, inputs, dir, format)
+	for _, f := range files {
+		fmt.Println("saved", f)
+	}
+	if err != nil {
+		return err
+	}
+	_, e, err := system.Evaluate(inputs)
+	if err != nil {
+		return err
+	}
+	e.Print(os.Stdout, system)
+	return nil
+}
+
+// runTrain uczy ANFIS na danych z pliku CSV, zapisuje nauczony system w FCL
+// oraz wykres krzywych błędu
+func runTrain(dataPath string, cfg TrainConfig, dir, format string) error {
+	data, err := LoadDataset(dataPath)
+	if err != nil {
+		return err
+	}
+	model, history, err := TrainANFIS(data, cfg)
+	if err != nil {
+		return err
+	}
+	if len(history.Validation) == 0 {
+		return errors.New("training ran no epochs")
+	}
+	for i := range history.Train {
+		if i%10 == 0 || i == len(history.Train)-1 {
+			fmt.Printf("epoch %3d  train RMSE %.4f  validation RMSE %.4f\n", i+1, history.Train[i], history.Validation[i])
+		}
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	curves := filepath.Join(dir, "training."+format)
+	if err := PlotTrainingHistory(history, curves); err != nil {
+		return err
+	}
+	fmt.Println("saved", curves)
+	best := 0
+	for i, e := range history.Validation {
+		if e < history.Validation[best] {
+			best = i
+		}
+	}
+	fmt.Printf("kept the model from epoch %d (validation RMSE %.4f)\n", best+1, history.Validation[best])
+	system := model.System()
+	fclPath := filepath.Join(dir, system.Name+".fcl")
+	f, err := os.Create(fclPath)
+	if err != nil {
+		return err
+	}
+	if err := WriteFCL(f, system); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	fmt.Println("saved", fclPath)
+	for _, v := range system.Inputs {
+		fmt.Printf("%s:", v.Name)
+		for _, term := range v.Terms {
+			fmt.Printf("  %s (%v)", term.Name, term.MF)
+		}
+		fmt.Println()
+	}
+	return nil
+}
+
+func main() {
+	mode := flag.String("mode", "once", "once: evaluate one set of inputs, batch: evaluate every row of a CSV file, serve: HTTP JSON endpoint, stream: control from CSV samples, heater: closed-loop heater simulation, plot: save plots of the system, train: fit an ANFIS model to -data")
+	fclPath := flag.String("fcl", "", "load the system from an FCL file (in -mode once and plot inputs are given as name=value arguments)")
+	systemName := flag.String("system", "", "built-in system instead of -fcl: attendance, attendance-sugeno, attendance-type2 or heater")
+	input := flag.String("input", "-", "CSV file with cases for -mode batch or samples for -mode stream, - for stdin")
+	addr := flag.String("addr", ":8080", "listen address for -mode serve")
+	follow := flag.Bool("follow", false, "keep reading -input as lines are appended, like tail -f")
+	tick := flag.Duration("tick", 100*time.Millisecond, "controller tick in -mode stream")
+	setpoint := flag.Float64("setpoint", 21, "temperature setpoint in -mode heater")
+	minutes := flag.Float64("minutes", 120, "simulated minutes in -mode heater")
+	pace := flag.Duration("pace", 0, "real time per simulation step in -mode heater, 0 runs as fast as possible")
+	plotDir := flag.String("out", "plots", "output directory for -mode plot and train")
+	plotFormat := flag.String("format", "png", "image format for -mode plot and train: png, svg or pdf")
+	dataPath := flag.String("data", "attendance.csv", "labelled CSV for -mode train, the last column is the target")
+	train := DefaultTrainConfig()
+	flag.IntVar(&train.Epochs, "epochs", train.Epochs, "training epochs in -mode train")
+	flag.IntVar(&train.Terms, "terms", train.Terms, "membership terms per input in -mode train")
+	flag.Float64Var(&train.Validation, "validation", train.Validation, "fraction of records held out for validation in -mode train")
+	flag.Parse()
+
+	var err error
+	switch *mode {
+	case "once":
+		err = runOnce(*fclPath, *systemName, flag.Args())
+	case "batch":
+		err = runBatch(*fclPath, *systemName, *input)
+	case "serve":
+		err = runServe(*fclPath, *systemName, *addr)
+	case "stream":
+		err = runStream(*fclPath, *systemName, *input, *follow, *tick)
+	case "heater":
+		var system *System
+		if system, err = loadSystem(*fclPath, *systemName, HeaterSystem); err == nil {
+			ctrl := NewController(system, 0)
+			plant := &Heater{Temperature: 15, Ambient: 5, Gain: 2, Loss: 0.05}
+			err = runHeater(os.Stdout, ctrl, plant, HeaterRun{Setpoint: *setpoint, Minutes: *minutes, Step: 0.1, Every: 5, Pace: *pace})
+			if err == nil {
+				ctrl.Stats.Print(os.Stdout)
+			}
+		}
+	case "plot":
+		err = runPlot(*fclPath, *systemName, flag.Args(), *plotDir, *plotFormat)
+	case "train":
+		err = runTrain(*dataPath, train, *plotDir, *plotFormat)
+	default:
+		err = fmt.Errorf("unknown mode %q (want once, batch, serve, stream, heater, plot or train)", *mode)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
 }
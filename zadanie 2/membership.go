@@ -0,0 +1,136 @@
+package main
+
+import (
+	"fmt"
+	"math"
+)
+
+// MembershipFunc to funkcja przynależności zbioru rozmytego: przypisuje
+// wartości ostrej x stopień przynależności z przedziału [0, 1]
+type MembershipFunc interface {
+	Degree(x float64) float64
+}
+
+// Triangular to trójkątna funkcja przynależności o wierzchołkach A <= B <= C.
+// Gdy A == B lub B == C, trójkąt staje się "ramieniem" (stopień 1 na krawędzi)
+type Triangular struct {
+	A, B, C float64
+}
+
+// Degree zwraca stopień przynależności x do trójkąta
+func (t Triangular) Degree(x float64) float64 {
+	switch {
+	case x == t.B:
+		return 1
+	case x <= t.A || x >= t.C:
+		return 0
+	case x < t.B:
+		return (x - t.A) / (t.B - t.A)
+	default:
+		return (t.C - x) / (t.C - t.B)
+	}
+}
+
+// String opisuje funkcję w notacji używanej w plikach definicji systemu
+func (t Triangular) String() string {
+	return fmt.Sprintf("trian %g %g %g", t.A, t.B, t.C)
+}
+
+// Trapezoidal to trapezowa funkcja przynależności: rośnie od A do B,
+// jest równa 1 od B do C i maleje od C do D
+type Trapezoidal struct {
+	A, B, C, D float64
+}
+
+// Degree zwraca stopień przynależności x do trapezu
+func (t Trapezoidal) Degree(x float64) float64 {
+	switch {
+	case x >= t.B && x <= t.C:
+		return 1
+	case x <= t.A || x >= t.D:
+		return 0
+	case x < t.B:
+		return (x - t.A) / (t.B - t.A)
+	default:
+		return (t.D - x) / (t.D - t.C)
+	}
+}
+
+// String opisuje funkcję w notacji używanej w plikach definicji systemu
+func (t Trapezoidal) String() string {
+	return fmt.Sprintf("trape %g %g %g %g", t.A, t.B, t.C, t.D)
+}
+
+// Gaussian to gaussowska funkcja przynależności o środku Mean i szerokości Sigma
+type Gaussian struct {
+	Mean, Sigma float64
+}
+
+// Degree zwraca stopień przynależności x do zbioru gaussowskiego
+func (g Gaussian) Degree(x float64) float64 {
+	d := (x - g.Mean) / g.Sigma
+	return math.Exp(-d * d / 2)
+}
+
+// String opisuje funkcję w notacji używanej w plikach definicji systemu
+func (g Gaussian) String() string {
+	return fmt.Sprintf("gauss %g %g", g.Mean, g.Sigma)
+}
+
+// Sigmoid to sigmoidalna funkcja przynależności 1 / (1 + e^(-Slope(x - Center))).
+// Dodatnie Slope daje zbiór "duży", ujemne zbiór "mały"
+type Sigmoid struct {
+	Slope, Center float64
+}
+
+// Degree zwraca stopień przynależności x do zbioru sigmoidalnego
+func (s Sigmoid) Degree(x float64) float64 {
+	return 1 / (1 + math.Exp(-s.Slope*(x-s.Center)))
+}
+
+// String opisuje funkcję w notacji używanej w plikach definicji systemu
+func (s Sigmoid) String() string {
+	return fmt.Sprintf("sigm %g %g", s.Slope, s.Center)
+}
+
+// Point to punkt (X, Y) funkcji przynależności zadanej łamaną
+type Point struct {
+	X, Y float64
+}
+
+// PiecewiseLinear to funkcja przynależności zadana punktami łamanej, jak
+// w definicjach terminów języka FCL. Punkty muszą być uporządkowane według X;
+// poza nimi funkcja przyjmuje wartość skrajnego punktu
+type PiecewiseLinear []Point
+
+// Degree zwraca stopień przynależności x, interpolując liniowo między punktami
+func (p PiecewiseLinear) Degree(x float64) float64 {
+	if len(p) == 0 {
+		return 0
+	}
+	if x <= p[0].X {
+		return p[0].Y
+	}
+	for i := 1; i < len(p); i++ {
+		if x <= p[i].X {
+			a, b := p[i-1], p[i]
+			if b.X == a.X {
+				return b.Y
+			}
+			return a.Y + (b.Y-a.Y)*(x-a.X)/(b.X-a.X)
+		}
+	}
+	return p[len(p)-1].Y
+}
+
+// String opisuje funkcję w notacji używanej w plikach definicji systemu
+func (p PiecewiseLinear) String() string {
+	s := ""
+	for i, pt := range p {
+		if i > 0 {
+			s += " "
+		}
+		s += fmt.Sprintf("(%g, %g)", pt.X, pt.Y)
+	}
+	return s
+}
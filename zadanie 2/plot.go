@@ -0,0 +1,360 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"image/color"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gonum.org/v1/plot"
+	"gonum.org/v1/plot/palette/moreland"
+	"gonum.org/v1/plot/plotter"
+	"gonum.org/v1/plot/plotutil"
+	"gonum.org/v1/plot/vg"
+	"gonum.org/v1/plot/vg/draw"
+)
+
+// plotPoints to liczba punktów, w których rysowane są funkcje przynależności
+const plotPoints = 400
+
+// saveCanvas rysuje na płótnie o podanym rozmiarze i zapisuje je do pliku.
+// Format (png, svg, pdf, ...) wynika z rozszerzenia pliku
+func saveCanvas(path string, w, h vg.Length, paint func(dc draw.Canvas)) (err error) {
+	format := strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), ".")
+	c, err := draw.NewFormattedCanvas(w, h, format)
+	if err != nil {
+		return err
+	}
+	paint(draw.New(c))
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+	}()
+	_, err = c.WriteTo(f)
+	return err
+}
+
+// membershipLines dodaje do wykresu funkcje przynależności wszystkich terminów zmiennej.
+// Zbiór typu 2 ma górną funkcję rysowaną linią ciągłą i dolną przerywaną
+func membershipLines(p *plot.Plot, v *Variable) error {
+	for i, term := range v.Terms {
+		if term.MF == nil {
+			continue
+		}
+		mfs := []MembershipFunc{term.MF}
+		if m, ok := term.MF.(IntervalMF); ok {
+			mfs = []MembershipFunc{m.Upper, m.Lower}
+		}
+		for k, mf := range mfs {
+			pts := make(plotter.XYs, plotPoints)
+			for j := range pts {
+				x := v.Min + (v.Max-v.Min)*float64(j)/float64(plotPoints-1)
+				pts[j].X, pts[j].Y = x, mf.Degree(x)
+			}
+			line, err := plotter.NewLine(pts)
+			if err != nil {
+				return err
+			}
+			line.Color = plotutil.Color(i)
+			line.Width = vg.Points(2)
+			if k > 0 {
+				line.Dashes = plotutil.Dashes(2)
+			} else {
+				p.Legend.Add(term.Name, line)
+			}
+			p.Add(line)
+		}
+	}
+	return nil
+}
+
+// newMembershipPlot tworzy pusty wykres o osiach dopasowanych do dziedziny zmiennej
+func newMembershipPlot(v *Variable) (*plot.Plot, error) {
+	p, err := plot.New()
+	if err != nil {
+		return nil, err
+	}
+	p.Title.Text = v.Name
+	p.X.Label.Text = v.Name
+	p.Y.Label.Text = "stopień przynależności"
+	p.X.Min, p.X.Max = v.Min, v.Max
+	p.Y.Min, p.Y.Max = 0, 1.05
+	p.Legend.Top = true
+	return p, nil
+}
+
+// PlotMembership zapisuje wykres funkcji przynależności terminów zmiennej
+func PlotMembership(v *Variable, path string) error {
+	p, err := newMembershipPlot(v)
+	if err != nil {
+		return err
+	}
+	if err := membershipLines(p, v); err != nil {
+		return err
+	}
+	return p.Save(6*vg.Inch, 4*vg.Inch, path)
+}
+
+// surfaceGrid to powierzchnia sterowania: wartości wyjścia na siatce dwóch wejść
+type surfaceGrid struct {
+	xs, ys   []float64
+	z        [][]float64 // z[wiersz y][kolumna x]
+	min, max float64     // dziedzina wyjścia, wspólna skala kolorów
+}
+
+func (g surfaceGrid) Dims() (c, r int)   { return len(g.xs), len(g.ys) }
+func (g surfaceGrid) Z(c, r int) float64 { return g.z[r][c] }
+func (g surfaceGrid) X(c int) float64    { return g.xs[c] }
+func (g surfaceGrid) Y(r int) float64    { return g.ys[r] }
+func (g surfaceGrid) Min() float64       { return g.min }
+func (g surfaceGrid) Max() float64       { return g.max }
+
+// linspace zwraca n równo rozłożonych punktów przedziału [lo, hi]
+func linspace(lo, hi float64, n int) []float64 {
+	xs := make([]float64, n)
+	for i := range xs {
+		xs[i] = lo + (hi-lo)*float64(i)/float64(n-1)
+	}
+	return xs
+}
+
+// surface liczy wartości wyjścia output na siatce n x n wejść xName i yName,
+// trzymając pozostałe wejścia na wartościach z fixed. Punkty, w których nie
+// odpaliła żadna reguła, mają wartość NaN
+func (s *System) surface(output, xName, yName string, fixed map[string]float64, n int) (surfaceGrid, error) {
+	xv, ok := s.Input(xName)
+	if !ok {
+		return surfaceGrid{}, fmt.Errorf("unknown input variable %q", xName)
+	}
+	yv, ok := s.Input(yName)
+	if !ok {
+		return surfaceGrid{}, fmt.Errorf("unknown input variable %q", yName)
+	}
+	out, ok := s.Output(output)
+	if !ok {
+		return surfaceGrid{}, fmt.Errorf("unknown output variable %q", output)
+	}
+	g := surfaceGrid{xs: linspace(xv.Min, xv.Max, n), ys: linspace(yv.Min, yv.Max, n), min: out.Min, max: out.Max}
+	inputs := make(map[string]float64, len(fixed)+2)
+	for name, x := range fixed {
+		inputs[name] = x
+	}
+	g.z = make([][]float64, n)
+	for r, y := range g.ys {
+		g.z[r] = make([]float64, n)
+		for c, x := range g.xs {
+			inputs[xName], inputs[yName] = x, y
+			outputs, _, err := s.Evaluate(inputs)
+			switch {
+			case err == nil:
+				g.z[r][c] = outputs[output]
+			case errors.Is(err, ErrNoRuleFired):
+				g.z[r][c] = math.NaN()
+			default:
+				return surfaceGrid{}, err
+			}
+		}
+	}
+	return g, nil
+}
+
+// PlotSurface zapisuje mapę ciepła wyjścia output w funkcji wejść xName i yName
+// wraz z legendą kolorów
+func PlotSurface(s *System, output, xName, yName string, fixed map[string]float64, path string) error {
+	g, err := s.surface(output, xName, yName, fixed, 60)
+	if err != nil {
+		return err
+	}
+	colors := moreland.SmoothBlueRed()
+	colors.SetMin(g.min)
+	colors.SetMax(g.max)
+
+	p, err := plot.New()
+	if err != nil {
+		return err
+	}
+	var held []string
+	for _, v := range s.Inputs {
+		if v.Name != xName && v.Name != yName {
+			held = append(held, fmt.Sprintf("%s = %g", v.Name, fixed[v.Name]))
+		}
+	}
+	p.Title.Text = output
+	if len(held) > 0 {
+		p.Title.Text += " (" + strings.Join(held, ", ") + ")"
+	}
+	p.X.Label.Text = xName
+	p.Y.Label.Text = yName
+	p.X.Padding, p.Y.Padding = 0, 0
+	heat := plotter.NewHeatMap(g, colors.Palette(255))
+	heat.NaN = color.Gray{Y: 200}
+	p.Add(heat)
+
+	bar, err := plot.New()
+	if err != nil {
+		return err
+	}
+	bar.Title.Text = output
+	bar.HideX()
+	bar.Y.Padding = 0
+	bar.Add(&plotter.ColorBar{ColorMap: colors, Vertical: true})
+
+	const barWidth = vg.Inch
+	return saveCanvas(path, 7*vg.Inch, 5*vg.Inch, func(dc draw.Canvas) {
+		p.Draw(draw.Crop(dc, 0, -barWidth, 0, 0))
+		bar.Draw(draw.Crop(dc, dc.Max.X-dc.Min.X-barWidth+vg.Points(10), 0, 0, 0))
+	})
+}
+
+// PlotTrace zapisuje przebieg wnioskowania: siły odpalenia reguł oraz dla
+// każdego wyjścia terminy, zagregowany zbiór (Mamdani) i wartość ostrą
+func PlotTrace(s *System, e *Explanation, path string) error {
+	rules, err := plot.New()
+	if err != nil {
+		return err
+	}
+	rules.Title.Text = "Siła odpalenia reguł"
+	rules.Y.Min, rules.Y.Max = 0, 1
+	strengths := make(plotter.Values, len(e.Rules))
+	names := make([]string, len(e.Rules))
+	for i, r := range e.Rules {
+		strengths[i] = r.Strength
+		names[i] = fmt.Sprintf("R%d", i+1)
+	}
+	bars, err := plotter.NewBarChart(strengths, vg.Points(15))
+	if err != nil {
+		return err
+	}
+	bars.Color = plotutil.Color(0)
+	rules.Add(bars)
+	rules.NominalX(names...)
+	rules.X.Min, rules.X.Max = -0.5, float64(len(names))-0.5
+
+	plots := []*plot.Plot{rules}
+	for _, v := range s.Outputs {
+		p, err := newMembershipPlot(v)
+		if err != nil {
+			return err
+		}
+		p.Title.Text = fmt.Sprintf("%s = %.3f", v.Name, e.Outputs[v.Name])
+		if iv, ok := e.Intervals[v.Name]; ok {
+			p.Title.Text += fmt.Sprintf(" [%.3f, %.3f]", iv.Lower, iv.Upper)
+		}
+		if set, ok := e.Aggregated[v.Name]; ok {
+			// w typie 2 jaśniejsze wypełnienie to ślad niepewności, ciemniejsze dolna funkcja
+			for k, mu := range [][]float64{set.Mu, set.Lower} {
+				if mu == nil {
+					continue
+				}
+				pts := make(plotter.XYs, len(set.X))
+				for i := range pts {
+					pts[i].X, pts[i].Y = set.X[i], mu[i]
+				}
+				area, err := plotter.NewLine(pts)
+				if err != nil {
+					return err
+				}
+				area.FillColor = color.NRGBA{R: 120, G: 120, B: 120, A: 120}
+				area.Color = color.Black
+				p.Add(area)
+				if k == 0 {
+					p.Legend.Add("agregacja", area)
+				}
+			}
+		}
+		// terminy rysujemy na zagregowanym zbiorze, żeby wypełnienie ich nie zasłaniało
+		if err := membershipLines(p, v); err != nil {
+			return err
+		}
+		crisp, err := plotter.NewLine(plotter.XYs{{X: e.Outputs[v.Name], Y: 0}, {X: e.Outputs[v.Name], Y: 1.05}})
+		if err != nil {
+			return err
+		}
+		crisp.Color = color.RGBA{R: 200, A: 255}
+		crisp.Width = vg.Points(2)
+		crisp.Dashes = plotutil.Dashes(1)
+		p.Add(crisp)
+		p.Legend.Add("wynik", crisp)
+		plots = append(plots, p)
+	}
+
+	tiles := draw.Tiles{Rows: len(plots), Cols: 1, PadY: vg.Points(10)}
+	return saveCanvas(path, 7*vg.Inch, vg.Length(len(plots))*3*vg.Inch, func(dc draw.Canvas) {
+		for i, p := range plots {
+			p.Draw(tiles.At(dc, 0, i))
+		}
+	})
+}
+
+// plotSystem zapisuje do katalogu dir wykresy terminów wszystkich zmiennych,
+// powierzchnie sterowania każdej pary wejść (pozostałe na wartościach z inputs)
+// i przebieg wnioskowania dla inputs
+func plotSystem(s *System, inputs map[string]float64, dir, format string) ([]string, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	var files []string
+	file := func(name string) string {
+		path := filepath.Join(dir, name+"."+format)
+		files = append(files, path)
+		return path
+	}
+	for _, v := range append(append([]*Variable(nil), s.Inputs...), s.Outputs...) {
+		if err := PlotMembership(v, file("membership_"+v.Name)); err != nil {
+			return files, err
+		}
+	}
+	for _, out := range s.Outputs {
+		for i, x := range s.Inputs {
+			for _, y := range s.Inputs[i+1:] {
+				name := fmt.Sprintf("surface_%s_%s_%s", out.Name, x.Name, y.Name)
+				if err := PlotSurface(s, out.Name, x.Name, y.Name, inputs, file(name)); err != nil {
+					return files, err
+				}
+			}
+		}
+	}
+	_, e, err := s.Evaluate(inputs)
+	if err != nil {
+		return files, err
+	}
+	return files, PlotTrace(s, e, file("trace"))
+}
+
+// PlotTrainingHistory zapisuje krzywe błędu uczenia i walidacji w kolejnych epokach
+func PlotTrainingHistory(h TrainingHistory, path string) error {
+	p, err := plot.New()
+	if err != nil {
+		return err
+	}
+	p.Title.Text = "Uczenie ANFIS"
+	p.X.Label.Text = "epoka"
+	p.Y.Label.Text = "RMSE"
+	p.Legend.Top = true
+	for i, curve := range []struct {
+		name   string
+		values []float64
+	}{{"uczący", h.Train}, {"walidacyjny", h.Validation}} {
+		pts := make(plotter.XYs, len(curve.values))
+		for j, e := range curve.values {
+			pts[j].X, pts[j].Y = float64(j+1), e
+		}
+		line, err := plotter.NewLine(pts)
+		if err != nil {
+			return err
+		}
+		line.Color = plotutil.Color(i)
+		line.Width = vg.Points(2)
+		p.Add(line)
+		p.Legend.Add(curve.name, line)
+	}
+	return p.Save(6*vg.Inch, 4*vg.Inch, path)
+}
@@ -0,0 +1,330 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// systems to wbudowane systemy, które można wybrać flagą -system
+var systems = map[string]func() *System{
+	"attendance":        AttendanceSystem,
+	"attendance-sugeno": AttendanceSugenoSystem,
+	"attendance-type2":  AttendanceType2System,
+	"heater":            HeaterSystem,
+}
+
+// builtinSystem zwraca wbudowany system o podanej nazwie
+func builtinSystem(name string) (*System, error) {
+	build, ok := systems[name]
+	if !ok {
+		names := make([]string, 0, len(systems))
+		for n := range systems {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("unknown system %q (want %s)", name, strings.Join(names, ", "))
+	}
+	return build(), nil
+}
+
+// checkInputs zgłasza wejścia, których system nie zna, np. literówki w nazwach
+func checkInputs(system *System, inputs map[string]float64) error {
+	for name := range inputs {
+		if _, ok := system.Input(name); !ok {
+			return fmt.Errorf("unknown input variable %q", name)
+		}
+	}
+	return nil
+}
+
+// firedRule to reguła o niezerowej sile odpalenia w odpowiedzi JSON;
+// Firing to przedział siły odpalenia w systemie typu 2
+type firedRule struct {
+	Index    int       `json:"index"` // numer reguły liczony od 1
+	Rule     string    `json:"rule"`
+	Strength float64   `json:"strength"`
+	Firing   *Interval `json:"firing,omitempty"`
+}
+
+// evaluation to wynik wnioskowania w postaci JSON: wartości ostre wyjść,
+// przynależność każdego wyjścia do jego terminów i reguły, które odpaliły.
+// Systemy typu 2 podają też przedziały wyjść po redukcji typu
+type evaluation struct {
+	Inputs    map[string]float64            `json:"inputs"`
+	Clamped   []string                      `json:"clamped,omitempty"`
+	Outputs   Result                        `json:"outputs"`
+	Intervals map[string]Interval           `json:"intervals,omitempty"`
+	Terms     map[string]map[string]float64 `json:"terms"`
+	Rules     []firedRule                   `json:"rules"`
+}
+
+// newEvaluation opisuje wnioskowanie e systemu s
+func newEvaluation(s *System, e *Explanation) evaluation {
+	ev := evaluation{
+		Inputs:    e.Inputs,
+		Clamped:   e.Clamped,
+		Outputs:   e.Outputs,
+		Intervals: e.Intervals,
+		Terms:     e.OutputDegrees(s),
+		Rules:     []firedRule{},
+	}
+	for i, r := range e.Rules {
+		if r.Firing.Upper > 0 {
+			fired := firedRule{Index: i + 1, Rule: r.Rule.String(), Strength: r.Strength}
+			if e.Intervals != nil {
+				fired.Firing = &e.Rules[i].Firing
+			}
+			ev.Rules = append(ev.Rules, fired)
+		}
+	}
+	return ev
+}
+
+// printEvaluation wypisuje wyjścia z przynależnością do terminów i reguły, które odpaliły
+func printEvaluation(w io.Writer, s *System, e *Explanation) {
+	if len(e.Clamped) > 0 {
+		fmt.Fprintf(w, "clamped to range: %s\n", strings.Join(e.Clamped, ", "))
+	}
+	degrees := e.OutputDegrees(s)
+	for _, out := range s.Outputs {
+		fmt.Fprintf(w, "%s: %.2f (range %g - %g)", out.Name, e.Outputs[out.Name], out.Min, out.Max)
+		if iv, ok := e.Intervals[out.Name]; ok {
+			fmt.Fprintf(w, " interval [%.2f, %.2f]", iv.Lower, iv.Upper)
+		}
+		for _, term := range out.Terms {
+			fmt.Fprintf(w, " %s %.2f", term.Name, degrees[out.Name][term.Name])
+		}
+		fmt.Fprintln(w)
+	}
+	for i, r := range e.Rules {
+		switch {
+		case r.Firing.Upper == 0:
+		case e.Intervals != nil:
+			fmt.Fprintf(w, "rule %d: [%.3f, %.3f]  %s\n", i+1, r.Firing.Lower, r.Firing.Upper, r.Rule)
+		default:
+			fmt.Fprintf(w, "rule %d: %.3f  %s\n", i+1, r.Strength, r.Rule)
+		}
+	}
+}
+
+// evaluateBatch czyta przypadki z CSV z nagłówkiem i wypisuje je jako CSV
+// z dopisanymi kolumnami wyjść (w systemie typu 2 także końców przedziałów
+// wyjść, nazwa_lower i nazwa_upper) i kolumną error. Kolumny o nazwach wejść systemu
+// są wejściami, pozostałe są przepisywane bez zmian. Błąd przypadku (np. wartość
+// spoza dziedziny, inna liczba pól niż w nagłówku albo błędny cudzysłów) trafia
+// do kolumny error i nie przerywa przetwarzania
+func evaluateBatch(system *System, r io.Reader, w io.Writer) error {
+	in := csv.NewReader(r)
+	in.TrimLeadingSpace = true
+	in.FieldsPerRecord = -1
+	header, err := in.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return errors.New("empty input, expected a CSV header")
+		}
+		return err
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.TrimSpace(name)] = i
+	}
+	for _, v := range system.Inputs {
+		if _, ok := columns[v.Name]; !ok {
+			return fmt.Errorf("missing column for input %q", v.Name)
+		}
+	}
+	for _, v := range system.Outputs {
+		if _, ok := columns[v.Name]; ok {
+			return fmt.Errorf("input already has a column named after output %q", v.Name)
+		}
+	}
+
+	type2 := system.IsType2()
+	out := csv.NewWriter(w)
+	record := append([]string(nil), header...)
+	for _, v := range system.Outputs {
+		record = append(record, v.Name)
+		if type2 {
+			record = append(record, v.Name+"_lower", v.Name+"_upper")
+		}
+	}
+	out.Write(append(record, "error"))
+	format := func(x float64) string { return strconv.FormatFloat(x, 'f', 3, 64) }
+	for {
+		fields, err := in.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		var parseErr *csv.ParseError
+		switch {
+		case errors.As(err, &parseErr):
+			fields, err = nil, fmt.Errorf("line %d: %v", parseErr.Line, parseErr.Err)
+		case err != nil:
+			return err
+		case len(fields) != len(header):
+			err = fmt.Errorf("expected %d fields, found %d", len(header), len(fields))
+		}
+		// kolumny wyjść zawsze stoją za kolumnami nagłówka, także w wierszach o złej liczbie pól
+		record = make([]string, len(header))
+		copy(record, fields)
+		var e *Explanation
+		if err == nil {
+			e, err = evaluateRecord(system, columns, fields)
+		}
+		for _, v := range system.Outputs {
+			switch {
+			case err != nil && type2:
+				record = append(record, "", "", "")
+			case err != nil:
+				record = append(record, "")
+			case type2:
+				iv := e.Intervals[v.Name]
+				record = append(record, format(e.Outputs[v.Name]), format(iv.Lower), format(iv.Upper))
+			default:
+				record = append(record, format(e.Outputs[v.Name]))
+			}
+		}
+		if err != nil {
+			record = append(record, err.Error())
+		} else {
+			record = append(record, "")
+		}
+		out.Write(record)
+	}
+	out.Flush()
+	return out.Error()
+}
+
+// evaluateRecord przeprowadza wnioskowanie dla jednej linii CSV
+func evaluateRecord(system *System, columns map[string]int, fields []string) (*Explanation, error) {
+	inputs := make(map[string]float64, len(system.Inputs))
+	for _, v := range system.Inputs {
+		x, err := strconv.ParseFloat(strings.TrimSpace(fields[columns[v.Name]]), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value of %q: %v", v.Name, err)
+		}
+		inputs[v.Name] = x
+	}
+	_, e, err := system.Evaluate(inputs)
+	return e, err
+}
+
+// evaluateRequest to treść POST /evaluate
+type evaluateRequest struct {
+	Inputs map[string]float64 `json:"inputs"`
+}
+
+// errorResponse to treść odpowiedzi z błędem; Variable wskazuje wejście spoza dziedziny
+type errorResponse struct {
+	Error    string `json:"error"`
+	Variable string `json:"variable,omitempty"`
+}
+
+// termInfo i variableInfo opisują zmienne systemu w GET /system
+type termInfo struct {
+	Name     string `json:"name"`
+	Function string `json:"function"`
+}
+
+type variableInfo struct {
+	Name  string     `json:"name"`
+	Min   float64    `json:"min"`
+	Max   float64    `json:"max"`
+	Terms []termInfo `json:"terms"`
+}
+
+// systemInfo to treść odpowiedzi GET /system
+type systemInfo struct {
+	Name    string         `json:"name"`
+	Type    string         `json:"type"`
+	Inputs  []variableInfo `json:"inputs"`
+	Outputs []variableInfo `json:"outputs"`
+	Rules   []string       `json:"rules"`
+}
+
+// newSystemInfo opisuje zmienne i reguły systemu
+func newSystemInfo(s *System) systemInfo {
+	info := systemInfo{Name: s.Name, Type: "mamdani"}
+	if s.Type == Sugeno {
+		info.Type = "sugeno"
+	}
+	describe := func(vars []*Variable) []variableInfo {
+		out := make([]variableInfo, len(vars))
+		for i, v := range vars {
+			out[i] = variableInfo{Name: v.Name, Min: v.Min, Max: v.Max}
+			for _, term := range v.Terms {
+				function := ""
+				switch {
+				case term.MF != nil:
+					function = fmt.Sprint(term.MF)
+				case term.Sugeno != nil:
+					function = term.Sugeno.String()
+				}
+				out[i].Terms = append(out[i].Terms, termInfo{Name: term.Name, Function: function})
+			}
+		}
+		return out
+	}
+	info.Inputs = describe(s.Inputs)
+	info.Outputs = describe(s.Outputs)
+	for _, r := range s.Rules {
+		info.Rules = append(info.Rules, r.String())
+	}
+	return info
+}
+
+// newService udostępnia system przez HTTP:
+//
+//	GET  /system    zmienne, terminy i reguły systemu
+//	POST /evaluate  wnioskowanie, treść evaluateRequest, odpowiedź evaluation
+//
+// Błędy mają postać errorResponse
+func newService(system *System) http.Handler {
+	info := newSystemInfo(system)
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /system", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, info)
+	})
+	mux.HandleFunc("POST /evaluate", func(w http.ResponseWriter, r *http.Request) {
+		var req evaluateRequest
+		dec := json.NewDecoder(r.Body)
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid request body: " + err.Error()})
+			return
+		}
+		if err := checkInputs(system, req.Inputs); err != nil {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: err.Error()})
+			return
+		}
+		_, e, err := system.Evaluate(req.Inputs)
+		if err != nil {
+			status, resp := http.StatusBadRequest, errorResponse{Error: err.Error()}
+			var rangeErr *RangeError
+			switch {
+			case errors.As(err, &rangeErr):
+				resp.Variable = rangeErr.Variable
+			case errors.Is(err, ErrNoRuleFired):
+				status = http.StatusUnprocessableEntity
+			}
+			writeJSON(w, status, resp)
+			return
+		}
+		writeJSON(w, http.StatusOK, newEvaluation(system, e))
+	})
+	return mux
+}
+
+// writeJSON wysyła v jako odpowiedź JSON
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,263 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// IntervalMF to funkcja przynależności przedziałowego zbioru rozmytego typu 2:
+// stopień przynależności x to przedział [Lower(x), Upper(x)], a obszar między
+// funkcjami (ślad niepewności) opisuje niepewność samej definicji terminu.
+// Dolna funkcja nie może przekraczać górnej
+type IntervalMF struct {
+	Upper, Lower MembershipFunc
+}
+
+// Degree zwraca środek przedziału przynależności, dzięki czemu zbiór typu 2
+// można używać wszędzie tam, gdzie wystarcza przybliżenie typu 1
+func (m IntervalMF) Degree(x float64) float64 {
+	return (m.Upper.Degree(x) + m.Lower.Degree(x)) / 2
+}
+
+// Bounds zwraca dolny i górny stopień przynależności x
+func (m IntervalMF) Bounds(x float64) Interval {
+	return Interval{Lower: m.Lower.Degree(x), Upper: m.Upper.Degree(x)}
+}
+
+// String opisuje funkcję w notacji używanej w plikach definicji systemu
+func (m IntervalMF) String() string {
+	return fmt.Sprintf("upper %v lower %v", m.Upper, m.Lower)
+}
+
+// check sprawdza na siatce dziedziny [min, max], czy dolna funkcja nie przekracza górnej
+func (m IntervalMF) check(min, max float64) error {
+	for _, x := range linspace(min, max, 201) {
+		if b := m.Bounds(x); b.Lower > b.Upper+1e-9 {
+			return fmt.Errorf("lower membership %.3f exceeds upper %.3f at %g", b.Lower, b.Upper, x)
+		}
+	}
+	return nil
+}
+
+// Interval to przedział [Lower, Upper]: stopień przynależności lub siła
+// odpalenia w systemie typu 2 albo zredukowany typ wyjścia
+type Interval struct {
+	Lower float64 `json:"lower"`
+	Upper float64 `json:"upper"`
+}
+
+// bounds zwraca przedział przynależności x do terminu; dla zbioru typu 1
+// oba końce są równe
+func bounds(mf MembershipFunc, x float64) Interval {
+	if m, ok := mf.(IntervalMF); ok {
+		return m.Bounds(x)
+	}
+	d := mf.Degree(x)
+	return Interval{d, d}
+}
+
+// IsType2 mówi, czy któryś termin systemu jest zbiorem typu 2. Taki system
+// wnioskuje na przedziałach (terminy typu 1 są przedziałami zerowej szerokości),
+// a wyjścia redukuje algorytmem Karnika-Mendela
+func (s *System) IsType2() bool {
+	for _, v := range append(append([]*Variable(nil), s.Inputs...), s.Outputs...) {
+		for _, term := range v.Terms {
+			if _, ok := term.MF.(IntervalMF); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// checkType2 sprawdza ślady niepewności terminów typu 2 i metodę wyostrzania:
+// redukcja typu Karnika-Mendela to uogólnienie środka ciężkości
+func (s *System) checkType2() error {
+	if !s.IsType2() {
+		return nil
+	}
+	for _, v := range append(append([]*Variable(nil), s.Inputs...), s.Outputs...) {
+		for _, term := range v.Terms {
+			if m, ok := term.MF.(IntervalMF); ok {
+				if err := m.check(v.Min, v.Max); err != nil {
+					return fmt.Errorf("variable %q, term %q: %v", v.Name, term.Name, err)
+				}
+			}
+		}
+	}
+	if s.Type == Mamdani && s.Defuzzify != Centroid {
+		return errors.New("type-2 systems are defuzzified by Karnik-Mendel type reduction of the centroid, other methods are not supported")
+	}
+	return nil
+}
+
+// evalInterval oblicza przedział siły odpalenia przesłanki. Normy są
+// niemalejące, więc wystarczy policzyć je osobno dla dolnych i górnych
+// stopni; negacja zamienia końce przedziału
+func evalInterval(x Expr, bounds map[string]map[string]Interval, ops Operators) Interval {
+	switch e := x.(type) {
+	case Is:
+		return bounds[e.Variable][e.Term]
+	case And:
+		var result Interval
+		for i, y := range e {
+			b := evalInterval(y, bounds, ops)
+			if i == 0 {
+				result = b
+			} else {
+				result = Interval{ops.And(result.Lower, b.Lower), ops.And(result.Upper, b.Upper)}
+			}
+		}
+		return result
+	case Or:
+		var result Interval
+		for _, y := range e {
+			b := evalInterval(y, bounds, ops)
+			result = Interval{ops.Or(result.Lower, b.Lower), ops.Or(result.Upper, b.Upper)}
+		}
+		return result
+	case Not:
+		b := evalInterval(e.X, bounds, ops)
+		return Interval{1 - b.Upper, 1 - b.Lower}
+	}
+	return Interval{}
+}
+
+// inferType2 przeprowadza wnioskowanie przedziałowe dla wejść już zapisanych w inf
+func (s *System) inferType2(inf *inference) error {
+	inf.bounds = make(map[string]map[string]Interval, len(s.Inputs))
+	for _, v := range s.Inputs {
+		b := make(map[string]Interval, len(v.Terms))
+		for _, term := range v.Terms {
+			if term.MF != nil {
+				b[term.Name] = bounds(term.MF, inf.inputs[v.Name])
+			}
+		}
+		inf.bounds[v.Name] = b
+	}
+
+	inf.firing = make([]Interval, len(s.Rules))
+	inf.strengths = make([]float64, len(s.Rules))
+	for i, rule := range s.Rules {
+		f := evalInterval(rule.If, inf.bounds, s.Operators)
+		f = Interval{f.Lower * rule.Weight, f.Upper * rule.Weight}
+		inf.firing[i] = f
+		inf.strengths[i] = (f.Lower + f.Upper) / 2
+	}
+
+	inf.lowerAggregated = make(map[string][]float64)
+	inf.intervals = make(map[string]Interval)
+	for _, out := range s.Outputs {
+		var y, lower, upper []float64
+		if s.Type == Sugeno {
+			for i, rule := range s.Rules {
+				for _, c := range rule.Then {
+					if c.Variable == out.Name {
+						term, _ := out.Term(c.Term)
+						y = append(y, term.Sugeno.value(inf.inputs))
+						lower = append(lower, inf.firing[i].Lower)
+						upper = append(upper, inf.firing[i].Upper)
+					}
+				}
+			}
+		} else {
+			y = s.samples(out)
+			lower, upper = s.aggregateType2(out, inf.firing)
+			inf.aggregated[out.Name], inf.lowerAggregated[out.Name] = upper, lower
+		}
+		reduced, ok := karnikMendel(y, lower, upper)
+		if !ok {
+			if out.Default == nil {
+				return fmt.Errorf("%w for output %q", ErrNoRuleFired, out.Name)
+			}
+			reduced = Interval{*out.Default, *out.Default}
+		}
+		inf.intervals[out.Name] = reduced
+		inf.outputs[out.Name] = (reduced.Lower + reduced.Upper) / 2
+	}
+	return nil
+}
+
+// aggregateType2 buduje dolną i górną funkcję przynależności zbioru wyjściowego:
+// jak w aggregate, ale osobno dla dolnych i górnych sił odpalenia i stopni terminów
+func (s *System) aggregateType2(out *Variable, firing []Interval) (lower, upper []float64) {
+	xs := s.samples(out)
+	lower, upper = make([]float64, len(xs)), make([]float64, len(xs))
+	for i, rule := range s.Rules {
+		for _, c := range rule.Then {
+			if c.Variable != out.Name || firing[i].Upper == 0 {
+				continue
+			}
+			term, _ := out.Term(c.Term)
+			for j, x := range xs {
+				b := bounds(term.MF, x)
+				lower[j] = math.Max(lower[j], math.Min(firing[i].Lower, b.Lower))
+				upper[j] = math.Max(upper[j], math.Min(firing[i].Upper, b.Upper))
+			}
+		}
+	}
+	return lower, upper
+}
+
+// karnikMendel redukuje typ: zwraca przedział średnich ważonych punktów y,
+// gdy waga punktu i może być dowolna z przedziału [lower[i], upper[i]].
+// ok jest fałszywe, gdy wszystkie górne wagi są zerowe
+func karnikMendel(y, lower, upper []float64) (Interval, bool) {
+	order := make([]int, len(y))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(a, b int) bool { return y[order[a]] < y[order[b]] })
+	ys := make([]float64, len(y))
+	lo := make([]float64, len(y))
+	hi := make([]float64, len(y))
+	for i, k := range order {
+		ys[i], lo[i], hi[i] = y[k], lower[k], upper[k]
+	}
+	if _, ok := weightedMean(ys, hi); !ok {
+		return Interval{}, false
+	}
+	return Interval{kmEndpoint(ys, lo, hi, true), kmEndpoint(ys, lo, hi, false)}, true
+}
+
+// kmEndpoint szuka iteracyjnie lewego (left) albo prawego końca przedziału
+// dla punktów ys posortowanych rosnąco. Lewy koniec daje waga górna dla punktów
+// nie większych od punktu przełączenia i dolna dla pozostałych, prawy odwrotnie.
+// Punkt przełączenia to bieżąca średnia; algorytm kończy się, gdy przestaje się ona zmieniać
+func kmEndpoint(ys, lower, upper []float64, left bool) float64 {
+	w := make([]float64, len(ys))
+	for i := range w {
+		w[i] = (lower[i] + upper[i]) / 2
+	}
+	c, _ := weightedMean(ys, w)
+	for iter := 0; iter <= len(ys); iter++ {
+		for i, y := range ys {
+			if (y <= c) == left {
+				w[i] = upper[i]
+			} else {
+				w[i] = lower[i]
+			}
+		}
+		next, ok := weightedMean(ys, w)
+		if !ok || math.Abs(next-c) < 1e-12 {
+			return c
+		}
+		c = next
+	}
+	return c
+}
+
+// weightedMean zwraca średnią ys ważoną w; ok jest fałszywe dla zerowej sumy wag
+func weightedMean(ys, w []float64) (float64, bool) {
+	num, den := 0.0, 0.0
+	for i, y := range ys {
+		num += y * w[i]
+		den += w[i]
+	}
+	if den == 0 {
+		return 0, false
+	}
+	return num / den, true
+}
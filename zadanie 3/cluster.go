@@ -0,0 +1,282 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"slices"
+	"strconv"
+)
+
+// ClusterAlgorithm to algorytm grupowania użytkowników.
+type ClusterAlgorithm string
+
+const (
+	// KMeans to k-średnich: środkiem grupy jest wektor średnich ocen jej członków.
+	KMeans ClusterAlgorithm = "kmeans"
+	// KMedoids to k-medoidów: środkiem grupy jest jej członek o najmniejszej
+	// sumie odległości do pozostałych.
+	KMedoids ClusterAlgorithm = "kmedoids"
+)
+
+// ClusterConfig opisuje grupowanie użytkowników według ich ocen.
+type ClusterConfig struct {
+	K         int              // liczba grup
+	Algorithm ClusterAlgorithm // KMeans albo KMedoids
+	Metric    Metric           // miara podobieństwa, odległość to 1 - podobieństwo
+	MaxIter   int              // górna granica liczby iteracji, gdy grupy nie przestają się zmieniać
+	Seed      int64            // ziarno generatora losowego; to samo ziarno daje te same grupy
+}
+
+// DefaultClusterConfig zwraca ustawienia domyślne: k-średnich z inicjalizacją
+// k-means++ i korelacją Pearsona.
+func DefaultClusterConfig() ClusterConfig {
+	return ClusterConfig{K: 2, Algorithm: KMeans, Metric: Pearson, MaxIter: 100, Seed: 1}
+}
+
+// Clustering to wynik grupowania użytkowników.
+type Clustering struct {
+	Clusters   [][]int              // identyfikatory użytkowników każdej grupy, rosnąco
+	Assignment map[int]int          // numer grupy każdego użytkownika
+	Centroids  []map[string]float64 // środki grup: średnie oceny (k-means) albo oceny medoidu
+	Medoids    []int                // medoidy grup, tylko dla KMedoids
+	Iterations int                  // liczba wykonanych iteracji
+	Converged  bool                 // czy przydział do grup przestał się zmieniać przed MaxIter
+	Cycled     bool                 // czy przydział wrócił do wcześniejszego stanu zamiast się ustalić
+}
+
+// ClusterUsers dzieli użytkowników na cfg.K grup na podstawie ich ocen
+// (użytkownik -> film -> ocena). Początkowe środki są wybierane metodą k-means++:
+// każdy kolejny z prawdopodobieństwem proporcjonalnym do kwadratu odległości
+// od najbliższego już wybranego. Iteracje kończą się, gdy przydział użytkowników
+// do grup się nie zmienia albo wraca do stanu z wcześniejszej iteracji: przy
+// odległościach liczonych tylko na wspólnych filmach środek grupy nie musi
+// minimalizować odległości i k-średnich może krążyć między kilkoma przydziałami.
+func ClusterUsers(ratings map[int]map[string]float64, cfg ClusterConfig) (*Clustering, error) {
+	users := sortedKeys(ratings)
+	if cfg.K < 1 || cfg.K > len(users) {
+		return nil, fmt.Errorf("cannot split %d users into %d clusters", len(users), cfg.K)
+	}
+	if cfg.Algorithm != KMeans && cfg.Algorithm != KMedoids {
+		return nil, fmt.Errorf("unknown clustering algorithm %q (want kmeans or kmedoids)", cfg.Algorithm)
+	}
+	if _, err := ParseMetric(string(cfg.Metric)); err != nil {
+		return nil, err
+	}
+	maxIter := cfg.MaxIter
+	if maxIter < 1 {
+		maxIter = 100
+	}
+	rng := rand.New(rand.NewSource(cfg.Seed))
+	means := itemMeans(ratings)
+	dist := func(a, b map[string]float64) float64 { return distance(cfg.Metric, a, b, means) }
+
+	seeds := seedCenters(users, ratings, cfg.K, dist, rng)
+	c := &Clustering{Centroids: make([]map[string]float64, cfg.K)}
+	for j, user := range seeds {
+		c.Centroids[j] = ratings[user]
+	}
+	if cfg.Algorithm == KMedoids {
+		c.Medoids = seeds
+	}
+
+	assignment := make([]int, len(users))
+	for i := range assignment {
+		assignment[i] = -1
+	}
+	visited := make(map[string]bool)
+	for c.Iterations < maxIter {
+		c.Iterations++
+		previous := slices.Clone(assignment)
+		members := make([][]int, cfg.K)
+		for i, user := range users {
+			best, bestDist := 0, math.Inf(1)
+			for j, centroid := range c.Centroids {
+				if d := dist(ratings[user], centroid); d < bestDist {
+					best, bestDist = j, d
+				}
+			}
+			assignment[i] = best
+			members[best] = append(members[best], user)
+		}
+		for j := range members {
+			if len(members[j]) == 0 {
+				// pusta grupa przejmuje użytkownika najdalszego od środka swojej grupy
+				far, farDist := 0, -1.0
+				for i, user := range users {
+					if d := dist(ratings[user], c.Centroids[assignment[i]]); d > farDist && len(members[assignment[i]]) > 1 {
+						far, farDist = i, d
+					}
+				}
+				members[assignment[far]] = remove(members[assignment[far]], users[far])
+				assignment[far] = j
+				members[j] = []int{users[far]}
+			}
+		}
+		if slices.Equal(assignment, previous) {
+			c.Converged = true
+			break
+		}
+		state := fmt.Sprint(assignment)
+		if visited[state] {
+			c.Cycled = true
+			break
+		}
+		visited[state] = true
+		for j, group := range members {
+			if cfg.Algorithm == KMedoids {
+				c.Medoids[j] = medoid(group, ratings, dist)
+				c.Centroids[j] = ratings[c.Medoids[j]]
+			} else {
+				c.Centroids[j] = meanRatings(group, ratings)
+			}
+		}
+	}
+
+	c.Clusters = make([][]int, cfg.K)
+	c.Assignment = make(map[int]int, len(users))
+	for i, user := range users {
+		c.Clusters[assignment[i]] = append(c.Clusters[assignment[i]], user)
+		c.Assignment[user] = assignment[i]
+	}
+	return c, nil
+}
+
+// seedCenters wybiera k początkowych środków metodą k-means++.
+func seedCenters(users []int, ratings map[int]map[string]float64, k int, dist func(a, b map[string]float64) float64, rng *rand.Rand) []int {
+	chosen := []int{users[rng.Intn(len(users))]}
+	for len(chosen) < k {
+		weights := make([]float64, len(users))
+		total := 0.0
+		for i, user := range users {
+			nearest := math.Inf(1)
+			for _, center := range chosen {
+				nearest = math.Min(nearest, dist(ratings[user], ratings[center]))
+			}
+			if contains(chosen, user) {
+				nearest = 0
+			}
+			weights[i] = nearest * nearest
+			total += weights[i]
+		}
+		next := -1
+		if total > 0 {
+			r := rng.Float64() * total
+			for i, w := range weights {
+				if r -= w; r < 0 && w > 0 {
+					next = users[i]
+					break
+				}
+			}
+		}
+		if next < 0 {
+			// wszyscy pozostali są w odległości 0 od wybranych: dowolny niewybrany
+			for _, i := range rng.Perm(len(users)) {
+				if !contains(chosen, users[i]) {
+					next = users[i]
+					break
+				}
+			}
+		}
+		chosen = append(chosen, next)
+	}
+	return chosen
+}
+
+// meanRatings zwraca średnią ocenę każdego filmu wśród użytkowników grupy,
+// licząc tylko tych, którzy go ocenili.
+func meanRatings(group []int, ratings map[int]map[string]float64) map[string]float64 {
+	vectors := make(map[int]map[string]float64, len(group))
+	for _, user := range group {
+		vectors[user] = ratings[user]
+	}
+	return itemMeans(vectors)
+}
+
+// medoid zwraca członka grupy o najmniejszej sumie odległości do pozostałych członków.
+func medoid(group []int, ratings map[int]map[string]float64, dist func(a, b map[string]float64) float64) int {
+	best, bestSum := group[0], math.Inf(1)
+	for _, candidate := range group {
+		sum := 0.0
+		for _, user := range group {
+			sum += dist(ratings[candidate], ratings[user])
+		}
+		if sum < bestSum {
+			best, bestSum = candidate, sum
+		}
+	}
+	return best
+}
+
+// contains sprawdza, czy lista zawiera użytkownika.
+func contains(users []int, user int) bool {
+	for _, u := range users {
+		if u == user {
+			return true
+		}
+	}
+	return false
+}
+
+// remove zwraca listę bez podanego użytkownika.
+func remove(users []int, user int) []int {
+	out := users[:0:0]
+	for _, u := range users {
+		if u != user {
+			out = append(out, u)
+		}
+	}
+	return out
+}
+
+// ClusterRecommender przewiduje ocenę filmu jako średnią ocen, które wystawili mu
+// pozostali członkowie grupy użytkownika.
+type ClusterRecommender struct {
+	Clustering *Clustering
+	ratings    map[int]map[string]float64
+}
+
+// NewClusterRecommender grupuje użytkowników według ich ocen.
+func NewClusterRecommender(mr *MovieRatings, cfg ClusterConfig) (*ClusterRecommender, error) {
+	ratings := mr.userRatings()
+	clustering, err := ClusterUsers(ratings, cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &ClusterRecommender{Clustering: clustering, ratings: ratings}, nil
+}
+
+// Predict zwraca średnią ocenę filmu wśród pozostałych członków grupy użytkownika.
+func (c *ClusterRecommender) Predict(personID int, title string) (float64, error) {
+	cluster, ok := c.Clustering.Assignment[personID]
+	if !ok {
+		return 0, fmt.Errorf("%w %d", ErrUnknownUser, personID)
+	}
+	sum, count := 0.0, 0
+	for _, user := range c.Clustering.Clusters[cluster] {
+		if rating, ok := c.ratings[user][title]; ok && user != personID {
+			sum += rating
+			count++
+		}
+	}
+	if count == 0 {
+		return 0, fmt.Errorf("%w: nobody in the cluster of user %d rated %q", ErrNoPrediction, personID, title)
+	}
+	return sum / float64(count), nil
+}
+
+// Explain wskazuje członków grupy, którzy ocenili film.
+func (c *ClusterRecommender) Explain(personID int, title string) (string, error) {
+	cluster, ok := c.Clustering.Assignment[personID]
+	if !ok {
+		return "", fmt.Errorf("%w %d", ErrUnknownUser, personID)
+	}
+	var users, ratings []string
+	for _, user := range c.Clustering.Clusters[cluster] {
+		if rating, ok := c.ratings[user][title]; ok && user != personID {
+			users = append(users, "user "+strconv.Itoa(user))
+			ratings = append(ratings, strconv.FormatFloat(rating, 'g', -1, 64))
+		}
+	}
+	return fmt.Sprintf("because %s from your cluster rated it %s", joinAnd(users), joinAnd(ratings)), nil
+}
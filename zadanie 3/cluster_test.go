@@ -0,0 +1,88 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+// tasteRatings to dwie wyraźne grupy: użytkownicy 1-3 lubią "A" i "B",
+// a użytkownicy 4-6 "C" i "D".
+func tasteRatings() map[int]map[string]float64 {
+	return map[int]map[string]float64{
+		1: {"A": 9, "B": 8, "C": 2, "D": 1},
+		2: {"A": 8, "B": 9, "C": 1, "D": 2},
+		3: {"A": 9, "B": 7, "C": 3, "D": 1},
+		4: {"A": 1, "B": 2, "C": 9, "D": 8},
+		5: {"A": 2, "B": 1, "C": 8, "D": 9},
+		6: {"A": 1, "B": 3, "C": 7, "D": 9},
+	}
+}
+
+func TestClusterUsers(t *testing.T) {
+	for _, algorithm := range []ClusterAlgorithm{KMeans, KMedoids} {
+		for seed := int64(1); seed <= 5; seed++ {
+			cfg := ClusterConfig{K: 2, Algorithm: algorithm, Metric: Pearson, MaxIter: 100, Seed: seed}
+			c, err := ClusterUsers(tasteRatings(), cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			groups := [][]int{c.Clusters[0], c.Clusters[1]}
+			if groups[0][0] != 1 {
+				groups[0], groups[1] = groups[1], groups[0]
+			}
+			if want := [][]int{{1, 2, 3}, {4, 5, 6}}; !reflect.DeepEqual(groups, want) {
+				t.Errorf("%s, seed %d: expected %v, found %v", algorithm, seed, want, c.Clusters)
+			}
+			if !c.Converged {
+				t.Errorf("%s, seed %d: expected the clustering to converge", algorithm, seed)
+			}
+
+			again, _ := ClusterUsers(tasteRatings(), cfg)
+			if !reflect.DeepEqual(c, again) {
+				t.Errorf("%s, seed %d: expected the same clustering for the same seed", algorithm, seed)
+			}
+		}
+	}
+}
+
+func TestClusterUsersBounds(t *testing.T) {
+	for _, k := range []int{0, 7} {
+		cfg := DefaultClusterConfig()
+		cfg.K = k
+		if _, err := ClusterUsers(tasteRatings(), cfg); err == nil {
+			t.Errorf("expected an error for K = %d and 6 users", k)
+		}
+	}
+	cfg := DefaultClusterConfig()
+	cfg.Algorithm = "dbscan"
+	if _, err := ClusterUsers(tasteRatings(), cfg); err == nil {
+		t.Error("expected an error for an unknown algorithm")
+	}
+	cfg = DefaultClusterConfig()
+	cfg.Metric = "euclidean"
+	if _, err := ClusterUsers(tasteRatings(), cfg); err == nil {
+		t.Error("expected an error for an unknown metric")
+	}
+}
+
+func TestClusterUsersFillsEmptyClusters(t *testing.T) {
+	// Użytkownicy 1 i 2 mają te same oceny, więc obaj trafiają do pierwszej
+	// z ich dwóch identycznych grup, a druga zostaje pusta i musi kogoś przejąć.
+	ratings := map[int]map[string]float64{
+		1: {"A": 9, "B": 1, "C": 5},
+		2: {"A": 9, "B": 1, "C": 5},
+		3: {"A": 1, "B": 9, "C": 5},
+	}
+	for _, algorithm := range []ClusterAlgorithm{KMeans, KMedoids} {
+		cfg := ClusterConfig{K: 3, Algorithm: algorithm, Metric: Pearson, MaxIter: 100, Seed: 1}
+		c, err := ClusterUsers(ratings, cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for j, group := range c.Clusters {
+			if len(group) != 1 {
+				t.Errorf("%s: expected one user in cluster %d, found %v", algorithm, j, c.Clusters)
+			}
+		}
+	}
+}
@@ -0,0 +1,317 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// ContentWeighting to sposób ważenia cech filmu w jego profilu.
+type ContentWeighting string
+
+const (
+	// TFIDF waży cechę liczbą wystąpień razy log(liczba filmów / liczba filmów z cechą),
+	// więc rzadkie cechy (np. reżyser) znaczą więcej niż częste (np. Drama).
+	TFIDF ContentWeighting = "tfidf"
+	// OneHot nadaje każdej cesze filmu wagę 1.
+	OneHot ContentWeighting = "onehot"
+)
+
+// ContentConfig opisuje profile filmów budowane z metadanych.
+type ContentConfig struct {
+	Weighting ContentWeighting
+	Plot      bool // czy słowa opisu fabuły są cechami obok gatunków, reżyserów i aktorów
+}
+
+// DefaultContentConfig zwraca profile TF-IDF z gatunków, reżyserów, aktorów i fabuły.
+func DefaultContentConfig() ContentConfig {
+	return ContentConfig{Weighting: TFIDF, Plot: true}
+}
+
+// movieFeatures zamienia metadane na cechy z przedrostkiem pola, np. "genre:Drama"
+// albo "plot:heist". Słowa fabuły krótsze niż 4 litery są pomijane jako mało znaczące.
+func movieFeatures(d *MovieDetails, plot bool) map[string]float64 {
+	features := make(map[string]float64)
+	for _, field := range []struct{ prefix, value string }{
+		{"genre", d.Genre}, {"director", d.Director}, {"actor", d.Actors},
+	} {
+		for _, name := range strings.Split(field.value, ",") {
+			if name = strings.TrimSpace(name); name != "" && name != "N/A" {
+				features[field.prefix+":"+name]++
+			}
+		}
+	}
+	if plot {
+		for _, word := range strings.FieldsFunc(strings.ToLower(d.Plot), func(r rune) bool { return !unicode.IsLetter(r) }) {
+			if len([]rune(word)) >= 4 {
+				features["plot:"+word]++
+			}
+		}
+	}
+	return features
+}
+
+// featureName zwraca czytelną nazwę cechy do uzasadnień.
+func featureName(feature string) string {
+	prefix, name, _ := strings.Cut(feature, ":")
+	if prefix == "plot" {
+		return fmt.Sprintf("stories about %q", name)
+	}
+	return name
+}
+
+// ContentBased poleca filmy podobne do tych, które użytkownik ocenił wysoko.
+// Profil użytkownika to suma profili ocenionych filmów ważona oceną pomniejszoną
+// o środek skali, więc filmy ocenione nisko odsuwają go od swoich cech. Predict
+// zwraca kosinus profilu użytkownika i profilu filmu z przedziału [-1, 1].
+// Wystarczy jedna ocena, żeby zbudować profil.
+type ContentBased struct {
+	items    map[string]map[string]float64 // tytuł -> cecha -> waga, znormalizowane do długości 1
+	users    map[int]map[string]float64    // użytkownik -> cecha -> waga
+	ratings  map[int]map[string]float64
+	midpoint float64
+	Missing  []string // tytuły bez metadanych, których nie da się polecić
+}
+
+// NewContentBased pobiera metadane ocenionych filmów i buduje profile filmów i użytkowników.
+// Filmy, których dostawca nie zna albo nie może pobrać, trafiają do Missing; ostrzeżenie
+// o nich wypisuje wywołujący (missingMetadata). Brak metadanych wszystkich filmów jest błędem.
+func NewContentBased(mr *MovieRatings, metadata MetadataProvider, cfg ContentConfig) (*ContentBased, error) {
+	if metadata == nil {
+		return nil, errors.New("content-based recommendations need a metadata provider")
+	}
+	if cfg.Weighting != TFIDF && cfg.Weighting != OneHot {
+		return nil, fmt.Errorf("unknown content weighting %q (want tfidf or onehot)", cfg.Weighting)
+	}
+	m := &ContentBased{items: make(map[string]map[string]float64), ratings: mr.userRatings()}
+	lo, hi := ratingRange(mr.Ratings)
+	m.midpoint = (lo + hi) / 2
+
+	frequency := make(map[string]int)
+	titles := mr.Titles()
+	var firstErr error
+	for _, title := range titles {
+		imdbID, err := mr.GetIMDBIDByTitle(title)
+		var details *MovieDetails
+		if err == nil {
+			details, err = metadata.MovieDetails(imdbID)
+		}
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			m.Missing = append(m.Missing, title)
+			continue
+		}
+		m.items[title] = movieFeatures(details, cfg.Plot)
+		for feature := range m.items[title] {
+			frequency[feature]++
+		}
+	}
+	if len(m.items) == 0 {
+		return nil, fmt.Errorf("no metadata for any of %d movies: %v", len(titles), firstErr)
+	}
+	for _, features := range m.items {
+		norm := 0.0
+		for feature, count := range features {
+			if cfg.Weighting == OneHot {
+				features[feature] = 1
+			} else {
+				features[feature] = count * math.Log(float64(len(m.items))/float64(frequency[feature]))
+			}
+			norm += features[feature] * features[feature]
+		}
+		for feature := range features {
+			if norm > 0 {
+				features[feature] /= math.Sqrt(norm)
+			}
+		}
+	}
+
+	m.users = make(map[int]map[string]float64, len(m.ratings))
+	for user, movies := range m.ratings {
+		profile := make(map[string]float64)
+		for title, rating := range movies {
+			for feature, w := range m.items[title] {
+				profile[feature] += (rating - m.midpoint) * w
+			}
+		}
+		m.users[user] = profile
+	}
+	return m, nil
+}
+
+// Predict zwraca podobieństwo filmu title do profilu użytkownika personID.
+func (m *ContentBased) Predict(personID int, title string) (float64, error) {
+	profile, ok := m.users[personID]
+	if !ok {
+		return 0, fmt.Errorf("%w %d", ErrUnknownUser, personID)
+	}
+	item, ok := m.items[title]
+	if !ok {
+		return 0, fmt.Errorf("%w: no metadata for %q", ErrNoPrediction, title)
+	}
+	return profileCosine(profile, item), nil
+}
+
+// profileCosine liczy kosinus dwóch profili. W odróżnieniu od similarity brak
+// cechy w profilu znaczy tyle co waga 0, więc normy obejmują wszystkie cechy.
+func profileCosine(a, b map[string]float64) float64 {
+	dot, normA, normB := 0.0, 0.0, 0.0
+	for key, x := range a {
+		dot += x * b[key]
+		normA += x * x
+	}
+	for _, y := range b {
+		normB += y * y
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / math.Sqrt(normA*normB)
+}
+
+// Explain wskazuje dwie cechy filmu, które najbardziej zbliżają go do gustu
+// użytkownika (albo najbardziej oddalają, gdy film nie pasuje).
+func (m *ContentBased) Explain(personID int, title string) (string, error) {
+	score, err := m.Predict(personID, title)
+	if err != nil {
+		return "", err
+	}
+	sign := 1.0
+	if score < 0 {
+		sign = -1
+	}
+	profile, item := m.users[personID], m.items[title]
+	features := sortedKeys(item)
+	sort.SliceStable(features, func(i, j int) bool {
+		return sign*profile[features[i]]*item[features[i]] > sign*profile[features[j]]*item[features[j]]
+	})
+	var names []string
+	for _, feature := range features {
+		if len(names) == 2 || sign*profile[feature]*item[feature] <= 0 {
+			break
+		}
+		names = append(names, featureName(feature))
+	}
+	if len(names) == 0 {
+		return "nothing in common with movies you rated", nil
+	}
+	if sign > 0 {
+		return "because you like " + joinAnd(names), nil
+	}
+	return "because you dislike " + joinAnd(names), nil
+}
+
+// missingMetadata zwraca tytuły bez metadanych pominięte przez model treściowy r
+// albo część treściową hybrydy; dla pozostałych modeli nil.
+func missingMetadata(r Recommender) []string {
+	switch m := r.(type) {
+	case *ContentBased:
+		return m.Missing
+	case *Hybrid:
+		return m.Content.Missing
+	}
+	return nil
+}
+
+// HybridConfig opisuje łączenie filtrowania kolaboratywnego z profilami treści.
+type HybridConfig struct {
+	Collaborative Strategy // strategia przewidująca oceny: cluster, svd, user-knn albo item-knn
+	// ColdStart to liczba ocen użytkownika, przy której obie części mają równą wagę.
+	// Waga części kolaboratywnej to n / (n + ColdStart) dla użytkownika z n ocenami,
+	// więc nowi użytkownicy dostają głównie rekomendacje według treści.
+	ColdStart float64
+}
+
+// DefaultHybridConfig zwraca połączenie SVD z treścią, równoważne przy 10 ocenach.
+func DefaultHybridConfig() HybridConfig {
+	return HybridConfig{Collaborative: StrategySVD, ColdStart: 10}
+}
+
+// Hybrid łączy przewidywaną ocenę modelu kolaboratywnego z podobieństwem treści
+// przeliczonym na skalę ocen. Gdy jedna część nie ma przewidywania, decyduje druga.
+type Hybrid struct {
+	Collaborative Recommender
+	Content       *ContentBased
+	cfg           HybridConfig
+	min, max      float64
+}
+
+// NewHybrid buduje obie części na ocenach mr, część treściową z metadanych opts.Metadata.
+func NewHybrid(mr *MovieRatings, opts Options) (*Hybrid, error) {
+	cfg := opts.Hybrid
+	switch cfg.Collaborative {
+	case StrategyALS, StrategyContent, StrategyHybrid:
+		return nil, fmt.Errorf("hybrid needs a rating-predicting collaborative strategy, not %q", cfg.Collaborative)
+	}
+	if cfg.ColdStart < 0 {
+		return nil, fmt.Errorf("invalid cold start weight %g", cfg.ColdStart)
+	}
+	collaborative, err := NewRecommender(cfg.Collaborative, mr, opts)
+	if err != nil {
+		return nil, err
+	}
+	content, err := NewContentBased(mr, opts.Metadata, opts.Content)
+	if err != nil {
+		return nil, err
+	}
+	h := &Hybrid{Collaborative: collaborative, Content: content, cfg: cfg}
+	h.min, h.max = ratingRange(mr.Ratings)
+	return h, nil
+}
+
+// weight zwraca wagę części kolaboratywnej dla użytkownika.
+func (h *Hybrid) weight(personID int) float64 {
+	n := float64(len(h.Content.ratings[personID]))
+	if n+h.cfg.ColdStart == 0 {
+		return 1
+	}
+	return n / (n + h.cfg.ColdStart)
+}
+
+// Predict zwraca ważoną średnią oceny kolaboratywnej i oceny według treści.
+func (h *Hybrid) Predict(personID int, title string) (float64, error) {
+	collaborative, errCF := h.Collaborative.Predict(personID, title)
+	if errCF != nil && !errors.Is(errCF, ErrNoPrediction) {
+		return 0, errCF
+	}
+	similarity, errCB := h.Content.Predict(personID, title)
+	if errCB != nil && !errors.Is(errCB, ErrNoPrediction) {
+		return 0, errCB
+	}
+	content := h.min + (similarity+1)/2*(h.max-h.min)
+	switch {
+	case errCF != nil && errCB != nil:
+		return 0, fmt.Errorf("%w: neither ratings nor metadata for %q", ErrNoPrediction, title)
+	case errCF != nil:
+		return content, nil
+	case errCB != nil:
+		return collaborative, nil
+	}
+	w := h.weight(personID)
+	return w*collaborative + (1-w)*content, nil
+}
+
+// Explain podaje uzasadnienie części, która ma większą wagę dla użytkownika,
+// a gdy jej brak, uzasadnienie drugiej części.
+func (h *Hybrid) Explain(personID int, title string) (string, error) {
+	parts := []Recommender{h.Collaborative, h.Content}
+	if h.weight(personID) < 0.5 {
+		parts[0], parts[1] = parts[1], parts[0]
+	}
+	for _, part := range parts {
+		explainer, ok := part.(Explainer)
+		if !ok {
+			continue
+		}
+		if _, err := part.Predict(personID, title); err != nil {
+			continue
+		}
+		return explainer.Explain(personID, title)
+	}
+	return "", nil
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+// surveyMetadata zna gatunki filmów z surveyRatings poza F: A-C to akcja, D i E dramaty.
+func surveyMetadata() FixtureProvider {
+	genres := map[string]string{"A": "Action", "B": "Action, Thriller", "C": "Action", "D": "Drama", "E": "Drama, Romance"}
+	fixtures := make(FixtureProvider)
+	for title, genre := range genres {
+		id := "tt000000" + title
+		fixtures[id] = &MovieDetails{IMDBID: id, Title: title, Genre: genre}
+	}
+	return fixtures
+}
+
+func TestContentBasedMissingMetadata(t *testing.T) {
+	data := surveyRatings()
+	m, err := NewContentBased(data, surveyMetadata(), ContentConfig{Weighting: OneHot})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(m.Missing) != "[F]" || fmt.Sprint(missingMetadata(m)) != "[F]" {
+		t.Errorf("expected F to be missing, found %v", m.Missing)
+	}
+	// użytkownik 1 lubi akcję, więc C jest mu bliższy niż dramat E
+	c, errC := m.Predict(1, "C")
+	e, errE := m.Predict(1, "E")
+	if errC != nil || errE != nil || c <= e {
+		t.Errorf("expected C (%.3f) above E (%.3f) for user 1, found errors %v, %v", c, e, errC, errE)
+	}
+
+	opts := DefaultOptions()
+	opts.Metadata = surveyMetadata()
+	h, err := NewHybrid(data, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(missingMetadata(h)) != "[F]" {
+		t.Errorf("expected the hybrid to report F as missing, found %v", missingMetadata(h))
+	}
+	if missing := missingMetadata(&SVD{}); missing != nil {
+		t.Errorf("expected no missing titles for svd, found %v", missing)
+	}
+
+	if _, err := NewContentBased(data, FixtureProvider{}, DefaultContentConfig()); err == nil {
+		t.Error("expected an error without metadata for any movie")
+	}
+	if _, err := NewContentBased(data, nil, DefaultContentConfig()); err == nil {
+		t.Error("expected an error without a metadata provider")
+	}
+}
@@ -0,0 +1,237 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+)
+
+// SplitMethod to sposób podziału ocen każdego użytkownika na uczące i testowe.
+type SplitMethod string
+
+const (
+	// LeaveOneOut odkłada do testu jedną losową ocenę każdego użytkownika.
+	LeaveOneOut SplitMethod = "leave-one-out"
+	// Temporal odkłada do testu ostatnie oceny użytkownika w kolejności wierszy
+	// pliku, która odpowiada kolejności wypełniania ankiety.
+	Temporal SplitMethod = "temporal"
+	// RandomHoldout odkłada do testu losową część ocen użytkownika.
+	RandomHoldout SplitMethod = "random"
+)
+
+// SplitConfig opisuje podział ocen na uczące i testowe.
+type SplitConfig struct {
+	Method       SplitMethod
+	TestFraction float64 // część ocen użytkownika w teście dla Temporal i RandomHoldout
+	Seed         int64   // ziarno losowania dla LeaveOneOut i RandomHoldout
+}
+
+// DefaultSplitConfig zwraca podział leave-one-out.
+func DefaultSplitConfig() SplitConfig {
+	return SplitConfig{Method: LeaveOneOut, TestFraction: 0.2, Seed: 1}
+}
+
+// Split dzieli oceny osobno dla każdego użytkownika. Każdy użytkownik zachowuje
+// w danych uczących co najmniej jedną ocenę, więc użytkownicy z jedną oceną nie
+// trafiają do testu. Kolejność ocen w obu częściach jest taka jak w ratings.
+func Split(ratings []MovieRating, cfg SplitConfig) ([]MovieRating, []MovieRating, error) {
+	if cfg.Method != LeaveOneOut && (cfg.TestFraction <= 0 || cfg.TestFraction >= 1) {
+		return nil, nil, fmt.Errorf("test fraction %g is not between 0 and 1", cfg.TestFraction)
+	}
+	rng := rand.New(rand.NewSource(cfg.Seed))
+	byUser := make(map[int][]int)
+	for i, r := range ratings {
+		byUser[r.PersonID] = append(byUser[r.PersonID], i)
+	}
+	test := make(map[int]bool)
+	for _, user := range sortedKeys(byUser) {
+		rows := byUser[user]
+		if len(rows) < 2 {
+			continue
+		}
+		var held []int
+		switch cfg.Method {
+		case LeaveOneOut:
+			held = []int{rows[rng.Intn(len(rows))]}
+		case Temporal, RandomHoldout:
+			n := min(len(rows)-1, max(1, int(math.Round(cfg.TestFraction*float64(len(rows))))))
+			if cfg.Method == Temporal {
+				held = rows[len(rows)-n:]
+			} else {
+				for _, k := range rng.Perm(len(rows))[:n] {
+					held = append(held, rows[k])
+				}
+			}
+		default:
+			return nil, nil, fmt.Errorf("unknown split method %q (want leave-one-out, temporal or random)", cfg.Method)
+		}
+		for _, i := range held {
+			test[i] = true
+		}
+	}
+	var train, held []MovieRating
+	for i, r := range ratings {
+		if test[i] {
+			held = append(held, r)
+		} else {
+			train = append(train, r)
+		}
+	}
+	return train, held, nil
+}
+
+// EvalConfig opisuje miary rankingowe.
+type EvalConfig struct {
+	K         int     // długość listy rekomendacji
+	Threshold float64 // najniższa ocena testowa, przy której film uznaje się za trafny
+}
+
+// DefaultEvalConfig zwraca listy 5 filmów i próg trafności 7.
+func DefaultEvalConfig() EvalConfig {
+	return EvalConfig{K: 5, Threshold: 7}
+}
+
+// Report to wynik oceny jednej strategii.
+type Report struct {
+	Strategy Strategy
+
+	// Przewidywanie ocen, liczone na ocenach testowych, dla których model ma przewidywanie.
+	// Dla ALS i content, które nie przewidują ocen, pola są puste, a RatingMetrics jest false.
+	RatingMetrics bool
+	RMSE, MAE     float64
+	Predicted     int // liczba ocen testowych z przewidywaniem
+	Missing       int // liczba ocen testowych bez przewidywania (ErrNoPrediction)
+
+	// Ranking, uśredniany po użytkownikach, którzy mają w teście trafny film.
+	Precision, Recall, MAP, NDCG float64
+	Users                        int
+	Coverage                     float64 // część katalogu, która trafiła na czyjąś listę
+	Novelty                      float64 // średnie -log2 popularności polecanych filmów; większe to mniej oczywiste
+
+	// MissingMetadata to tytuły pominięte przez strategie content i hybrid z braku metadanych.
+	MissingMetadata []string
+}
+
+// Evaluate uczy model strategii na danych uczących i mierzy go na testowych.
+// Lista rekomendacji użytkownika powstaje tak jak w MovieRatings.Recommend:
+// z filmów, których nie ocenił w danych uczących.
+func Evaluate(strategy Strategy, train, test []MovieRating, opts Options, cfg EvalConfig) (*Report, error) {
+	if cfg.K < 1 {
+		return nil, fmt.Errorf("invalid list length %d", cfg.K)
+	}
+	if len(test) == 0 {
+		return nil, errors.New("no ratings to test on")
+	}
+	trainRatings := &MovieRatings{Ratings: train}
+	model, err := NewRecommender(strategy, trainRatings, opts)
+	if err != nil {
+		return nil, err
+	}
+	report := &Report{Strategy: strategy, RatingMetrics: strategy != StrategyALS && strategy != StrategyContent, MissingMetadata: missingMetadata(model)}
+	if err := report.measure(model, trainRatings, test, cfg); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// measure wypełnia miary raportu dla modelu nauczonego na trainRatings. Miary
+// przewidywania ocen liczy tylko wtedy, gdy RatingMetrics jest true.
+func (report *Report) measure(model Recommender, trainRatings *MovieRatings, test []MovieRating, cfg EvalConfig) error {
+	sumSq, sumAbs := 0.0, 0.0
+	relevant := make(map[int]map[string]bool)
+	for _, r := range test {
+		if r.Rating >= cfg.Threshold {
+			if relevant[r.PersonID] == nil {
+				relevant[r.PersonID] = make(map[string]bool)
+			}
+			relevant[r.PersonID][r.MovieTitle] = true
+		}
+		if !report.RatingMetrics {
+			continue
+		}
+		x, err := model.Predict(r.PersonID, r.MovieTitle)
+		if errors.Is(err, ErrNoPrediction) {
+			report.Missing++
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		sumSq += (x - r.Rating) * (x - r.Rating)
+		sumAbs += math.Abs(x - r.Rating)
+		report.Predicted++
+	}
+	if report.Predicted > 0 {
+		report.RMSE = math.Sqrt(sumSq / float64(report.Predicted))
+		report.MAE = sumAbs / float64(report.Predicted)
+	}
+
+	users := trainRatings.userRatings()
+	catalogue := trainRatings.Titles()
+	popularity := make(map[string]float64)
+	for _, movies := range users {
+		for title := range movies {
+			popularity[title] += 1 / float64(len(users))
+		}
+	}
+	recommended := make(map[string]bool)
+	novelty, listed := 0.0, 0
+	for _, user := range sortedKeys(relevant) {
+		top, _, err := trainRatings.Recommend(model, user, cfg.K)
+		if err != nil {
+			return err
+		}
+		hits, precisionSum, dcg := 0, 0.0, 0.0
+		for i, r := range top {
+			recommended[r.Title] = true
+			novelty -= math.Log2(popularity[r.Title])
+			listed++
+			if relevant[user][r.Title] {
+				hits++
+				precisionSum += float64(hits) / float64(i+1)
+				dcg += 1 / math.Log2(float64(i+2))
+			}
+		}
+		ideal := 0.0
+		for i := 0; i < min(cfg.K, len(relevant[user])); i++ {
+			ideal += 1 / math.Log2(float64(i+2))
+		}
+		report.Precision += float64(hits) / float64(cfg.K)
+		report.Recall += float64(hits) / float64(len(relevant[user]))
+		report.MAP += precisionSum / float64(min(cfg.K, len(relevant[user])))
+		report.NDCG += dcg / ideal
+		report.Users++
+	}
+	if report.Users > 0 {
+		n := float64(report.Users)
+		report.Precision /= n
+		report.Recall /= n
+		report.MAP /= n
+		report.NDCG /= n
+	}
+	if listed > 0 {
+		report.Novelty = novelty / float64(listed)
+	}
+	report.Coverage = float64(len(recommended)) / float64(len(catalogue))
+	return nil
+}
+
+// PrintReports wypisuje tabelę porównującą strategie.
+func PrintReports(w io.Writer, reports []*Report, cfg EvalConfig) {
+	fmt.Fprintf(w, "%-10s %6s %6s %9s %6s %6s %6s %6s %6s %8s %8s\n",
+		"strategy", "RMSE", "MAE", "predicted", fmt.Sprintf("P@%d", cfg.K), fmt.Sprintf("R@%d", cfg.K),
+		"MAP", "NDCG", "users", "coverage", "novelty")
+	for _, r := range reports {
+		rmse, mae, predicted := "n/a", "n/a", "n/a"
+		if r.RatingMetrics {
+			predicted = fmt.Sprintf("%d/%d", r.Predicted, r.Predicted+r.Missing)
+		}
+		if r.Predicted > 0 {
+			rmse, mae = fmt.Sprintf("%.3f", r.RMSE), fmt.Sprintf("%.3f", r.MAE)
+		}
+		fmt.Fprintf(w, "%-10s %6s %6s %9s %6.3f %6.3f %6.3f %6.3f %6d %8.3f %8.3f\n",
+			r.Strategy, rmse, mae, predicted, r.Precision, r.Recall, r.MAP, r.NDCG, r.Users, r.Coverage, r.Novelty)
+	}
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+// splitRatings to oceny trzech użytkowników przeplecione w kolejności ankiety:
+// użytkownik 1 ma pięć ocen, użytkownik 2 dwie, a użytkownik 3 jedną.
+func splitRatings() []MovieRating {
+	return []MovieRating{
+		{PersonID: 1, MovieTitle: "A", Rating: 1},
+		{PersonID: 2, MovieTitle: "A", Rating: 2},
+		{PersonID: 1, MovieTitle: "B", Rating: 3},
+		{PersonID: 3, MovieTitle: "A", Rating: 4},
+		{PersonID: 1, MovieTitle: "C", Rating: 5},
+		{PersonID: 2, MovieTitle: "B", Rating: 6},
+		{PersonID: 1, MovieTitle: "D", Rating: 7},
+		{PersonID: 1, MovieTitle: "E", Rating: 8},
+	}
+}
+
+// heldPerUser liczy oceny każdego użytkownika w części testowej.
+func heldPerUser(test []MovieRating) map[int]int {
+	held := make(map[int]int)
+	for _, r := range test {
+		held[r.PersonID]++
+	}
+	return held
+}
+
+// checkPartition sprawdza, że train i test dzielą ratings bez zmiany kolejności.
+func checkPartition(t *testing.T, ratings, train, test []MovieRating) {
+	t.Helper()
+	if len(train)+len(test) != len(ratings) {
+		t.Fatalf("expected %d ratings in both parts, found %d + %d", len(ratings), len(train), len(test))
+	}
+	i, j := 0, 0
+	for _, r := range ratings {
+		switch {
+		case i < len(train) && train[i] == r:
+			i++
+		case j < len(test) && test[j] == r:
+			j++
+		default:
+			t.Fatalf("expected %v in order in one of the parts, found train %v and test %v", r, train, test)
+		}
+	}
+}
+
+func TestSplitTemporal(t *testing.T) {
+	ratings := splitRatings()
+	train, test, err := Split(ratings, SplitConfig{Method: Temporal, TestFraction: 0.4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Użytkownik 1 odkłada round(0.4·5) = 2 ostatnie oceny, użytkownik 2 co
+	// najmniej jedną, a użytkownik 3 z jedną oceną zostaje w całości w danych uczących.
+	want := []MovieRating{ratings[5], ratings[6], ratings[7]}
+	if !reflect.DeepEqual(test, want) {
+		t.Errorf("expected test %v, found %v", want, test)
+	}
+	checkPartition(t, ratings, train, test)
+}
+
+func TestSplitRandom(t *testing.T) {
+	ratings := splitRatings()
+	for _, cfg := range []SplitConfig{
+		{Method: LeaveOneOut, Seed: 7},
+		{Method: RandomHoldout, TestFraction: 0.4, Seed: 7},
+	} {
+		train, test, err := Split(ratings, cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkPartition(t, ratings, train, test)
+
+		want := map[int]int{1: 1, 2: 1}
+		if cfg.Method == RandomHoldout {
+			want[1] = 2
+		}
+		if held := heldPerUser(test); !reflect.DeepEqual(held, want) {
+			t.Errorf("%s: expected held ratings per user %v, found %v", cfg.Method, want, held)
+		}
+
+		train2, test2, _ := Split(ratings, cfg)
+		if !reflect.DeepEqual(train, train2) || !reflect.DeepEqual(test, test2) {
+			t.Errorf("%s: expected the same split for the same seed", cfg.Method)
+		}
+	}
+}
+
+func TestSplitErrors(t *testing.T) {
+	for _, cfg := range []SplitConfig{
+		{Method: Temporal, TestFraction: 0},
+		{Method: RandomHoldout, TestFraction: 1},
+		{Method: "weekly", TestFraction: 0.2},
+	} {
+		if _, _, err := Split(splitRatings(), cfg); err == nil {
+			t.Errorf("expected an error for %+v", cfg)
+		}
+	}
+}
+
+func TestMeasure(t *testing.T) {
+	// Użytkownik 1 zna "A", użytkownik 3 zna "B", a użytkownik 2 zna cały katalog
+	// i nie ma ocen testowych.
+	train := &MovieRatings{Ratings: []MovieRating{
+		{PersonID: 1, MovieTitle: "A", Rating: 9},
+		{PersonID: 2, MovieTitle: "A", Rating: 5},
+		{PersonID: 2, MovieTitle: "B", Rating: 6},
+		{PersonID: 2, MovieTitle: "C", Rating: 7},
+		{PersonID: 2, MovieTitle: "D", Rating: 8},
+		{PersonID: 2, MovieTitle: "E", Rating: 4},
+		{PersonID: 2, MovieTitle: "F", Rating: 3},
+		{PersonID: 3, MovieTitle: "B", Rating: 5},
+	}}
+	test := []MovieRating{
+		{PersonID: 1, MovieTitle: "C", Rating: 8},
+		{PersonID: 1, MovieTitle: "D", Rating: 2},
+		{PersonID: 3, MovieTitle: "A", Rating: 7},
+		{PersonID: 3, MovieTitle: "E", Rating: 9},
+		{PersonID: 3, MovieTitle: "F", Rating: 1},
+	}
+	// Ranking jest wspólny dla wszystkich: A > B > C > D > E, a "F" nie ma przewidywania.
+	model := stubRecommender{"A": 6, "B": 5, "C": 4, "D": 3, "E": 2}
+	report := &Report{RatingMetrics: true}
+	if err := report.measure(model, train, test, EvalConfig{K: 2, Threshold: 7}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Błędy przewidywań: C 4, D 1, A 1, E 7; "F" jest pominięty.
+	// Użytkownik 1 dostaje [B C] i trafny {C}: P 1/2, R 1, AP 1/2, NDCG 1/log2(3).
+	// Użytkownik 3 dostaje [A C] i trafne {A E}: P 1/2, R 1/2, AP 1/2, NDCG 1/(1+1/log2(3)).
+	// Polecone są A, B i C z sześciu filmów; A i B ma 2 z 3 użytkowników, C jeden.
+	want := &Report{
+		RatingMetrics: true,
+		RMSE:          math.Sqrt(67.0 / 4),
+		MAE:           13.0 / 4,
+		Predicted:     4,
+		Missing:       1,
+		Precision:     0.5,
+		Recall:        0.75,
+		MAP:           0.5,
+		NDCG:          (1/math.Log2(3) + 1/(1+1/math.Log2(3))) / 2,
+		Users:         2,
+		Coverage:      0.5,
+		Novelty:       (math.Log2(1.5) + math.Log2(3)) / 2,
+	}
+	got := []float64{report.RMSE, report.MAE, report.Precision, report.Recall, report.MAP, report.NDCG, report.Coverage, report.Novelty}
+	exp := []float64{want.RMSE, want.MAE, want.Precision, want.Recall, want.MAP, want.NDCG, want.Coverage, want.Novelty}
+	for i := range got {
+		if math.Abs(got[i]-exp[i]) > 1e-9 {
+			t.Errorf("expected %+v, found %+v", want, report)
+			break
+		}
+	}
+	if report.Predicted != want.Predicted || report.Missing != want.Missing || report.Users != want.Users {
+		t.Errorf("expected %d predicted, %d missing and %d users, found %d, %d and %d",
+			want.Predicted, want.Missing, want.Users, report.Predicted, report.Missing, report.Users)
+	}
+}
+
+func TestEvaluateErrors(t *testing.T) {
+	ratings := splitRatings()
+	if _, err := Evaluate(StrategyCluster, ratings, ratings[:1], DefaultOptions(), EvalConfig{K: 0, Threshold: 7}); err == nil {
+		t.Error("expected an error for an empty list")
+	}
+	if _, err := Evaluate(StrategyCluster, ratings, nil, DefaultOptions(), DefaultEvalConfig()); err == nil {
+		t.Error("expected an error for no test ratings")
+	}
+}
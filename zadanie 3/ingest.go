@@ -0,0 +1,302 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Skala ocen ankiety. Oceny spoza niej są odrzucane przy wczytywaniu i w Store.
+const (
+	MinRating = 0.0
+	MaxRating = 10.0
+)
+
+// validRating sprawdza, czy ocena jest liczbą ze skali ankiety.
+func validRating(rating float64) bool {
+	return !math.IsNaN(rating) && rating >= MinRating && rating <= MaxRating
+}
+
+// IssueKind to rodzaj problemu znalezionego podczas wczytywania danych.
+type IssueKind string
+
+const (
+	IssueInvalidRecord   IssueKind = "invalid-record"       // za mało pól w wierszu
+	IssueInvalidPersonID IssueKind = "invalid-person-id"    // ID użytkownika nie jest liczbą
+	IssueEmptyTitle      IssueKind = "empty-title"          // pusty tytuł filmu
+	IssueTitleNormalized IssueKind = "title-normalized"     // tytuł zapisany inaczej niż wcześniej (spacje, wielkość liter)
+	IssueMissingRating   IssueKind = "missing-rating"       // film obejrzany, ale bez oceny
+	IssueInvalidRating   IssueKind = "invalid-rating"       // ocena nie jest liczbą ze skali MinRating-MaxRating
+	IssueDuplicateRating IssueKind = "duplicate-rating"     // ten sam użytkownik ocenił albo obejrzał film drugi raz
+	IssueNearDuplicate   IssueKind = "near-duplicate-title" // podobne tytuły, być może ten sam film
+	IssueIDTrimmed       IssueKind = "imdb-id-trimmed"      // ID IMDB otoczone białymi znakami
+	IssueInvalidIMDBID   IssueKind = "invalid-imdb-id"      // ID IMDB bez przedrostka "tt"
+	IssueUnknownTitle    IssueKind = "unknown-title"        // ID IMDB dla filmu, którego nikt nie ocenił
+	IssueMissingIMDBID   IssueKind = "missing-imdb-id"      // oceniony film bez ID IMDB
+)
+
+// IssueAction mówi, co wczytywanie zrobiło z problemem.
+type IssueAction string
+
+const (
+	ActionFixed    IssueAction = "fixed"    // dane poprawione i przyjęte
+	ActionRejected IssueAction = "rejected" // wiersz pominięty
+	ActionWarning  IssueAction = "warning"  // dane przyjęte bez zmian, do sprawdzenia
+)
+
+// Issue to jeden problem w danych. Line to numer wiersza pliku (od 1) albo 0,
+// gdy problem dotyczy całych danych.
+type Issue struct {
+	Line    int         `json:"line,omitempty"`
+	Kind    IssueKind   `json:"kind"`
+	Action  IssueAction `json:"action"`
+	Message string      `json:"message"`
+}
+
+// ValidationReport opisuje wczytanie jednego pliku: ile wierszy przyjęto
+// i co poprawiono albo odrzucono.
+type ValidationReport struct {
+	File     string  `json:"file"`
+	Rows     int     `json:"rows"`
+	Accepted int     `json:"accepted"`
+	Issues   []Issue `json:"issues"`
+}
+
+// add dopisuje problem do raportu.
+func (r *ValidationReport) add(line int, kind IssueKind, action IssueAction, format string, args ...any) {
+	r.Issues = append(r.Issues, Issue{Line: line, Kind: kind, Action: action, Message: fmt.Sprintf(format, args...)})
+}
+
+// Count zwraca liczbę problemów danego rodzaju.
+func (r *ValidationReport) Count(kind IssueKind) int {
+	n := 0
+	for _, issue := range r.Issues {
+		if issue.Kind == kind {
+			n++
+		}
+	}
+	return n
+}
+
+// Print wypisuje podsumowanie i listę problemów: wszystkich albo tylko odrzuconych wierszy.
+func (r *ValidationReport) Print(w io.Writer, all bool) {
+	actions := make(map[IssueAction]int)
+	for _, issue := range r.Issues {
+		actions[issue.Action]++
+	}
+	fmt.Fprintf(w, "%s: %d rows, %d accepted, %d fixed, %d rejected, %d warnings\n",
+		r.File, r.Rows, r.Accepted, actions[ActionFixed], actions[ActionRejected], actions[ActionWarning])
+	for _, issue := range r.Issues {
+		if !all && issue.Action != ActionRejected {
+			continue
+		}
+		if issue.Line > 0 {
+			fmt.Fprintf(w, "  line %d: %s (%s): %s\n", issue.Line, issue.Kind, issue.Action, issue.Message)
+		} else {
+			fmt.Fprintf(w, "  %s (%s): %s\n", issue.Kind, issue.Action, issue.Message)
+		}
+	}
+}
+
+// cleanTitle usuwa białe znaki z początku i końca tytułu i skleja spacje wewnątrz.
+func cleanTitle(title string) string {
+	return strings.Join(strings.Fields(title), " ")
+}
+
+// titleKey zwraca klucz, pod którym tytuły uznaje się za ten sam film: bez
+// nadmiarowych spacji i bez rozróżniania wielkości liter.
+func titleKey(title string) string {
+	return strings.ToLower(cleanTitle(title))
+}
+
+// ReadRatings wczytuje oceny z CSV (ID użytkownika, tytuł, ocena; pierwszy wiersz
+// to nagłówek) i dopisuje je do mr. Tytuły o tym samym kluczu (titleKey) są
+// zapisywane tak jak przy pierwszym wystąpieniu. Pusta ocena oznacza film obejrzany
+// bez oceny i trafia do Unrated, a nie do Ratings jako 0. Wiersze z oceną, która
+// nie jest liczbą ze skali MinRating-MaxRating, i powtórne wiersze tego samego
+// użytkownika dla tego samego filmu (z oceną albo bez) są odrzucane. Na końcu
+// raport wskazuje pary tytułów tak podobnych, że mogą być literówką, ale ich nie łączy.
+func (mr *MovieRatings) ReadRatings(r io.Reader, name string) (*ValidationReport, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("could not read CSV: %v", err)
+	}
+
+	report := &ValidationReport{File: name}
+	titles := make(map[string]string)
+	seen := make(map[int]map[string]int) // użytkownik -> klucz tytułu -> wiersz
+	for _, ratings := range [][]MovieRating{mr.Ratings, mr.Unrated} {
+		for _, rating := range ratings {
+			titles[titleKey(rating.MovieTitle)] = rating.MovieTitle
+		}
+	}
+	for i, record := range records {
+		line := i + 1
+		if i == 0 {
+			continue
+		}
+		report.Rows++
+		if len(record) < 3 {
+			report.add(line, IssueInvalidRecord, ActionRejected, "expected at least 3 fields, got %d", len(record))
+			continue
+		}
+		personID, err := strconv.Atoi(strings.TrimSpace(record[0]))
+		if err != nil {
+			report.add(line, IssueInvalidPersonID, ActionRejected, "could not parse person ID %q", record[0])
+			continue
+		}
+		key := titleKey(record[1])
+		if key == "" {
+			report.add(line, IssueEmptyTitle, ActionRejected, "empty movie title")
+			continue
+		}
+		title, known := titles[key]
+		if !known {
+			title = cleanTitle(record[1])
+			titles[key] = title
+		}
+		if record[1] != title {
+			report.add(line, IssueTitleNormalized, ActionFixed, "%q read as %q", record[1], title)
+		}
+
+		movie := MovieRating{PersonID: personID, MovieTitle: title}
+		value := strings.TrimSpace(record[2])
+		if value != "" {
+			movie.Rating, err = strconv.ParseFloat(value, 64)
+			if err != nil {
+				report.add(line, IssueInvalidRating, ActionRejected, "could not parse rating %q for %q", record[2], title)
+				continue
+			}
+			if !validRating(movie.Rating) {
+				report.add(line, IssueInvalidRating, ActionRejected, "rating %q for %q outside the scale %g-%g", record[2], title, MinRating, MaxRating)
+				continue
+			}
+		}
+		if first, ok := seen[personID][key]; ok {
+			report.add(line, IssueDuplicateRating, ActionRejected, "user %d already rated or watched %q at line %d", personID, title, first)
+			continue
+		}
+		if seen[personID] == nil {
+			seen[personID] = make(map[string]int)
+		}
+		seen[personID][key] = line
+		if value == "" {
+			report.add(line, IssueMissingRating, ActionWarning, "user %d watched %q without rating it; kept as watched, not as 0", personID, title)
+			mr.Unrated = append(mr.Unrated, movie)
+		} else {
+			mr.Ratings = append(mr.Ratings, movie)
+		}
+		report.Accepted++
+	}
+
+	keys := sortedKeys(titles)
+	for i, a := range keys {
+		for _, b := range keys[i+1:] {
+			if similarTitles(a, b) {
+				report.add(0, IssueNearDuplicate, ActionWarning, "%q and %q may be the same movie", titles[a], titles[b])
+			}
+		}
+	}
+	return report, nil
+}
+
+// similarTitles sprawdza, czy klucze tytułów różnią się o najwyżej 2 edycje, a przy
+// krótkich tytułach o 1 na każde 4 znaki. Tytuły różniące się tylko cyframi (np.
+// kolejne części filmu) nie są uznawane za podobne.
+func similarTitles(a, b string) bool {
+	d := levenshtein(a, b)
+	if d > 2 || d > min(len([]rune(a)), len([]rune(b)))/4 {
+		return false
+	}
+	numbering := func(r rune) bool { return r == ' ' || r >= '0' && r <= '9' }
+	return strings.TrimRightFunc(a, numbering) != strings.TrimRightFunc(b, numbering)
+}
+
+// levenshtein zwraca odległość edycyjną napisów: najmniejszą liczbę wstawień,
+// usunięć i zamian znaków, które zamieniają a w b.
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
+
+// ReadIMDBIDs wczytuje CSV z tytułami i ID IMDB (pierwszy wiersz to nagłówek)
+// i przypisuje ID ocenom w mr. Tytuły są dopasowywane po titleKey, a tytuły
+// i ID są oczyszczane z otaczających spacji.
+func (mr *MovieRatings) ReadIMDBIDs(r io.Reader, name string) (*ValidationReport, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("could not read CSV: %v", err)
+	}
+
+	report := &ValidationReport{File: name}
+	known := make(map[string]bool)
+	for _, ratings := range [][]MovieRating{mr.Ratings, mr.Unrated} {
+		for _, rating := range ratings {
+			known[titleKey(rating.MovieTitle)] = true
+		}
+	}
+	imdbMap := make(map[string]string)
+	for i, record := range records {
+		line := i + 1
+		if i == 0 {
+			continue
+		}
+		report.Rows++
+		if len(record) < 2 {
+			report.add(line, IssueInvalidRecord, ActionRejected, "expected at least 2 fields, got %d", len(record))
+			continue
+		}
+		key := titleKey(record[0])
+		id := strings.TrimSpace(record[1])
+		if !strings.HasPrefix(id, "tt") {
+			report.add(line, IssueInvalidIMDBID, ActionRejected, "IMDB ID %q of %q does not start with tt", id, record[0])
+			continue
+		}
+		if id != record[1] {
+			report.add(line, IssueIDTrimmed, ActionFixed, "IMDB ID %q read as %q", record[1], id)
+		}
+		if !known[key] {
+			report.add(line, IssueUnknownTitle, ActionWarning, "nobody rated %q", cleanTitle(record[0]))
+		}
+		imdbMap[key] = id
+		report.Accepted++
+	}
+
+	missing := make(map[string]bool)
+	for _, ratings := range [][]MovieRating{mr.Ratings, mr.Unrated} {
+		for i, rating := range ratings {
+			if imdbID, ok := imdbMap[titleKey(rating.MovieTitle)]; ok {
+				ratings[i].IMDBID = imdbID
+			} else {
+				missing[rating.MovieTitle] = true
+			}
+		}
+	}
+	for _, title := range sortedKeys(missing) {
+		report.add(0, IssueMissingIMDBID, ActionWarning, "no IMDB ID for %q", title)
+	}
+	return report, nil
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// ratingsCSV zawiera po jednym przypadku każdego problemu, który ReadRatings
+// poprawia, odrzuca albo zgłasza.
+const ratingsCSV = `person,title,rating
+1,The Matrix,9
+2,  the  matrix ,8
+1,Inception,
+2,Inception,0
+3,Inception,11
+3,Inception,-1
+3,Inception,NaN
+1,The Matrix,7
+1,inception,5
+x,Inception,5
+4,,5
+4,Inception
+3,The Matrx,6
+3,Rocky 2,6
+3,Rocky 3,6
+`
+
+// issueSummary skraca problemy raportu do wiersza, rodzaju i działania.
+func issueSummary(report *ValidationReport) []string {
+	var summary []string
+	for _, issue := range report.Issues {
+		summary = append(summary, fmt.Sprintf("%d %s %s", issue.Line, issue.Kind, issue.Action))
+	}
+	return summary
+}
+
+func TestReadRatings(t *testing.T) {
+	mr := &MovieRatings{}
+	report, err := mr.ReadRatings(strings.NewReader(ratingsCSV), "ratings.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"3 title-normalized fixed",    // spacje i wielkość liter jak przy pierwszym wystąpieniu
+		"4 missing-rating warning",    // brak oceny to nie 0
+		"6 invalid-rating rejected",   // ponad MaxRating
+		"7 invalid-rating rejected",   // poniżej MinRating
+		"8 invalid-rating rejected",   // NaN jest liczbą dla ParseFloat, ale nie oceną
+		"9 duplicate-rating rejected", // drugi wiersz ocenionego filmu
+		"10 title-normalized fixed",
+		"10 duplicate-rating rejected", // drugi wiersz filmu obejrzanego bez oceny
+		"11 invalid-person-id rejected",
+		"12 empty-title rejected",
+		"13 invalid-record rejected",
+		"0 near-duplicate-title warning", // "The Matrx", ale nie kolejne części "Rocky"
+	}
+	if got := issueSummary(report); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected issues\n%s\nfound\n%s", strings.Join(want, "\n"), strings.Join(got, "\n"))
+	}
+	if report.Rows != 15 || report.Accepted != 7 {
+		t.Errorf("expected 15 rows and 7 accepted, found %d and %d", report.Rows, report.Accepted)
+	}
+	if last := report.Issues[len(report.Issues)-1].Message; !strings.Contains(last, `"The Matrix"`) || !strings.Contains(last, `"The Matrx"`) {
+		t.Errorf("expected The Matrix and The Matrx to be near duplicates, found %s", last)
+	}
+
+	wantRatings := []MovieRating{
+		{PersonID: 1, MovieTitle: "The Matrix", Rating: 9},
+		{PersonID: 2, MovieTitle: "The Matrix", Rating: 8},
+		{PersonID: 2, MovieTitle: "Inception", Rating: 0},
+		{PersonID: 3, MovieTitle: "The Matrx", Rating: 6},
+		{PersonID: 3, MovieTitle: "Rocky 2", Rating: 6},
+		{PersonID: 3, MovieTitle: "Rocky 3", Rating: 6},
+	}
+	if !reflect.DeepEqual(mr.Ratings, wantRatings) {
+		t.Errorf("expected ratings %v, found %v", wantRatings, mr.Ratings)
+	}
+	if want := []MovieRating{{PersonID: 1, MovieTitle: "Inception"}}; !reflect.DeepEqual(mr.Unrated, want) {
+		t.Errorf("expected unrated %v, found %v", want, mr.Unrated)
+	}
+}
+
+func TestReadIMDBIDs(t *testing.T) {
+	mr := &MovieRatings{}
+	if _, err := mr.ReadRatings(strings.NewReader(ratingsCSV), "ratings.csv"); err != nil {
+		t.Fatal(err)
+	}
+	report, err := mr.ReadIMDBIDs(strings.NewReader(`title,imdb
+ the matrix ,  tt0133093
+Inception,0137523
+Inception,tt1375666
+Rocky 2,tt0079817
+Unknown Film,tt0000001
+short
+`), "imdb.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"2 imdb-id-trimmed fixed",
+		"3 invalid-imdb-id rejected",
+		"6 unknown-title warning",
+		"7 invalid-record rejected",
+		"0 missing-imdb-id warning", // Rocky 3
+		"0 missing-imdb-id warning", // The Matrx
+	}
+	if got := issueSummary(report); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected issues\n%s\nfound\n%s", strings.Join(want, "\n"), strings.Join(got, "\n"))
+	}
+	if report.Rows != 6 || report.Accepted != 4 {
+		t.Errorf("expected 6 rows and 4 accepted, found %d and %d", report.Rows, report.Accepted)
+	}
+
+	if id := mr.Ratings[1].IMDBID; id != "tt0133093" {
+		t.Errorf("expected the trimmed ID tt0133093 for The Matrix, found %q", id)
+	}
+	if id := mr.Unrated[0].IMDBID; id != "tt1375666" {
+		t.Errorf("expected unrated movies to get IDs too, found %q", id)
+	}
+	if id := mr.Ratings[5].IMDBID; id != "" {
+		t.Errorf("expected no ID for Rocky 3, found %q", id)
+	}
+}
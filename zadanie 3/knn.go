@@ -0,0 +1,295 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// KNNConfig opisuje filtrowanie kolaboratywne metodą najbliższych sąsiadów.
+type KNNConfig struct {
+	K         int     // liczba sąsiadów użytych do przewidywania
+	Metric    Metric  // miara podobieństwa sąsiadów
+	Shrinkage float64 // λ: podobieństwo jest mnożone przez n / (n + λ), n to liczba wspólnych ocen
+}
+
+// DefaultKNNConfig zwraca ustawienia domyślne: 5 sąsiadów, korelacja Pearsona
+// i silne ściąganie podobieństw opartych na kilku wspólnych ocenach.
+func DefaultKNNConfig() KNNConfig {
+	return KNNConfig{K: 5, Metric: Pearson, Shrinkage: 5}
+}
+
+// shrunkSimilarity zwraca podobieństwo wektorów pomniejszone proporcjonalnie
+// do tego, jak mało mają wspólnych ocen.
+func shrunkSimilarity[K comparable](cfg KNNConfig, a, b map[K]float64, means map[K]float64) float64 {
+	common := 0
+	for key := range a {
+		if _, ok := b[key]; ok {
+			common++
+		}
+	}
+	if common == 0 {
+		return 0
+	}
+	return similarity(cfg.Metric, a, b, means) * float64(common) / (float64(common) + cfg.Shrinkage)
+}
+
+// neighbour to sąsiad użyty w przewidywaniu: użytkownik (UserKNN) albo film (ItemKNN).
+type neighbour struct {
+	Name       string
+	Similarity float64
+	Rating     float64 // ocena sąsiada dla filmu (UserKNN) albo użytkownika dla sąsiada (ItemKNN)
+	Deviation  float64 // Rating pomniejszona o średnią ocen sąsiada
+}
+
+// predictFromNeighbours liczy przewidywanie wyśrodkowane średnią:
+// mean + Σ sim * odchylenie / Σ |sim| dla najbliższych k sąsiadów o dodatnim podobieństwie.
+func predictFromNeighbours(mean float64, candidates []neighbour, k int) (float64, []neighbour, bool) {
+	var positive []neighbour
+	for _, n := range candidates {
+		if n.Similarity > 0 {
+			positive = append(positive, n)
+		}
+	}
+	sort.SliceStable(positive, func(i, j int) bool { return positive[i].Similarity > positive[j].Similarity })
+	if len(positive) > k {
+		positive = positive[:k]
+	}
+	num, den := 0.0, 0.0
+	for _, n := range positive {
+		num += n.Similarity * n.Deviation
+		den += math.Abs(n.Similarity)
+	}
+	if den == 0 {
+		return 0, nil, false
+	}
+	return mean + num/den, positive, true
+}
+
+// explainNeighbours opisuje przewidywanie dwoma sąsiadami o największym wpływie
+// w kierunku wyniku: podnoszącymi go, gdy film jest polecany, i obniżającymi, gdy nie.
+func explainNeighbours(used []neighbour, recommended bool, describe func(names []string, ratings []string, recommended bool) string) string {
+	sorted := append([]neighbour(nil), used...)
+	sign := 1.0
+	if !recommended {
+		sign = -1
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sign*sorted[i].Similarity*sorted[i].Deviation > sign*sorted[j].Similarity*sorted[j].Deviation
+	})
+	var names, ratings []string
+	for _, n := range sorted {
+		if len(names) == 2 || sign*n.Deviation <= 0 {
+			break
+		}
+		names = append(names, n.Name)
+		ratings = append(ratings, strconv.FormatFloat(n.Rating, 'g', -1, 64))
+	}
+	if len(names) == 0 {
+		return "no strong signal from similar ratings"
+	}
+	return describe(names, ratings, recommended)
+}
+
+// joinAnd łączy elementy listy w "a", "a and b".
+func joinAnd(items []string) string {
+	return strings.Join(items, " and ")
+}
+
+// UserKNN przewiduje ocenę filmu na podstawie ocen k użytkowników najbardziej
+// podobnych do danego, którzy ten film widzieli.
+type UserKNN struct {
+	cfg        KNNConfig
+	ratings    map[int]map[string]float64
+	userMeans  map[int]float64
+	movieMeans map[string]float64
+	min, max   float64
+}
+
+// NewUserKNN przygotowuje model na podstawie ocen.
+func NewUserKNN(mr *MovieRatings, cfg KNNConfig) (*UserKNN, error) {
+	if cfg.K < 1 || cfg.Shrinkage < 0 {
+		return nil, fmt.Errorf("invalid kNN configuration %+v", cfg)
+	}
+	if _, err := ParseMetric(string(cfg.Metric)); err != nil {
+		return nil, err
+	}
+	ratings := mr.userRatings()
+	m := &UserKNN{cfg: cfg, ratings: ratings, userMeans: make(map[int]float64), movieMeans: itemMeans(ratings)}
+	m.min, m.max = ratingRange(mr.Ratings)
+	for user, movies := range ratings {
+		m.userMeans[user] = mean(movies)
+	}
+	return m, nil
+}
+
+// neighbours zwraca użytkowników, którzy ocenili film, z ich podobieństwem do personID.
+func (m *UserKNN) neighbours(personID int, title string) (float64, []neighbour, bool, error) {
+	own, ok := m.ratings[personID]
+	if !ok {
+		return 0, nil, false, fmt.Errorf("%w %d", ErrUnknownUser, personID)
+	}
+	var candidates []neighbour
+	for _, user := range sortedKeys(m.ratings) {
+		rating, ok := m.ratings[user][title]
+		if !ok || user == personID {
+			continue
+		}
+		candidates = append(candidates, neighbour{
+			Name:       "user " + strconv.Itoa(user),
+			Similarity: shrunkSimilarity(m.cfg, own, m.ratings[user], m.movieMeans),
+			Rating:     rating,
+			Deviation:  rating - m.userMeans[user],
+		})
+	}
+	x, used, ok := predictFromNeighbours(m.userMeans[personID], candidates, m.cfg.K)
+	return math.Max(m.min, math.Min(m.max, x)), used, ok, nil
+}
+
+// Predict przewiduje ocenę filmu title przez użytkownika personID.
+func (m *UserKNN) Predict(personID int, title string) (float64, error) {
+	x, _, ok, err := m.neighbours(personID, title)
+	if err != nil {
+		return 0, err
+	}
+	if !ok {
+		return 0, fmt.Errorf("%w: no similar user rated %q", ErrNoPrediction, title)
+	}
+	return x, nil
+}
+
+// Explain wskazuje podobnych użytkowników, których oceny najbardziej wpłynęły na przewidywanie.
+func (m *UserKNN) Explain(personID int, title string) (string, error) {
+	x, used, ok, err := m.neighbours(personID, title)
+	if err != nil || !ok {
+		return "", err
+	}
+	return explainNeighbours(used, x >= m.movieMeans[title], func(names, ratings []string, recommended bool) string {
+		return fmt.Sprintf("because %s, who rate movies like you, rated it %s", joinAnd(names), joinAnd(ratings))
+	}), nil
+}
+
+// ItemKNN przewiduje ocenę filmu na podstawie ocen, które użytkownik wystawił
+// k najbardziej podobnym filmom. Podobieństwa wszystkich par filmów są liczone
+// z góry przy tworzeniu modelu.
+type ItemKNN struct {
+	cfg          KNNConfig
+	ratings      map[int]map[string]float64
+	userMeans    map[int]float64
+	movieMeans   map[string]float64
+	similarities map[string]map[string]float64
+	min, max     float64
+}
+
+// NewItemKNN przygotowuje model i macierz podobieństw filmów. Wektorem filmu są
+// oceny użytkowników, a skorygowany kosinus odejmuje od nich średnie użytkowników.
+func NewItemKNN(mr *MovieRatings, cfg KNNConfig) (*ItemKNN, error) {
+	if cfg.K < 1 || cfg.Shrinkage < 0 {
+		return nil, fmt.Errorf("invalid kNN configuration %+v", cfg)
+	}
+	if _, err := ParseMetric(string(cfg.Metric)); err != nil {
+		return nil, err
+	}
+	ratings := mr.userRatings()
+	items := make(map[string]map[int]float64)
+	for user, movies := range ratings {
+		for title, rating := range movies {
+			if _, ok := items[title]; !ok {
+				items[title] = make(map[int]float64)
+			}
+			items[title][user] = rating
+		}
+	}
+	m := &ItemKNN{
+		cfg:          cfg,
+		ratings:      ratings,
+		userMeans:    itemMeans(items),
+		movieMeans:   make(map[string]float64),
+		similarities: make(map[string]map[string]float64),
+	}
+	m.min, m.max = ratingRange(mr.Ratings)
+	titles := sortedKeys(items)
+	for _, title := range titles {
+		m.movieMeans[title] = mean(items[title])
+		m.similarities[title] = make(map[string]float64)
+	}
+	for i, a := range titles {
+		for _, b := range titles[i+1:] {
+			if s := shrunkSimilarity(cfg, items[a], items[b], m.userMeans); s != 0 {
+				m.similarities[a][b] = s
+				m.similarities[b][a] = s
+			}
+		}
+	}
+	return m, nil
+}
+
+// neighbours zwraca filmy ocenione przez personID z ich podobieństwem do title.
+func (m *ItemKNN) neighbours(personID int, title string) (float64, []neighbour, bool, error) {
+	own, ok := m.ratings[personID]
+	if !ok {
+		return 0, nil, false, fmt.Errorf("%w %d", ErrUnknownUser, personID)
+	}
+	var candidates []neighbour
+	for _, other := range sortedKeys(own) {
+		if other == title {
+			continue
+		}
+		candidates = append(candidates, neighbour{
+			Name:       other,
+			Similarity: m.similarities[title][other],
+			Rating:     own[other],
+			Deviation:  own[other] - m.movieMeans[other],
+		})
+	}
+	x, used, ok := predictFromNeighbours(m.movieMeans[title], candidates, m.cfg.K)
+	return math.Max(m.min, math.Min(m.max, x)), used, ok, nil
+}
+
+// Predict przewiduje ocenę filmu title przez użytkownika personID.
+func (m *ItemKNN) Predict(personID int, title string) (float64, error) {
+	x, _, ok, err := m.neighbours(personID, title)
+	if err != nil {
+		return 0, err
+	}
+	if !ok {
+		return 0, fmt.Errorf("%w: user %d rated no movie similar to %q", ErrNoPrediction, personID, title)
+	}
+	return x, nil
+}
+
+// Explain wskazuje ocenione przez użytkownika filmy, które najbardziej wpłynęły na przewidywanie.
+// Kierunek wpływu jest liczony względem średniej filmu, od której wychodzi przewidywanie.
+func (m *ItemKNN) Explain(personID int, title string) (string, error) {
+	x, used, ok, err := m.neighbours(personID, title)
+	if err != nil || !ok {
+		return "", err
+	}
+	return explainNeighbours(used, x >= m.movieMeans[title], func(names, ratings []string, recommended bool) string {
+		how := "highly"
+		if !recommended {
+			how = "poorly"
+		}
+		return fmt.Sprintf("because you rated %s %s (%s)", joinAnd(names), how, joinAnd(ratings))
+	}), nil
+}
+
+// mean zwraca średnią wartości mapy.
+func mean[K comparable](values map[K]float64) float64 {
+	sum := 0.0
+	for _, x := range values {
+		sum += x
+	}
+	return sum / float64(len(values))
+}
+
+// ratingRange zwraca najmniejszą i największą ocenę w danych.
+func ratingRange(ratings []MovieRating) (float64, float64) {
+	lo, hi := math.Inf(1), math.Inf(-1)
+	for _, r := range ratings {
+		lo, hi = math.Min(lo, r.Rating), math.Max(hi, r.Rating)
+	}
+	return lo, hi
+}
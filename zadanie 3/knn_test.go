@@ -0,0 +1,103 @@
+package main
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+// knnRatings to trzy osoby i trzy filmy; użytkownik 1 nie widział "C".
+// Użytkownik 2 ocenia jak on (kosinus 1), a użytkownik 3 trochę inaczej (kosinus 0.8).
+func knnRatings() *MovieRatings {
+	return &MovieRatings{Ratings: []MovieRating{
+		{PersonID: 1, MovieTitle: "A", Rating: 4},
+		{PersonID: 1, MovieTitle: "B", Rating: 2},
+		{PersonID: 2, MovieTitle: "A", Rating: 4},
+		{PersonID: 2, MovieTitle: "B", Rating: 2},
+		{PersonID: 2, MovieTitle: "C", Rating: 6},
+		{PersonID: 3, MovieTitle: "A", Rating: 2},
+		{PersonID: 3, MovieTitle: "B", Rating: 4},
+		{PersonID: 3, MovieTitle: "C", Rating: 3},
+	}}
+}
+
+func TestShrunkSimilarity(t *testing.T) {
+	a := map[string]float64{"A": 1, "B": 2, "C": 3}
+	b := map[string]float64{"B": 6, "C": 4}
+	for _, tt := range []struct {
+		shrinkage, want float64
+	}{
+		{0, 24.0 / 26},
+		{2, 24.0 / 26 * 2 / 4}, // 2 wspólne oceny: n / (n + λ) = 1/2
+		{6, 24.0 / 26 * 2 / 8},
+	} {
+		cfg := KNNConfig{K: 5, Metric: Cosine, Shrinkage: tt.shrinkage}
+		if got := shrunkSimilarity(cfg, a, b, nil); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("λ = %g: expected %g, found %g", tt.shrinkage, tt.want, got)
+		}
+	}
+	cfg := KNNConfig{K: 5, Metric: Cosine, Shrinkage: 2}
+	if got := shrunkSimilarity(cfg, a, map[string]float64{"E": 5}, nil); got != 0 {
+		t.Errorf("expected 0 without common items, found %g", got)
+	}
+}
+
+func TestUserKNN(t *testing.T) {
+	tests := []struct {
+		k    int
+		want float64
+	}{
+		// średnia użytkownika 1 to 3; odchylenia sąsiadów od ich średnich to
+		// 6 - 4 = 2 (podobieństwo 1) i 3 - 3 = 0 (podobieństwo 0.8)
+		{5, 3 + (1*2+0.8*0)/1.8},
+		{1, 3 + 2},
+	}
+	for _, tt := range tests {
+		m, err := NewUserKNN(knnRatings(), KNNConfig{K: tt.k, Metric: Cosine})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, err := m.Predict(1, "C"); err != nil || math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("k = %d: expected %g, found %g and %v", tt.k, tt.want, got, err)
+		}
+	}
+}
+
+func TestItemKNN(t *testing.T) {
+	m, err := NewItemKNN(knnRatings(), KNNConfig{K: 5, Metric: Cosine})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Średnie filmów: A 10/3, B 8/3, C 4.5. "C" ma z "A" kosinus 1, a z "B" 0.8;
+	// użytkownik 1 ocenił je o 2/3 powyżej i o 2/3 poniżej średniej.
+	want := 4.5 + (1*2.0/3-0.8*2.0/3)/1.8
+	if got, err := m.Predict(1, "C"); err != nil || math.Abs(got-want) > 1e-9 {
+		t.Errorf("expected %g, found %g and %v", want, got, err)
+	}
+}
+
+func TestKNNErrors(t *testing.T) {
+	for _, cfg := range []KNNConfig{
+		{K: 0, Metric: Cosine},
+		{K: 2, Metric: Cosine, Shrinkage: -1},
+		{K: 2, Metric: "euclidean"},
+	} {
+		if _, err := NewUserKNN(knnRatings(), cfg); err == nil {
+			t.Errorf("user kNN: expected an error for %+v", cfg)
+		}
+		if _, err := NewItemKNN(knnRatings(), cfg); err == nil {
+			t.Errorf("item kNN: expected an error for %+v", cfg)
+		}
+	}
+
+	user, _ := NewUserKNN(knnRatings(), DefaultKNNConfig())
+	item, _ := NewItemKNN(knnRatings(), DefaultKNNConfig())
+	for name, model := range map[string]Recommender{"user": user, "item": item} {
+		if _, err := model.Predict(9, "C"); !errors.Is(err, ErrUnknownUser) {
+			t.Errorf("%s kNN: expected ErrUnknownUser, found %v", name, err)
+		}
+		if _, err := model.Predict(1, "Z"); !errors.Is(err, ErrNoPrediction) {
+			t.Errorf("%s kNN: expected ErrNoPrediction for a movie nobody rated, found %v", name, err)
+		}
+	}
+}
@@ -13,20 +13,18 @@ package main
 //- Zaproponouj 5 film, których dany użytkownik nie powinnien oglądać (antyrekomendacje).
 
 import (
-	"encoding/csv"
+	"context"
 	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
-	"io"
-	"math"
 	"net/http"
 	"os"
-	"sort"
-	"strconv"
+	"os/signal"
 	"strings"
+	"time"
 )
 
-const APIKEY string = "api_key_hihi"
-
 // MovieRating to struktura przechowywująca dane uzytkowników i filmów jakie obejrzeli i jak je ocenili wraz z id z bazy danych IM
 type MovieRating struct {
 	PersonID   int
@@ -36,16 +34,20 @@ type MovieRating struct {
 }
 
 // MovieRatings to struktura przechowywująca oceny poszczególnych filmów
+// oraz filmy obejrzane, którym użytkownik nie wystawił oceny (Unrated, z Rating równym 0)
 type MovieRatings struct {
 	Ratings []MovieRating
+	Unrated []MovieRating
 }
 
 // GetIMDBIDByTitle szuka w tablicy ocen filmu o podanym tytule i zwraca jego ID z bazy IMDB.
 // Jeśli film nie zostanie znaleziony, funkcja zwraca odpowiedni komunikat o błędzie.
 func (movieRatings *MovieRatings) GetIMDBIDByTitle(title string) (string, error) {
-	for _, movie := range movieRatings.Ratings {
-		if movie.MovieTitle == title {
-			return movie.IMDBID, nil
+	for _, movies := range [][]MovieRating{movieRatings.Ratings, movieRatings.Unrated} {
+		for _, movie := range movies {
+			if movie.MovieTitle == title {
+				return movie.IMDBID, nil
+			}
 		}
 	}
 	return "", fmt.Errorf("no sutch a film")
@@ -53,102 +55,30 @@ func (movieRatings *MovieRatings) GetIMDBIDByTitle(title string) (string, error)
 
 // LoadCSV wczytuje dane o ocenach filmów z pliku CSV i zapisuje je w strukturze MovieRatings.
 // Każdy wiersz pliku powinien zawierać dane w formacie: ID użytkownika, tytuł filmu i ocena.
-// Funkcja ignoruje nagłówki i błędne wiersze, a błędy podczas parsowania są logowane.
-func (mr *MovieRatings) LoadCSV(filename string) error {
+// Szczegóły wczytywania opisuje ReadRatings; raport zawiera wszystko, co poprawiono lub odrzucono.
+func (mr *MovieRatings) LoadCSV(filename string) (*ValidationReport, error) {
 	file, err := os.Open(filename)
 	if err != nil {
-		return fmt.Errorf("could not open file: %v", err)
+		return nil, fmt.Errorf("could not open file: %v", err)
 	}
 	defer file.Close()
-
-	reader := csv.NewReader(file)
-	reader.FieldsPerRecord = -1
-
-	records, err := reader.ReadAll()
-	if err != nil {
-		return fmt.Errorf("could not read CSV: %v", err)
-	}
-
-	for i, record := range records {
-		if i == 0 {
-			continue
-		}
-
-		if len(record) < 3 {
-			fmt.Printf("Warning: invalid record at line %d: expected at least 3 fields, got %d\n", i+1, len(record))
-			continue
-		}
-
-		personID, err := strconv.Atoi(record[0])
-		if err != nil {
-			fmt.Printf("Warning: could not parse person ID at line %d: %v\n", i+1, err)
-			continue
-		}
-
-		rating := 0.0
-		if record[2] != "" {
-			rating, err = strconv.ParseFloat(record[2], 64)
-			if err != nil {
-				fmt.Printf("Warning: could not parse rating for movie '%s' at line %d: %v\n", record[1], i+1, err)
-				rating = 0.0
-			}
-		}
-
-		movieRating := MovieRating{
-			PersonID:   personID,
-			MovieTitle: record[1],
-			Rating:     rating,
-		}
-		mr.Ratings = append(mr.Ratings, movieRating)
-	}
-
-	return nil
+	return mr.ReadRatings(file, filename)
 }
 
 // LoadIMDBIDs wczytuje powiązania tytułów filmów z ich ID z bazy IMDB z pliku CSV.
-// Dla każdego filmu w strukturze MovieRatings, przypisuje odpowiednie ID z pliku.
-// Wiersze z błędnymi danymi są ignorowane, a funkcja loguje ewentualne problemy.
-func (mr *MovieRatings) LoadIMDBIDs(filename string) error {
+// Dla każdego filmu w strukturze MovieRatings, przypisuje odpowiednie ID z pliku
+// (szczegóły opisuje ReadIMDBIDs).
+func (mr *MovieRatings) LoadIMDBIDs(filename string) (*ValidationReport, error) {
 	file, err := os.Open(filename)
 	if err != nil {
-		return fmt.Errorf("could not open file: %v", err)
+		return nil, fmt.Errorf("could not open file: %v", err)
 	}
 	defer file.Close()
-
-	reader := csv.NewReader(file)
-	reader.FieldsPerRecord = -1
-
-	records, err := reader.ReadAll()
-	if err != nil {
-		return fmt.Errorf("could not read CSV: %v", err)
-	}
-
-	imdbMap := make(map[string]string)
-	for i, record := range records {
-		if i == 0 {
-			continue
-		}
-		if len(record) < 2 {
-			fmt.Printf("Warning: invalid record at line %d: expected at least 2 fields, got %d\n", i+1, len(record))
-			continue
-		}
-		imdbMap[record[0]] = record[1]
-	}
-	for i, rating := range mr.Ratings {
-		if imdbID, ok := imdbMap[rating.MovieTitle]; ok {
-			mr.Ratings[i].IMDBID = imdbID
-		}
-	}
-	return nil
+	return mr.ReadIMDBIDs(file, filename)
 }
 
-// RecommendMovies generuje rekomendacje filmowe dla użytkownika o podanym ID.
-// Funkcja używa algorytmu k-średnich (k-means) do podziału użytkowników na grupy na
-// podstawie ich ocen filmów. Następnie wybiera filmy najlepiej oceniane przez osoby
-// w tej samej grupie (których użytkownik jeszcze nie oglądał). Zwraca dwie listy:
-// - Najlepsze 5 filmów, które użytkownik prawdopodobnie polubi.
-// - Najgorsze 5 filmów, które użytkownik powinien unikać.
-func (mr *MovieRatings) RecommendMovies(personID int, k int) ([]string, []string) {
+// userRatings zwraca oceny w postaci użytkownik -> film -> ocena.
+func (mr *MovieRatings) userRatings() map[int]map[string]float64 {
 	userRatings := make(map[int]map[string]float64)
 	for _, rating := range mr.Ratings {
 		if _, ok := userRatings[rating.PersonID]; !ok {
@@ -156,232 +86,263 @@ func (mr *MovieRatings) RecommendMovies(personID int, k int) ([]string, []string
 		}
 		userRatings[rating.PersonID][rating.MovieTitle] = rating.Rating
 	}
+	return userRatings
+}
 
-	users := []int{}
-	for user := range userRatings {
-		users = append(users, user)
+// RecommendMovies generuje rekomendacje filmowe dla użytkownika o podanym ID.
+// Funkcja używa algorytmu k-średnich (k-means) do podziału użytkowników na k grup na
+// podstawie ich ocen filmów (ustawienia jak w DefaultClusterConfig). Następnie ocenia
+// filmy, których użytkownik jeszcze nie oglądał, średnią ocen osób z tej samej grupy.
+// Zwraca dwie listy:
+// - Najlepsze 5 filmów, które użytkownik prawdopodobnie polubi.
+// - Najgorsze 5 filmów, które użytkownik powinien unikać.
+// Dla nieznanego użytkownika lub niepoprawnego k obie listy są puste.
+func (mr *MovieRatings) RecommendMovies(personID int, k int) ([]string, []string) {
+	cfg := DefaultClusterConfig()
+	cfg.K = k
+	best, worst, err := mr.RecommendMoviesWithConfig(personID, cfg)
+	if err != nil {
+		return nil, nil
 	}
+	return best, worst
+}
 
-	centroids := make([]int, k)
-	for i := 0; i < k; i++ {
-		centroids[i] = users[i%len(users)]
+// RecommendMoviesWithConfig działa jak RecommendMovies, ale pozwala wybrać algorytm
+// grupowania, miarę podobieństwa i ziarno generatora losowego. Przy równych ocenach
+// decyduje kolejność alfabetyczna, więc wynik dla tego samego ziarna jest zawsze taki sam.
+func (mr *MovieRatings) RecommendMoviesWithConfig(personID int, cfg ClusterConfig) ([]string, []string, error) {
+	model, err := NewClusterRecommender(mr, cfg)
+	if err != nil {
+		return nil, nil, err
 	}
-
-	clusters := make(map[int][]int)
-	for i := 0; i < 10; i++ {
-		for j := 0; j < k; j++ {
-			clusters[j] = []int{}
-		}
-
-		for _, user := range users {
-			closest := 0
-			closestDistance := math.MaxFloat64
-			for j, centroid := range centroids {
-				distance := calculateDistance(userRatings[user], userRatings[centroid])
-				if distance < closestDistance {
-					closest = j
-					closestDistance = distance
-				}
-			}
-			clusters[closest] = append(clusters[closest], user)
-		}
-		centroids = calculateNewCentroids(clusters, userRatings)
+	best, worst, err := mr.Recommend(model, personID, 5)
+	if err != nil {
+		return nil, nil, err
 	}
+	return titles(best), titles(worst), nil
+}
 
-	userCluster := -1
-	for j, users := range clusters {
-		for _, user := range users {
-			if user == personID {
-				userCluster = j
-				break
-			}
-		}
-		if userCluster != -1 {
-			break
-		}
+// titles zwraca tytuły rekomendacji.
+func titles(recommendations []Recommendation) []string {
+	var out []string
+	for _, r := range recommendations {
+		out = append(out, r.Title)
 	}
+	return out
+}
 
-	recommendedMovies := make(map[string]float64)
-	seenMovies := userRatings[personID]
-	for _, user := range clusters[userCluster] {
-		for movie, rating := range userRatings[user] {
-			if _, seen := seenMovies[movie]; !seen {
-				recommendedMovies[movie] += rating
-			}
+// printRecommendations wypisuje filmy z przewidywanym wynikiem i szczegółami od dostawcy metadanych.
+func printRecommendations(movieRatings *MovieRatings, metadata MetadataProvider, header string, recommendations []Recommendation) {
+	fmt.Println(header)
+	for _, r := range recommendations {
+		fmt.Printf("%s (score %.2f)\n", r.Title, r.Score)
+		if r.Reason != "" {
+			fmt.Println(r.Reason)
+		}
+		if details, err := metadata.MovieDetails(r.IMDBID); err == nil {
+			fmt.Println(details)
+		} else {
+			fmt.Println("Error fetching movie details:", err)
 		}
 	}
-
-	type movieRecommendation struct {
-		Movie  string
-		Rating float64
-	}
-	recommendations := []movieRecommendation{}
-	for movie, rating := range recommendedMovies {
-		recommendations = append(recommendations, movieRecommendation{Movie: movie, Rating: rating})
-	}
-
-	sort.Slice(recommendations, func(i, j int) bool {
-		return recommendations[i].Rating > recommendations[j].Rating
-	})
-
-	var bestMovies, worstMovies []string
-	for i := 0; i < len(recommendations) && i < 5; i++ {
-		bestMovies = append(bestMovies, recommendations[i].Movie)
-	}
-
-	for i := len(recommendations) - 1; i >= 0 && len(recommendations)-i <= 5; i-- {
-		worstMovies = append(worstMovies, recommendations[i].Movie)
-	}
-
-	return bestMovies, worstMovies
 }
 
-// calculateDistance oblicza odległość Euklidesową między dwoma użytkownikami
-// na podstawie ich ocen filmów. Funkcja działa zarówno dla wspólnych ocen
-// (tych samych filmów), jak i dla filmów ocenionych tylko przez jedną osobę.
-// Wynik to liczba reprezentująca poziom podobieństwa: im mniejsza wartość,
-// tym bardziej podobne są preferencje użytkowników.
-func calculateDistance(user1, user2 map[string]float64) float64 {
-	sum := 0.0
-	for movie, rating1 := range user1 {
-		rating2, ok := user2[movie]
-		if ok {
-			sum += (rating1 - rating2) * (rating1 - rating2)
-		} else {
-			sum += rating1 * rating1
-		}
+// printMissingMetadata ostrzega o filmach bez metadanych pominiętych przez strategię
+// treściową, wymieniając kilka pierwszych tytułów.
+func printMissingMetadata(strategy Strategy, missing []string, movies int) {
+	if len(missing) == 0 {
+		return
 	}
-	for movie, rating2 := range user2 {
-		if _, ok := user1[movie]; !ok {
-			sum += rating2 * rating2
-		}
+	shown := missing[:min(len(missing), 3)]
+	more := ""
+	if len(missing) > len(shown) {
+		more = ", ..."
 	}
-	return math.Sqrt(sum)
+	fmt.Fprintf(os.Stderr, "Warning: %s: no metadata for %d of %d movies, content-based scores skip them (%s%s)\n",
+		strategy, len(missing), movies, strings.Join(shown, ", "), more)
 }
 
-// calculateNewCentroids oblicza nowe centroidy dla klastrów w algorytmie k-means.
-// Każdy centroid jest reprezentowany przez użytkownika, którego oceny są najbardziej
-// "centralne" (średnie) w porównaniu do innych użytkowników w danym klastrze.
-// Proces minimalizuje sumę odległości między ocenami użytkowników w klastrze.
-func calculateNewCentroids(clusters map[int][]int, userRatings map[int]map[string]float64) []int {
-	centroids := make([]int, len(clusters))
-	for clusterIdx, users := range clusters {
-		if len(users) == 0 {
-			continue
-		}
-
-		minDistanceSum := math.MaxFloat64
-		newCentroid := users[0]
-
-		for _, candidate := range users {
-			distanceSum := 0.0
-			for _, user := range users {
-				distanceSum += calculateDistance(userRatings[candidate], userRatings[user])
-			}
-
-			if distanceSum < minDistanceSum {
-				minDistanceSum = distanceSum
-				newCentroid = candidate
-			}
+// newMetadataProvider zwraca pamięć podręczną szczegółów filmów zapisaną w pliku cache,
+// uzupełnianą z OMDb. Bez klucza API albo w trybie offline korzysta tylko z pliku.
+func newMetadataProvider(omdbKey, cache string, offline bool) (MetadataProvider, error) {
+	var source MetadataProvider
+	if !offline {
+		omdb, err := NewOMDbProvider(omdbKey)
+		if err != nil {
+			fmt.Printf("Warning: %v, showing cached movie details only\n", err)
+		} else {
+			source = omdb
 		}
-
-		centroids[clusterIdx] = newCentroid
 	}
-
-	return centroids
+	return NewCachedProvider(source, cache)
 }
 
-// getMovieDetails pobiera szczegółowe informacje o filmie na podstawie jego ID z bazy IMDB.
-// Funkcja wysyła zapytanie do API OMDB, a następnie analizuje odpowiedź w formacie JSON.
-// Zwracane dane obejmują m.in. tytuł, rok produkcji, gatunek, reżysera i ocenę na IMDB.
-// Jeśli film nie zostanie znaleziony lub API zwróci błąd, funkcja zgłasza odpowiedni komunikat.
-func getMovieDetails(imdbID string) (string, error) {
-	imdbID = strings.TrimPrefix(imdbID, "tt")
-	imdbID = strings.TrimSpace(imdbID)
-	url := fmt.Sprintf("http://www.omdbapi.com/?apikey=%s&i=%s", APIKEY, imdbID)
-	resp, err := http.Get(url)
+// runServe udostępnia rekomendacje przez HTTP (newService) do przerwania
+// programu (SIGINT). Zmiany ocen trafiają do modelu tak, jak opisuje Store.
+func runServe(movieRatings *MovieRatings, strategy Strategy, opts Options, addr string, retrain time.Duration) error {
+	store, err := NewStore(movieRatings, strategy, opts)
 	if err != nil {
-		return "", fmt.Errorf("could not fetch movie details: %v", err)
+		return err
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("received non-OK response code: %d", resp.StatusCode)
+	printMissingMetadata(strategy, missingMetadata(store.current.Load().model), len(movieRatings.Titles()))
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	go store.Run(ctx, retrain)
+	srv := &http.Server{Addr: addr, Handler: newService(store, opts.Metadata)}
+	go func() {
+		<-ctx.Done()
+		shutdown, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		srv.Shutdown(shutdown)
+	}()
+	fmt.Fprintf(os.Stderr, "serving %s recommendations on %s\n", strategy, addr)
+	if err := srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return err
 	}
+	return nil
+}
 
-	bodyBytes, err := io.ReadAll(resp.Body)
+// runEvaluation dzieli oceny i porównuje strategie na tym samym podziale.
+func runEvaluation(movieRatings *MovieRatings, strategies []Strategy, split SplitConfig, opts Options, cfg EvalConfig) error {
+	train, test, err := Split(movieRatings.Ratings, split)
 	if err != nil {
-		return "", fmt.Errorf("could not read response body: %v", err)
+		return err
 	}
-
-	var result map[string]interface{}
-	if err := json.Unmarshal(bodyBytes, &result); err != nil {
-		return "", fmt.Errorf("could not decode response: %v", err)
+	fmt.Printf("Split %s: %d training and %d test ratings\n", split.Method, len(train), len(test))
+	var reports []*Report
+	for _, strategy := range strategies {
+		report, err := Evaluate(strategy, train, test, opts, cfg)
+		if err != nil {
+			if len(strategies) == 1 {
+				return fmt.Errorf("%s: %w", strategy, err)
+			}
+			fmt.Printf("Skipping %s: %v\n", strategy, err)
+			continue
+		}
+		printMissingMetadata(strategy, report.MissingMetadata, len((&MovieRatings{Ratings: train}).Titles()))
+		reports = append(reports, report)
 	}
-
-	if response, ok := result["Response"].(string); !ok || response == "False" {
-		return "", fmt.Errorf("movie not found: %v", result["Error"])
+	if len(reports) == 0 {
+		return errors.New("no strategy could be evaluated")
 	}
-
-	details := fmt.Sprintf("Title: %s\nYear: %s\nRated: %s\nReleased: %s\nRuntime: %s\nGenre: %s\nDirector: %s\nActors: %s\nPlot: %s\nIMDB Rating: %s\n",
-		getString(result, "Title"), getString(result, "Year"), getString(result, "Rated"),
-		getString(result, "Released"), getString(result, "Runtime"), getString(result, "Genre"),
-		getString(result, "Director"), getString(result, "Actors"), getString(result, "Plot"),
-		getString(result, "imdbRating"))
-
-	return details, nil
+	PrintReports(os.Stdout, reports, cfg)
+	return nil
 }
 
-// getString wyciąga wartość z mapy JSON na podstawie podanego klucza.
-// Jeśli wartość nie istnieje lub klucz jest niepoprawny, funkcja zwraca "N/A".
-func getString(data map[string]interface{}, key string) string {
-	if val, ok := data[key]; ok {
-		return fmt.Sprintf("%v", val)
+func main() {
+	personID := flag.Int("user", 1, "ID of the user to recommend movies for")
+	n := flag.Int("n", 5, "number of recommendations and anti-recommendations")
+	strategy := flag.String("model", string(StrategySVD), "recommender: cluster (mean rating in the user's cluster), svd (biased matrix factorization), als (implicit-feedback matrix factorization), user-knn or item-knn (nearest-neighbour collaborative filtering), content (genres, directors, cast and plot of rated movies) or hybrid (collaborative blended with content)")
+	opts := DefaultOptions()
+	flag.IntVar(&opts.Cluster.K, "k", opts.Cluster.K, "number of user clusters for -model cluster")
+	algorithm := flag.String("algorithm", string(opts.Cluster.Algorithm), "clustering algorithm for -model cluster: kmeans or kmedoids")
+	metric := flag.String("metric", string(opts.Cluster.Metric), "similarity metric for -model cluster, user-knn and item-knn: cosine, pearson, adjusted-cosine or jaccard")
+	flag.IntVar(&opts.Cluster.MaxIter, "max-iter", opts.Cluster.MaxIter, "maximum number of clustering iterations")
+	seed := flag.Int64("seed", 1, "random seed of clustering and model training")
+	factors := flag.Int("factors", 0, "latent factors for -model svd and als, 0 for the model default")
+	epochs := flag.Int("epochs", 0, "training epochs for -model svd and als, 0 for the model default")
+	reg := flag.Float64("reg", 0, "regularization for -model svd and als, 0 for the model default")
+	flag.IntVar(&opts.KNN.K, "neighbours", opts.KNN.K, "number of neighbours for -model user-knn and item-knn")
+	flag.Float64Var(&opts.KNN.Shrinkage, "shrinkage", opts.KNN.Shrinkage, "similarity shrinkage for -model user-knn and item-knn")
+	weighting := flag.String("weighting", string(opts.Content.Weighting), "feature weighting of -model content and hybrid: tfidf or onehot")
+	flag.BoolVar(&opts.Content.Plot, "plot", opts.Content.Plot, "use plot words as features of -model content and hybrid")
+	hybridWith := flag.String("hybrid-with", string(opts.Hybrid.Collaborative), "collaborative part of -model hybrid: cluster, svd, user-knn or item-knn")
+	flag.Float64Var(&opts.Hybrid.ColdStart, "cold-start", opts.Hybrid.ColdStart, "number of user ratings at which -model hybrid weighs collaborative and content scores equally")
+	evaluate := flag.Bool("evaluate", false, "evaluate the -model (or all models with -model all) on held-out ratings instead of recommending")
+	split := DefaultSplitConfig()
+	method := flag.String("split", string(split.Method), "evaluation split of each user's ratings: leave-one-out, temporal (last rows) or random")
+	flag.Float64Var(&split.TestFraction, "test-fraction", split.TestFraction, "fraction of each user's ratings held out by -split temporal and random")
+	threshold := flag.Float64("threshold", DefaultEvalConfig().Threshold, "lowest held-out rating counted as relevant by the ranking metrics")
+	serve := flag.Bool("serve", false, "run as an HTTP service with the -model instead of printing recommendations for -user")
+	addr := flag.String("addr", ":8080", "listen address of -serve")
+	retrain := flag.Duration("retrain", 10*time.Minute, "interval of full background retraining by -serve after ratings were folded into an svd or als model or a retraining failed, 0 to retrain only after changes the model cannot fold in")
+	validate := flag.Bool("validate", false, "print the JSON report of everything fixed or rejected while loading the data and exit")
+	omdbKey := flag.String("omdb-key", "", "OMDb API key, defaults to the OMDB_API_KEY environment variable")
+	cache := flag.String("cache", "omdb_cache.json", "file caching movie details fetched from OMDb")
+	offline := flag.Bool("offline", false, "show movie details only from the -cache file, without calling OMDb")
+	flag.Parse()
+	if *n < 1 {
+		fmt.Println("Error: expected a positive -n, found", *n)
+		return
+	}
+	opts.Content.Weighting = ContentWeighting(*weighting)
+	opts.Hybrid.Collaborative = Strategy(*hybridWith)
+	split.Method = SplitMethod(*method)
+	split.Seed = *seed
+	opts.Cluster.Algorithm = ClusterAlgorithm(*algorithm)
+	opts.Cluster.Metric = Metric(*metric)
+	opts.KNN.Metric = Metric(*metric)
+	opts.Cluster.Seed, opts.SVD.Seed, opts.ALS.Seed = *seed, *seed, *seed
+	if *factors > 0 {
+		opts.SVD.Factors, opts.ALS.Factors = *factors, *factors
+	}
+	if *epochs > 0 {
+		opts.SVD.Epochs, opts.ALS.Epochs = *epochs, *epochs
+	}
+	if *reg > 0 {
+		opts.SVD.Regularization, opts.ALS.Regularization = *reg, *reg
 	}
-	return "N/A"
-}
 
-func main() {
 	var movieRatings MovieRatings
-	err := movieRatings.LoadCSV("dane.csv")
+	ratingsReport, err := movieRatings.LoadCSV("dane.csv")
 	if err != nil {
 		fmt.Println("Error loading CSV:", err)
 		return
 	}
-	err = movieRatings.LoadIMDBIDs("imdb.csv")
+	imdbReport, err := movieRatings.LoadIMDBIDs("imdb.csv")
 	if err != nil {
 		fmt.Println("Error loading IMDB CSV:", err)
 		return
 	}
+	if *validate {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode([]*ValidationReport{ratingsReport, imdbReport}); err != nil {
+			fmt.Println("Error writing validation report:", err)
+		}
+		return
+	}
+	ratingsReport.Print(os.Stdout, false)
+	imdbReport.Print(os.Stdout, false)
 
-	recommendations, antiRecommendations := movieRatings.RecommendMovies(1, 2)
+	metadata, err := newMetadataProvider(*omdbKey, *cache, *offline)
+	if err != nil {
+		fmt.Println("Error loading movie details cache:", err)
+		return
+	}
+	opts.Metadata = metadata
 
-	fmt.Println("Top 5 Recommended Movies:")
-	for _, movie := range recommendations {
-		fmt.Println(movie)
-		imdbID, err := movieRatings.GetIMDBIDByTitle(movie)
-		if err != nil {
-			fmt.Println("Error fetching movie details:", err)
-		}
-		if details, err := getMovieDetails(imdbID); err == nil {
-			fmt.Println(details)
-		} else {
-			fmt.Println("Error fetching movie details:", err)
+	if *serve {
+		if err := runServe(&movieRatings, Strategy(*strategy), opts, *addr, *retrain); err != nil {
+			fmt.Println("Error serving:", err)
 		}
+		return
 	}
 
-	fmt.Println("\nTop 5 Anti-Recommended Movies:")
-	for _, movie := range antiRecommendations {
-		fmt.Println(movie)
-		imdbID, err := movieRatings.GetIMDBIDByTitle(movie)
-		if err != nil {
-			fmt.Println("Error fetching movie details:", err)
+	if *evaluate {
+		strategies := []Strategy{Strategy(*strategy)}
+		if *strategy == "all" {
+			strategies = Strategies()
 		}
-		if details, err := getMovieDetails(imdbID); err == nil {
-			fmt.Println(details)
-		} else {
-			fmt.Println("Error fetching movie details:", err)
+		if err := runEvaluation(&movieRatings, strategies, split, opts, EvalConfig{K: *n, Threshold: *threshold}); err != nil {
+			fmt.Println("Error evaluating:", err)
 		}
+		return
+	}
+
+	recommender, err := NewRecommender(Strategy(*strategy), &movieRatings, opts)
+	if err != nil {
+		fmt.Println("Error training the model:", err)
+		return
+	}
+	printMissingMetadata(Strategy(*strategy), missingMetadata(recommender), len(movieRatings.Titles()))
+	recommendations, antiRecommendations, err := movieRatings.Recommend(recommender, *personID, *n)
+	if err != nil {
+		fmt.Println("Error recommending movies:", err)
+		return
 	}
 
+	printRecommendations(&movieRatings, metadata, fmt.Sprintf("Top %d Recommended Movies:", *n), recommendations)
+	fmt.Println()
+	printRecommendations(&movieRatings, metadata, fmt.Sprintf("Top %d Anti-Recommended Movies:", *n), antiRecommendations)
 }
@@ -0,0 +1,232 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrMovieNotFound oznacza, że dostawca nie zna filmu o podanym ID.
+var ErrMovieNotFound = errors.New("movie not found")
+
+// MovieDetails to szczegóły filmu; nazwy pól JSON są takie jak w odpowiedziach OMDb.
+type MovieDetails struct {
+	IMDBID     string `json:"imdbID"`
+	Title      string `json:"Title"`
+	Year       string `json:"Year"`
+	Rated      string `json:"Rated"`
+	Released   string `json:"Released"`
+	Runtime    string `json:"Runtime"`
+	Genre      string `json:"Genre"`
+	Director   string `json:"Director"`
+	Actors     string `json:"Actors"`
+	Plot       string `json:"Plot"`
+	IMDBRating string `json:"imdbRating"`
+}
+
+// String zwraca szczegóły w postaci kolejnych linii "Pole: wartość"; brakujące pola to "N/A".
+func (d *MovieDetails) String() string {
+	na := func(s string) string {
+		if s == "" {
+			return "N/A"
+		}
+		return s
+	}
+	return fmt.Sprintf("Title: %s\nYear: %s\nRated: %s\nReleased: %s\nRuntime: %s\nGenre: %s\nDirector: %s\nActors: %s\nPlot: %s\nIMDB Rating: %s\n",
+		na(d.Title), na(d.Year), na(d.Rated), na(d.Released), na(d.Runtime), na(d.Genre),
+		na(d.Director), na(d.Actors), na(d.Plot), na(d.IMDBRating))
+}
+
+// MetadataProvider dostarcza szczegóły filmu na podstawie jego ID z bazy IMDB (np. "tt0133093").
+type MetadataProvider interface {
+	MovieDetails(imdbID string) (*MovieDetails, error)
+}
+
+// normalizeIMDBID usuwa białe znaki wokół ID. Przedrostek "tt" jest częścią ID
+// i musi zostać, inaczej OMDb nie znajduje filmu.
+func normalizeIMDBID(imdbID string) (string, error) {
+	imdbID = strings.TrimSpace(imdbID)
+	if imdbID == "" {
+		return "", errors.New("empty IMDB ID")
+	}
+	return imdbID, nil
+}
+
+// DefaultOMDbURL to adres API OMDb.
+const DefaultOMDbURL = "http://www.omdbapi.com/"
+
+// omdbClient to klient używany, gdy OMDbProvider nie ma własnego. Limit czasu
+// chroni przed zawieszeniem rekomendacji i serwera na niedostępnym API.
+var omdbClient = &http.Client{Timeout: 10 * time.Second}
+
+// OMDbProvider pobiera szczegóły filmów z API OMDb.
+type OMDbProvider struct {
+	APIKey  string
+	BaseURL string       // adres API; w testach adres serwera httptest
+	Client  *http.Client // nil oznacza klienta z 10-sekundowym limitem czasu
+}
+
+// NewOMDbProvider tworzy dostawcę OMDb. Pusty apiKey oznacza klucz ze zmiennej
+// środowiskowej OMDB_API_KEY.
+func NewOMDbProvider(apiKey string) (*OMDbProvider, error) {
+	if apiKey == "" {
+		apiKey = os.Getenv("OMDB_API_KEY")
+	}
+	if apiKey == "" {
+		return nil, errors.New("no OMDb API key: set OMDB_API_KEY or pass -omdb-key")
+	}
+	return &OMDbProvider{APIKey: apiKey, BaseURL: DefaultOMDbURL, Client: omdbClient}, nil
+}
+
+// MovieDetails wysyła zapytanie do OMDb i dekoduje odpowiedź JSON.
+// Odpowiedź z "Response": "False" zamienia na ErrMovieNotFound.
+func (p *OMDbProvider) MovieDetails(imdbID string) (*MovieDetails, error) {
+	imdbID, err := normalizeIMDBID(imdbID)
+	if err != nil {
+		return nil, err
+	}
+	client := p.Client
+	if client == nil {
+		client = omdbClient
+	}
+	query := url.Values{"apikey": {p.APIKey}, "i": {imdbID}}
+	resp, err := client.Get(p.BaseURL + "?" + query.Encode())
+	if err != nil {
+		return nil, fmt.Errorf("could not fetch movie details: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("received non-OK response code: %d", resp.StatusCode)
+	}
+
+	var result struct {
+		MovieDetails
+		Response string
+		Error    string
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("could not decode response: %v", err)
+	}
+	if result.Response != "True" {
+		return nil, fmt.Errorf("%w: %s: %s", ErrMovieNotFound, imdbID, result.Error)
+	}
+	if result.IMDBID == "" {
+		result.IMDBID = imdbID
+	}
+	return &result.MovieDetails, nil
+}
+
+// FixtureProvider to dostawca ze stałymi danymi (ID -> szczegóły), do testów
+// i pracy bez sieci.
+type FixtureProvider map[string]*MovieDetails
+
+// LoadFixtures wczytuje FixtureProvider z pliku JSON w formacie pliku CachedProvider.
+func LoadFixtures(filename string) (FixtureProvider, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("could not read fixtures: %v", err)
+	}
+	fixtures := make(FixtureProvider)
+	if err := json.Unmarshal(data, &fixtures); err != nil {
+		return nil, fmt.Errorf("could not decode fixtures %s: %v", filename, err)
+	}
+	return fixtures, nil
+}
+
+// MovieDetails zwraca szczegóły z danych albo ErrMovieNotFound.
+func (f FixtureProvider) MovieDetails(imdbID string) (*MovieDetails, error) {
+	imdbID, err := normalizeIMDBID(imdbID)
+	if err != nil {
+		return nil, err
+	}
+	details, ok := f[imdbID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrMovieNotFound, imdbID)
+	}
+	return details, nil
+}
+
+// CachedProvider zapamiętuje szczegóły filmów w pliku JSON (ID -> szczegóły),
+// żeby każdy film był pobierany z sieci tylko raz. Bez dostawcy źródłowego
+// (Source == nil) działa tylko na zapisanych danych.
+type CachedProvider struct {
+	Source MetadataProvider
+
+	path    string
+	mu      sync.Mutex
+	entries map[string]*MovieDetails
+}
+
+// NewCachedProvider wczytuje pamięć podręczną z pliku; brak pliku oznacza pustą pamięć.
+func NewCachedProvider(source MetadataProvider, filename string) (*CachedProvider, error) {
+	c := &CachedProvider{Source: source, path: filename, entries: make(map[string]*MovieDetails)}
+	data, err := os.ReadFile(filename)
+	if errors.Is(err, os.ErrNotExist) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not read metadata cache: %v", err)
+	}
+	if err := json.Unmarshal(data, &c.entries); err != nil {
+		return nil, fmt.Errorf("could not decode metadata cache %s: %v", filename, err)
+	}
+	return c, nil
+}
+
+// MovieDetails zwraca zapisane szczegóły, a w razie ich braku pobiera je ze
+// źródła i zapisuje plik. Pobieranie odbywa się bez blokady, więc wolne
+// zapytanie o jeden film nie wstrzymuje odczytów innych filmów.
+func (c *CachedProvider) MovieDetails(imdbID string) (*MovieDetails, error) {
+	imdbID, err := normalizeIMDBID(imdbID)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	details, ok := c.entries[imdbID]
+	c.mu.Unlock()
+	if ok {
+		return details, nil
+	}
+	if c.Source == nil {
+		return nil, fmt.Errorf("%w in the offline cache: %s", ErrMovieNotFound, imdbID)
+	}
+	details, err = c.Source.MovieDetails(imdbID)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[imdbID] = details
+	return details, c.save()
+}
+
+// save zapisuje pamięć przez plik tymczasowy, żeby przerwany zapis nie zniszczył danych.
+// Wywołujący musi trzymać c.mu.
+func (c *CachedProvider) save() error {
+	data, err := json.MarshalIndent(c.entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(c.path), filepath.Base(c.path)+".*")
+	if err != nil {
+		return fmt.Errorf("could not write metadata cache: %v", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("could not write metadata cache: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("could not write metadata cache: %v", err)
+	}
+	return os.Rename(tmp.Name(), c.path)
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// omdbServer udaje API OMDb: zna tylko film tt0133093, a na ID "tt500" odpowiada błędem serwera.
+func omdbServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("apikey") != "secret" {
+			t.Errorf("expected API key %q, found %q", "secret", r.URL.Query().Get("apikey"))
+		}
+		switch r.URL.Query().Get("i") {
+		case "tt0133093":
+			w.Write([]byte(`{"Title":"The Matrix","Year":"1999","Genre":"Action, Sci-Fi","imdbID":"tt0133093","Response":"True"}`))
+		case "tt500":
+			http.Error(w, "internal error", http.StatusInternalServerError)
+		default:
+			w.Write([]byte(`{"Response":"False","Error":"Incorrect IMDb ID."}`))
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestOMDbProvider(t *testing.T) {
+	server := omdbServer(t)
+	p := &OMDbProvider{APIKey: "secret", BaseURL: server.URL, Client: server.Client()}
+
+	details, err := p.MovieDetails("  tt0133093 ")
+	if err != nil {
+		t.Fatalf("MovieDetails: %v", err)
+	}
+	if details.Title != "The Matrix" || details.IMDBID != "tt0133093" || details.Genre != "Action, Sci-Fi" {
+		t.Errorf("unexpected details %+v", details)
+	}
+
+	if _, err := p.MovieDetails("tt0000000"); !errors.Is(err, ErrMovieNotFound) {
+		t.Errorf("expected ErrMovieNotFound for Response False, found %v", err)
+	} else if !strings.Contains(err.Error(), "Incorrect IMDb ID.") {
+		t.Errorf("expected the OMDb error in %q", err)
+	}
+
+	if _, err := p.MovieDetails("tt500"); err == nil || errors.Is(err, ErrMovieNotFound) {
+		t.Errorf("expected a response code error, found %v", err)
+	}
+
+	if _, err := p.MovieDetails(" "); err == nil {
+		t.Error("expected an error for an empty ID")
+	}
+}
+
+func TestCachedProviderRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "metadata.json")
+	source := FixtureProvider{"tt0133093": {IMDBID: "tt0133093", Title: "The Matrix", Director: "Lana Wachowski, Lilly Wachowski"}}
+	cache, err := NewCachedProvider(source, path)
+	if err != nil {
+		t.Fatalf("NewCachedProvider: %v", err)
+	}
+	if _, err := cache.MovieDetails("tt0133093"); err != nil {
+		t.Fatalf("MovieDetails: %v", err)
+	}
+	if _, err := cache.MovieDetails("tt0111161"); !errors.Is(err, ErrMovieNotFound) {
+		t.Errorf("expected ErrMovieNotFound from the source, found %v", err)
+	}
+
+	offline, err := NewCachedProvider(nil, path)
+	if err != nil {
+		t.Fatalf("NewCachedProvider offline: %v", err)
+	}
+	details, err := offline.MovieDetails("tt0133093")
+	if err != nil {
+		t.Fatalf("MovieDetails offline: %v", err)
+	}
+	if *details != *source["tt0133093"] {
+		t.Errorf("expected %+v after reloading the cache, found %+v", source["tt0133093"], details)
+	}
+	if _, err := offline.MovieDetails("tt0111161"); !errors.Is(err, ErrMovieNotFound) {
+		t.Errorf("expected ErrMovieNotFound offline, found %v", err)
+	}
+
+	fixtures, err := LoadFixtures(path)
+	if err != nil {
+		t.Fatalf("LoadFixtures: %v", err)
+	}
+	if len(fixtures) != 1 || fixtures["tt0133093"].Title != "The Matrix" {
+		t.Errorf("expected the cached movie in fixtures, found %v", fixtures)
+	}
+}
@@ -0,0 +1,358 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"math"
+	"math/rand"
+)
+
+// SVDConfig opisuje uczenie modelu SVD.
+type SVDConfig struct {
+	Factors        int     // liczba ukrytych czynników
+	Epochs         int     // liczba przejść po wszystkich ocenach
+	LearningRate   float64 // krok spadku gradientu
+	Regularization float64 // kara za duże czynniki i obciążenia
+	Seed           int64   // ziarno losowej inicjalizacji i kolejności ocen
+}
+
+// DefaultSVDConfig zwraca ustawienia dobrane do niewielkiej ankiety z dane.csv.
+func DefaultSVDConfig() SVDConfig {
+	return SVDConfig{Factors: 8, Epochs: 200, LearningRate: 0.01, Regularization: 0.5, Seed: 1}
+}
+
+// SVD to model czynników ukrytych z obciążeniami (Funk SVD). Ocena użytkownika u
+// dla filmu i jest przewidywana jako
+//
+//	średnia + obciążenie u + obciążenie i + <czynniki u, czynniki i>
+//
+// i przycinana do skali ocen z danych uczących.
+type SVD struct {
+	cfg         SVDConfig
+	mean        float64
+	min, max    float64
+	userBias    map[int]float64
+	itemBias    map[string]float64
+	userFactors map[int][]float64
+	itemFactors map[string][]float64
+}
+
+// TrainSVD uczy model SVD stochastycznym spadkiem gradientu na ocenach.
+func TrainSVD(ratings []MovieRating, cfg SVDConfig) (*SVD, error) {
+	if len(ratings) == 0 {
+		return nil, errors.New("no ratings to train on")
+	}
+	if cfg.Factors < 1 || cfg.Epochs < 1 || cfg.LearningRate <= 0 || cfg.Regularization < 0 {
+		return nil, fmt.Errorf("invalid SVD configuration %+v", cfg)
+	}
+	rng := rand.New(rand.NewSource(cfg.Seed))
+	m := &SVD{
+		cfg:         cfg,
+		min:         math.Inf(1),
+		max:         math.Inf(-1),
+		userBias:    make(map[int]float64),
+		itemBias:    make(map[string]float64),
+		userFactors: make(map[int][]float64),
+		itemFactors: make(map[string][]float64),
+	}
+	randomFactors := func() []float64 {
+		f := make([]float64, cfg.Factors)
+		for i := range f {
+			f[i] = rng.NormFloat64() * 0.1
+		}
+		return f
+	}
+	for _, r := range ratings {
+		m.mean += r.Rating
+		m.min = math.Min(m.min, r.Rating)
+		m.max = math.Max(m.max, r.Rating)
+		if _, ok := m.userFactors[r.PersonID]; !ok {
+			m.userFactors[r.PersonID] = randomFactors()
+		}
+		if _, ok := m.itemFactors[r.MovieTitle]; !ok {
+			m.itemFactors[r.MovieTitle] = randomFactors()
+		}
+	}
+	m.mean /= float64(len(ratings))
+
+	for epoch := 0; epoch < cfg.Epochs; epoch++ {
+		for _, k := range rng.Perm(len(ratings)) {
+			r := ratings[k]
+			p, q := m.userFactors[r.PersonID], m.itemFactors[r.MovieTitle]
+			e := r.Rating - (m.mean + m.userBias[r.PersonID] + m.itemBias[r.MovieTitle] + dot(p, q))
+			m.userBias[r.PersonID] += cfg.LearningRate * (e - cfg.Regularization*m.userBias[r.PersonID])
+			m.itemBias[r.MovieTitle] += cfg.LearningRate * (e - cfg.Regularization*m.itemBias[r.MovieTitle])
+			for f := range p {
+				pf, qf := p[f], q[f]
+				p[f] += cfg.LearningRate * (e*qf - cfg.Regularization*pf)
+				q[f] += cfg.LearningRate * (e*pf - cfg.Regularization*qf)
+			}
+		}
+	}
+	return m, nil
+}
+
+// Predict przewiduje ocenę filmu title przez użytkownika personID.
+func (m *SVD) Predict(personID int, title string) (float64, error) {
+	p, ok := m.userFactors[personID]
+	if !ok {
+		return 0, fmt.Errorf("%w %d", ErrUnknownUser, personID)
+	}
+	q, ok := m.itemFactors[title]
+	if !ok {
+		return 0, fmt.Errorf("%w for unknown movie %q", ErrNoPrediction, title)
+	}
+	x := m.mean + m.userBias[personID] + m.itemBias[title] + dot(p, q)
+	return math.Max(m.min, math.Min(m.max, x)), nil
+}
+
+// FoldIn zwraca kopię modelu, w której obciążenie i czynniki użytkownika personID
+// wyznaczono na nowo z jego ocen ratings tym samym spadkiem gradientu co w TrainSVD,
+// ale przy ustalonych parametrach filmów. Oceny filmów nieznanych modelowi są
+// pomijane, a użytkownik bez pozostałych ocen znika z modelu.
+func (m *SVD) FoldIn(personID int, ratings []MovieRating) (Incremental, error) {
+	c := *m
+	c.userBias, c.userFactors = maps.Clone(m.userBias), maps.Clone(m.userFactors)
+	delete(c.userBias, personID)
+	delete(c.userFactors, personID)
+	var known []MovieRating
+	for _, r := range ratings {
+		if _, ok := m.itemFactors[r.MovieTitle]; ok && r.PersonID == personID {
+			known = append(known, r)
+		}
+	}
+	if len(known) == 0 {
+		return &c, nil
+	}
+	rng := rand.New(rand.NewSource(m.cfg.Seed + int64(personID)))
+	p := make([]float64, m.cfg.Factors)
+	for f := range p {
+		p[f] = rng.NormFloat64() * 0.1
+	}
+	bias := 0.0
+	for epoch := 0; epoch < m.cfg.Epochs; epoch++ {
+		for _, k := range rng.Perm(len(known)) {
+			r := known[k]
+			q := m.itemFactors[r.MovieTitle]
+			e := r.Rating - (m.mean + bias + m.itemBias[r.MovieTitle] + dot(p, q))
+			bias += m.cfg.LearningRate * (e - m.cfg.Regularization*bias)
+			for f := range p {
+				p[f] += m.cfg.LearningRate * (e*q[f] - m.cfg.Regularization*p[f])
+			}
+		}
+	}
+	c.userBias[personID], c.userFactors[personID] = bias, p
+	return &c, nil
+}
+
+// ALSConfig opisuje uczenie modelu ALS dla danych niejawnych.
+type ALSConfig struct {
+	Factors        int     // liczba ukrytych czynników
+	Epochs         int     // liczba naprzemiennych kroków (użytkownicy, potem filmy)
+	Regularization float64 // kara za duże czynniki
+	Alpha          float64 // wzrost pewności na jednostkę oceny: pewność = 1 + Alpha * ocena
+	Seed           int64   // ziarno losowej inicjalizacji
+}
+
+// DefaultALSConfig zwraca ustawienia dobrane do niewielkiej ankiety z dane.csv.
+func DefaultALSConfig() ALSConfig {
+	return ALSConfig{Factors: 8, Epochs: 15, Regularization: 0.1, Alpha: 2, Seed: 1}
+}
+
+// ALS to model czynników ukrytych dla danych niejawnych (Hu, Koren, Volinsky).
+// Ocena jest traktowana tylko jako sygnał, że użytkownik obejrzał film, z pewnością
+// rosnącą wraz z oceną; film nieobejrzany to słaby sygnał braku zainteresowania.
+// Predict zwraca przewidywaną preferencję, zwykle z przedziału [0, 1], a nie ocenę.
+type ALS struct {
+	cfg         ALSConfig
+	users       map[int]int
+	items       map[string]int
+	userFactors [][]float64
+	itemFactors [][]float64
+}
+
+// TrainALS uczy model ALS naprzemiennie wyznaczając czynniki użytkowników
+// i filmów metodą najmniejszych kwadratów z wagami pewności.
+func TrainALS(ratings []MovieRating, cfg ALSConfig) (*ALS, error) {
+	if len(ratings) == 0 {
+		return nil, errors.New("no ratings to train on")
+	}
+	if cfg.Factors < 1 || cfg.Epochs < 1 || cfg.Regularization <= 0 || cfg.Alpha < 0 {
+		return nil, fmt.Errorf("invalid ALS configuration %+v", cfg)
+	}
+	rng := rand.New(rand.NewSource(cfg.Seed))
+	m := &ALS{cfg: cfg, users: make(map[int]int), items: make(map[string]int)}
+	byUser := make(map[int]map[string]float64)
+	for _, r := range ratings {
+		if _, ok := byUser[r.PersonID]; !ok {
+			byUser[r.PersonID] = make(map[string]float64)
+		}
+		byUser[r.PersonID][r.MovieTitle] = r.Rating
+	}
+	for i, user := range sortedKeys(byUser) {
+		m.users[user] = i
+	}
+	titles := make(map[string]bool)
+	for _, r := range ratings {
+		titles[r.MovieTitle] = true
+	}
+	for i, title := range sortedKeys(titles) {
+		m.items[title] = i
+	}
+
+	// confidence[u][i] > 0 tylko dla obejrzanych filmów
+	confidence := make([]map[int]float64, len(m.users))
+	itemConfidence := make([]map[int]float64, len(m.items))
+	for i := range itemConfidence {
+		itemConfidence[i] = make(map[int]float64)
+	}
+	for user, movies := range byUser {
+		u := m.users[user]
+		confidence[u] = make(map[int]float64, len(movies))
+		for title, rating := range movies {
+			c := 1 + cfg.Alpha*rating
+			confidence[u][m.items[title]] = c
+			itemConfidence[m.items[title]][u] = c
+		}
+	}
+
+	randomFactors := func(n int) [][]float64 {
+		f := make([][]float64, n)
+		for i := range f {
+			f[i] = make([]float64, cfg.Factors)
+			for j := range f[i] {
+				f[i][j] = rng.NormFloat64() * 0.1
+			}
+		}
+		return f
+	}
+	m.userFactors = randomFactors(len(m.users))
+	m.itemFactors = randomFactors(len(m.items))
+	for epoch := 0; epoch < cfg.Epochs; epoch++ {
+		alsStep(m.userFactors, m.itemFactors, confidence, cfg.Regularization)
+		alsStep(m.itemFactors, m.userFactors, itemConfidence, cfg.Regularization)
+	}
+	return m, nil
+}
+
+// alsStep wyznacza na nowo czynniki x przy ustalonych czynnikach y:
+//
+//	x_u = (YᵀY + Yᵀ(C_u - I)Y + λI)⁻¹ Yᵀ C_u p_u
+//
+// gdzie p_u to 1 dla obejrzanych filmów, a C_u to ich pewności (1 dla pozostałych).
+// YᵀY jest liczone raz, a poprawka tylko dla obejrzanych filmów.
+func alsStep(x, y [][]float64, confidence []map[int]float64, reg float64) {
+	k := len(y[0])
+	gram := make([][]float64, k)
+	for a := range gram {
+		gram[a] = make([]float64, k)
+		for b := range gram[a] {
+			for _, v := range y {
+				gram[a][b] += v[a] * v[b]
+			}
+		}
+	}
+	for u := range x {
+		a := make([][]float64, k)
+		for i := range a {
+			a[i] = append([]float64(nil), gram[i]...)
+			a[i][i] += reg
+		}
+		rhs := make([]float64, k)
+		for i, c := range confidence[u] {
+			v := y[i]
+			for p := 0; p < k; p++ {
+				rhs[p] += c * v[p]
+				for q := 0; q < k; q++ {
+					a[p][q] += (c - 1) * v[p] * v[q]
+				}
+			}
+		}
+		x[u] = solve(a, rhs)
+	}
+}
+
+// Predict zwraca przewidywaną preferencję użytkownika personID dla filmu title.
+func (m *ALS) Predict(personID int, title string) (float64, error) {
+	u, ok := m.users[personID]
+	if !ok {
+		return 0, fmt.Errorf("%w %d", ErrUnknownUser, personID)
+	}
+	i, ok := m.items[title]
+	if !ok {
+		return 0, fmt.Errorf("%w for unknown movie %q", ErrNoPrediction, title)
+	}
+	return dot(m.userFactors[u], m.itemFactors[i]), nil
+}
+
+// FoldIn zwraca kopię modelu, w której czynniki użytkownika personID wyznaczono
+// z jego ocen ratings jednym krokiem alsStep przy ustalonych czynnikach filmów.
+// Oceny filmów nieznanych modelowi są pomijane, a użytkownik bez pozostałych
+// ocen znika z modelu.
+func (m *ALS) FoldIn(personID int, ratings []MovieRating) (Incremental, error) {
+	c := *m
+	c.users = maps.Clone(m.users)
+	c.userFactors = append([][]float64(nil), m.userFactors...)
+	confidence := make(map[int]float64)
+	for _, r := range ratings {
+		if i, ok := m.items[r.MovieTitle]; ok && r.PersonID == personID {
+			confidence[i] = 1 + m.cfg.Alpha*r.Rating
+		}
+	}
+	u, known := c.users[personID]
+	if len(confidence) == 0 {
+		delete(c.users, personID)
+		return &c, nil
+	}
+	if !known {
+		u = len(c.userFactors)
+		c.users[personID] = u
+		c.userFactors = append(c.userFactors, nil)
+	}
+	x := make([][]float64, 1)
+	alsStep(x, m.itemFactors, []map[int]float64{confidence}, m.cfg.Regularization)
+	c.userFactors[u] = x[0]
+	return &c, nil
+}
+
+// dot zwraca iloczyn skalarny wektorów.
+func dot(a, b []float64) float64 {
+	s := 0.0
+	for i := range a {
+		s += a[i] * b[i]
+	}
+	return s
+}
+
+// solve rozwiązuje układ a x = b eliminacją Gaussa z wyborem elementu głównego.
+// Macierz jest dodatnio określona dzięki regularyzacji, więc układ ma rozwiązanie.
+func solve(a [][]float64, b []float64) []float64 {
+	n := len(b)
+	for col := 0; col < n; col++ {
+		pivot := col
+		for r := col + 1; r < n; r++ {
+			if math.Abs(a[r][col]) > math.Abs(a[pivot][col]) {
+				pivot = r
+			}
+		}
+		a[col], a[pivot] = a[pivot], a[col]
+		b[col], b[pivot] = b[pivot], b[col]
+		for r := col + 1; r < n; r++ {
+			f := a[r][col] / a[col][col]
+			for c := col; c < n; c++ {
+				a[r][c] -= f * a[col][c]
+			}
+			b[r] -= f * b[col]
+		}
+	}
+	x := make([]float64, n)
+	for r := n - 1; r >= 0; r-- {
+		s := b[r]
+		for c := r + 1; c < n; c++ {
+			s -= a[r][c] * x[c]
+		}
+		x[r] = s / a[r][r]
+	}
+	return x
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestFoldInKeepsTheOriginalModel(t *testing.T) {
+	data := surveyRatings()
+	svd, err := TrainSVD(data.Ratings, DefaultSVDConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	als, err := TrainALS(data.Ratings, DefaultALSConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	newUser := []MovieRating{{PersonID: 7, MovieTitle: "D", Rating: 10}, {PersonID: 7, MovieTitle: "Z", Rating: 1}}
+	for name, model := range map[string]Incremental{"svd": svd, "als": als} {
+		before, _ := model.Predict(1, "C")
+		folded, err := model.FoldIn(7, newUser)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := folded.Predict(7, "F"); err != nil {
+			t.Errorf("%s: expected a prediction for the folded-in user, found %v", name, err)
+		}
+		if _, err := model.Predict(7, "F"); !errors.Is(err, ErrUnknownUser) {
+			t.Errorf("%s: expected the original model not to know the new user, found %v", name, err)
+		}
+		if after, _ := folded.Predict(1, "C"); after != before {
+			t.Errorf("%s: expected other users to keep their predictions, found %g instead of %g", name, after, before)
+		}
+
+		removed, err := folded.FoldIn(1, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := removed.Predict(1, "C"); !errors.Is(err, ErrUnknownUser) {
+			t.Errorf("%s: expected a user without ratings to leave the model, found %v", name, err)
+		}
+		if _, err := folded.Predict(1, "C"); err != nil {
+			t.Errorf("%s: expected removing a user to keep the folded model intact, found %v", name, err)
+		}
+	}
+}
+
+func TestMatrixFactorizationConfig(t *testing.T) {
+	data := surveyRatings().Ratings
+	for _, cfg := range []SVDConfig{
+		{Factors: 0, Epochs: 10, LearningRate: 0.01},
+		{Factors: 2, Epochs: 0, LearningRate: 0.01},
+		{Factors: 2, Epochs: 10, LearningRate: 0},
+		{Factors: 2, Epochs: 10, LearningRate: 0.01, Regularization: -1},
+	} {
+		if _, err := TrainSVD(data, cfg); err == nil {
+			t.Errorf("expected an error for %+v", cfg)
+		}
+	}
+	for _, cfg := range []ALSConfig{
+		{Factors: 0, Epochs: 10, Regularization: 0.1},
+		{Factors: 2, Epochs: 0, Regularization: 0.1},
+		{Factors: 2, Epochs: 10, Regularization: 0},
+		{Factors: 2, Epochs: 10, Regularization: 0.1, Alpha: -1},
+	} {
+		if _, err := TrainALS(data, cfg); err == nil {
+			t.Errorf("expected an error for %+v", cfg)
+		}
+	}
+	if _, err := TrainSVD(nil, DefaultSVDConfig()); err == nil {
+		t.Error("expected an error for SVD without ratings")
+	}
+	if _, err := TrainALS(nil, DefaultALSConfig()); err == nil {
+		t.Error("expected an error for ALS without ratings")
+	}
+}
+
+func TestMatrixFactorizationUnknown(t *testing.T) {
+	data := surveyRatings().Ratings
+	svd, err := TrainSVD(data, DefaultSVDConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	als, err := TrainALS(data, DefaultALSConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, model := range map[string]Recommender{"svd": svd, "als": als} {
+		if _, err := model.Predict(99, "A"); !errors.Is(err, ErrUnknownUser) {
+			t.Errorf("%s: expected ErrUnknownUser, found %v", name, err)
+		}
+		if _, err := model.Predict(1, "Z"); !errors.Is(err, ErrNoPrediction) {
+			t.Errorf("%s: expected ErrNoPrediction for an unknown movie, found %v", name, err)
+		}
+		if _, err := model.Predict(1, "C"); err != nil {
+			t.Errorf("%s: expected a prediction for a known user and movie, found %v", name, err)
+		}
+	}
+}
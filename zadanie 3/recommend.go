@@ -0,0 +1,193 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Błędy zwracane przez Predict; można je rozpoznać przez errors.Is.
+var (
+	// ErrUnknownUser oznacza użytkownika bez żadnych ocen w danych uczących.
+	ErrUnknownUser = errors.New("unknown user")
+	// ErrNoPrediction oznacza, że model nie ma podstaw do oceny filmu dla użytkownika,
+	// np. nikt z jego grupy nie widział filmu.
+	ErrNoPrediction = errors.New("no prediction")
+)
+
+// Recommender przewiduje, jak użytkownik oceniłby film. Modele ocen (np. SVD)
+// zwracają ocenę w skali danych, modele preferencji (ALS) wynik, w którym
+// większa wartość oznacza większe prawdopodobieństwo, że film się spodoba.
+type Recommender interface {
+	Predict(personID int, title string) (float64, error)
+}
+
+// Explainer to model, który potrafi uzasadnić swoje przewidywanie,
+// np. "because you rated X and Y highly".
+type Explainer interface {
+	Explain(personID int, title string) (string, error)
+}
+
+// Incremental to model, który potrafi przyjąć zmienione oceny jednego użytkownika
+// bez uczenia od nowa (fold-in): parametry filmów zostają bez zmian, a parametry
+// użytkownika są wyznaczane z jego ocen. FoldIn nie zmienia modelu, tylko zwraca
+// jego kopię, więc stary model można dalej czytać współbieżnie.
+type Incremental interface {
+	Recommender
+	FoldIn(personID int, ratings []MovieRating) (Incremental, error)
+}
+
+// Recommendation to film z przewidywanym wynikiem dla użytkownika i uzasadnieniem,
+// jeśli model je podaje.
+type Recommendation struct {
+	Title  string
+	IMDBID string
+	Score  float64
+	Reason string
+}
+
+// Recommend ocenia modelem r wszystkie filmy, których użytkownik nie oglądał,
+// i zwraca n najlepszych (malejąco) oraz n najgorszych (rosnąco, antyrekomendacje).
+// Filmy, dla których model nie ma przewidywania, są pomijane. Listy się nie
+// pokrywają: przy mniej niż 2n kandydatach lepsza połowa trafia do rekomendacji,
+// a gorsza do antyrekomendacji. Jeśli model jest Explainerem, rekomendacje zawierają uzasadnienia.
+// n musi być dodatnie.
+func (mr *MovieRatings) Recommend(r Recommender, personID int, n int) ([]Recommendation, []Recommendation, error) {
+	if n < 1 {
+		return nil, nil, fmt.Errorf("expected a positive number of movies, found %d", n)
+	}
+	seen := make(map[string]bool)
+	for _, rating := range mr.Ratings {
+		if rating.PersonID == personID {
+			seen[rating.MovieTitle] = true
+		}
+	}
+	for _, rating := range mr.Unrated {
+		if rating.PersonID == personID {
+			seen[rating.MovieTitle] = true
+		}
+	}
+	if len(seen) == 0 {
+		return nil, nil, fmt.Errorf("%w %d", ErrUnknownUser, personID)
+	}
+	var scored []Recommendation
+	for _, title := range mr.Titles() {
+		if seen[title] {
+			continue
+		}
+		score, err := r.Predict(personID, title)
+		if errors.Is(err, ErrNoPrediction) {
+			continue
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		imdbID, _ := mr.GetIMDBIDByTitle(title)
+		scored = append(scored, Recommendation{Title: title, IMDBID: imdbID, Score: score})
+	}
+	sort.SliceStable(scored, func(i, j int) bool { return scored[i].Score > scored[j].Score })
+
+	top := append([]Recommendation(nil), scored[:min(n, len(scored)-len(scored)/2)]...)
+	var bottom []Recommendation
+	for i := len(scored) - 1; i >= len(top) && len(bottom) < n; i-- {
+		bottom = append(bottom, scored[i])
+	}
+	if explainer, ok := r.(Explainer); ok {
+		for _, list := range [][]Recommendation{top, bottom} {
+			for i := range list {
+				reason, err := explainer.Explain(personID, list[i].Title)
+				if err != nil {
+					return nil, nil, err
+				}
+				list[i].Reason = reason
+			}
+		}
+	}
+	return top, bottom, nil
+}
+
+// Strategy to nazwa algorytmu rekomendacji.
+type Strategy string
+
+const (
+	// StrategyCluster to średnia ocen w grupie użytkownika (ClusterRecommender).
+	StrategyCluster Strategy = "cluster"
+	// StrategySVD to rozkład macierzy ocen z obciążeniami (SVD).
+	StrategySVD Strategy = "svd"
+	// StrategyALS to rozkład macierzy dla danych niejawnych (ALS).
+	StrategyALS Strategy = "als"
+	// StrategyUserKNN to najbliżsi sąsiedzi wśród użytkowników (UserKNN).
+	StrategyUserKNN Strategy = "user-knn"
+	// StrategyItemKNN to najbliżsi sąsiedzi wśród filmów (ItemKNN).
+	StrategyItemKNN Strategy = "item-knn"
+	// StrategyContent to podobieństwo gatunków, reżyserów, aktorów i fabuły (ContentBased).
+	StrategyContent Strategy = "content"
+	// StrategyHybrid to połączenie strategii kolaboratywnej z treścią (Hybrid).
+	StrategyHybrid Strategy = "hybrid"
+)
+
+// Strategies zwraca wszystkie dostępne strategie.
+func Strategies() []Strategy {
+	return []Strategy{StrategyCluster, StrategySVD, StrategyALS, StrategyUserKNN, StrategyItemKNN, StrategyContent, StrategyHybrid}
+}
+
+// Options to ustawienia wszystkich modeli; NewRecommender używa tych, które
+// dotyczą wybranej strategii.
+type Options struct {
+	Cluster ClusterConfig
+	SVD     SVDConfig
+	ALS     ALSConfig
+	KNN     KNNConfig
+	Content ContentConfig
+	Hybrid  HybridConfig
+
+	// Metadata dostarcza szczegóły filmów strategiom content i hybrid.
+	Metadata MetadataProvider
+}
+
+// DefaultOptions zwraca domyślne ustawienia wszystkich modeli, bez dostawcy metadanych.
+func DefaultOptions() Options {
+	return Options{
+		Cluster: DefaultClusterConfig(),
+		SVD:     DefaultSVDConfig(),
+		ALS:     DefaultALSConfig(),
+		KNN:     DefaultKNNConfig(),
+		Content: DefaultContentConfig(),
+		Hybrid:  DefaultHybridConfig(),
+	}
+}
+
+// NewRecommender buduje model wybranej strategii na ocenach mr.
+func NewRecommender(strategy Strategy, mr *MovieRatings, opts Options) (Recommender, error) {
+	switch strategy {
+	case StrategyCluster:
+		return NewClusterRecommender(mr, opts.Cluster)
+	case StrategySVD:
+		return TrainSVD(mr.Ratings, opts.SVD)
+	case StrategyALS:
+		return TrainALS(mr.Ratings, opts.ALS)
+	case StrategyUserKNN:
+		return NewUserKNN(mr, opts.KNN)
+	case StrategyItemKNN:
+		return NewItemKNN(mr, opts.KNN)
+	case StrategyContent:
+		return NewContentBased(mr, opts.Metadata, opts.Content)
+	case StrategyHybrid:
+		return NewHybrid(mr, opts)
+	}
+	names := make([]string, 0, len(Strategies()))
+	for _, s := range Strategies() {
+		names = append(names, string(s))
+	}
+	return nil, fmt.Errorf("unknown strategy %q (want %s)", strategy, strings.Join(names, ", "))
+}
+
+// Titles zwraca tytuły wszystkich ocenionych filmów w porządku alfabetycznym.
+func (mr *MovieRatings) Titles() []string {
+	titles := make(map[string]bool)
+	for _, rating := range mr.Ratings {
+		titles[rating.MovieTitle] = true
+	}
+	return sortedKeys(titles)
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+// stubRecommender przewiduje stałe wyniki z mapy, a dla pozostałych filmów zwraca ErrNoPrediction.
+type stubRecommender map[string]float64
+
+func (s stubRecommender) Predict(personID int, title string) (float64, error) {
+	if score, ok := s[title]; ok {
+		return score, nil
+	}
+	return 0, ErrNoPrediction
+}
+
+// smallRatings to cztery filmy ocenione przez dwóch użytkowników; użytkownik 1 widział tylko "A".
+func smallRatings() *MovieRatings {
+	return &MovieRatings{Ratings: []MovieRating{
+		{PersonID: 1, MovieTitle: "A", Rating: 8},
+		{PersonID: 2, MovieTitle: "A", Rating: 6},
+		{PersonID: 2, MovieTitle: "B", Rating: 9},
+		{PersonID: 2, MovieTitle: "C", Rating: 4},
+		{PersonID: 2, MovieTitle: "D", Rating: 2},
+	}}
+}
+
+func TestRecommend(t *testing.T) {
+	mr := smallRatings()
+	model := stubRecommender{"A": 10, "B": 7, "C": 3}
+
+	top, bottom, err := mr.Recommend(model, 1, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// "A" jest obejrzany, a "D" nie ma przewidywania; z dwóch kandydatów lepszy
+	// trafia do rekomendacji, a gorszy do antyrekomendacji.
+	if len(top) != 1 || top[0].Title != "B" || len(bottom) != 1 || bottom[0].Title != "C" {
+		t.Errorf("expected [B] and [C], found %v and %v", titles(top), titles(bottom))
+	}
+
+	if _, _, err := mr.Recommend(model, 3, 5); !errors.Is(err, ErrUnknownUser) {
+		t.Errorf("expected ErrUnknownUser, found %v", err)
+	}
+	for _, n := range []int{0, -1} {
+		if _, _, err := mr.Recommend(model, 1, n); err == nil {
+			t.Errorf("expected an error for n = %d", n)
+		}
+	}
+}
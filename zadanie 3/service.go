@@ -0,0 +1,234 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// ratingRequest to treść POST /ratings; brak oceny (null) oznacza film obejrzany bez oceny.
+type ratingRequest struct {
+	User   int      `json:"user"`
+	Title  string   `json:"title"`
+	IMDBID string   `json:"imdbID,omitempty"`
+	Rating *float64 `json:"rating"`
+}
+
+// ratingItem to ocena filmu w odpowiedziach; Rating jest null dla filmu obejrzanego bez oceny.
+type ratingItem struct {
+	Title  string   `json:"title"`
+	IMDBID string   `json:"imdbID,omitempty"`
+	Rating *float64 `json:"rating"`
+}
+
+// newRatingItem zamienia ocenę na ratingItem; rated mówi, czy film ma ocenę.
+func newRatingItem(r MovieRating, rated bool) ratingItem {
+	item := ratingItem{Title: r.MovieTitle, IMDBID: r.IMDBID}
+	if rated {
+		rating := r.Rating
+		item.Rating = &rating
+	}
+	return item
+}
+
+// ratingResponse to treść odpowiedzi POST /ratings: zapisana ocena z tytułem
+// dopasowanym do znanych tytułów.
+type ratingResponse struct {
+	User int `json:"user"`
+	ratingItem
+}
+
+// userResponse to treść GET /users/{id}
+type userResponse struct {
+	User    int          `json:"user"`
+	Ratings []ratingItem `json:"ratings"`
+}
+
+// recommendationItem to film z przewidywanym wynikiem i uzasadnieniem.
+type recommendationItem struct {
+	Title  string  `json:"title"`
+	IMDBID string  `json:"imdbID,omitempty"`
+	Score  float64 `json:"score"`
+	Reason string  `json:"reason,omitempty"`
+}
+
+// recommendationsResponse to treść GET /users/{id}/recommendations i /anti-recommendations.
+type recommendationsResponse struct {
+	User         int                  `json:"user"`
+	Strategy     Strategy             `json:"strategy"`
+	ModelVersion int                  `json:"modelVersion"`
+	Movies       []recommendationItem `json:"movies"`
+}
+
+// movieResponse to treść GET /movies/{id}; Details są puste, gdy dostawca
+// metadanych ich nie zna, a DetailsError mówi wtedy dlaczego.
+type movieResponse struct {
+	IMDBID       string        `json:"imdbID"`
+	Title        string        `json:"title"`
+	Ratings      int           `json:"ratings"`
+	MeanRating   *float64      `json:"meanRating"`
+	Unrated      int           `json:"unrated"`
+	Details      *MovieDetails `json:"details,omitempty"`
+	DetailsError string        `json:"detailsError,omitempty"`
+}
+
+// errorResponse to treść odpowiedzi z błędem.
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// newService udostępnia Store przez HTTP:
+//
+//	GET    /status                               stan danych i modelu (StoreStatus)
+//	POST   /ratings                              zapis oceny, treść ratingRequest
+//	GET    /users/{id}                           oceny użytkownika
+//	DELETE /users/{id}                           usunięcie wszystkich ocen użytkownika
+//	GET    /users/{id}/recommendations?n=5       n najlepszych filmów z uzasadnieniami
+//	GET    /users/{id}/anti-recommendations?n=5  n najgorszych filmów z uzasadnieniami
+//	GET    /movies/{id}                          film o podanym ID IMDB z ocenami i szczegółami
+//
+// Błędy mają postać errorResponse. Rekomendacje pochodzą z bieżącego modelu Store;
+// modele svd i als znają nowe oceny od razu po POST /ratings, a w pozostałych
+// użytkownik, którego oceny model jeszcze nie zna, dostaje 503 z Retry-After,
+// a jeśli ostatnie uczenie się nie udało, 500 z jego błędem.
+func newService(store *Store, metadata MetadataProvider) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /status", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, store.Status())
+	})
+	mux.HandleFunc("POST /ratings", func(w http.ResponseWriter, r *http.Request) {
+		var req ratingRequest
+		dec := json.NewDecoder(r.Body)
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid request body: " + err.Error()})
+			return
+		}
+		movie, err := store.AddRating(req.User, req.Title, req.IMDBID, req.Rating)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusCreated, ratingResponse{User: req.User, ratingItem: newRatingItem(movie, req.Rating != nil)})
+	})
+	mux.HandleFunc("GET /users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		personID, ok := userID(w, r)
+		if !ok {
+			return
+		}
+		rated, unrated := store.UserRatings(personID)
+		if len(rated)+len(unrated) == 0 {
+			writeJSON(w, http.StatusNotFound, errorResponse{Error: fmt.Sprintf("%v %d", ErrUnknownUser, personID)})
+			return
+		}
+		resp := userResponse{User: personID, Ratings: []ratingItem{}}
+		for _, m := range rated {
+			resp.Ratings = append(resp.Ratings, newRatingItem(m, true))
+		}
+		for _, m := range unrated {
+			resp.Ratings = append(resp.Ratings, newRatingItem(m, false))
+		}
+		writeJSON(w, http.StatusOK, resp)
+	})
+	mux.HandleFunc("DELETE /users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		personID, ok := userID(w, r)
+		if !ok {
+			return
+		}
+		if store.RemoveUser(personID) == 0 {
+			writeJSON(w, http.StatusNotFound, errorResponse{Error: fmt.Sprintf("%v %d", ErrUnknownUser, personID)})
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+	recommendations := func(anti bool) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			personID, ok := userID(w, r)
+			if !ok {
+				return
+			}
+			n := 5
+			if value := r.URL.Query().Get("n"); value != "" {
+				var err error
+				if n, err = strconv.Atoi(value); err != nil || n < 1 {
+					writeJSON(w, http.StatusBadRequest, errorResponse{Error: fmt.Sprintf("expected a positive number of movies, found %q", value)})
+					return
+				}
+			}
+			top, bottom, version, err := store.Recommend(personID, n)
+			if errors.Is(err, ErrUnknownUser) {
+				if rated, _ := store.UserRatings(personID); len(rated) > 0 {
+					if trainErr := store.TrainError(); trainErr != nil {
+						writeJSON(w, http.StatusInternalServerError, errorResponse{Error: fmt.Sprintf("could not learn the ratings of user %d: %v", personID, trainErr)})
+						return
+					}
+					w.Header().Set("Retry-After", "1")
+					writeJSON(w, http.StatusServiceUnavailable, errorResponse{Error: fmt.Sprintf("the model is still learning the ratings of user %d", personID)})
+					return
+				}
+				writeJSON(w, http.StatusNotFound, errorResponse{Error: err.Error()})
+				return
+			}
+			if err != nil {
+				writeJSON(w, http.StatusInternalServerError, errorResponse{Error: err.Error()})
+				return
+			}
+			list := top
+			if anti {
+				list = bottom
+			}
+			resp := recommendationsResponse{User: personID, Strategy: store.strategy, ModelVersion: version, Movies: []recommendationItem{}}
+			for _, rec := range list {
+				resp.Movies = append(resp.Movies, recommendationItem{Title: rec.Title, IMDBID: rec.IMDBID, Score: rec.Score, Reason: rec.Reason})
+			}
+			writeJSON(w, http.StatusOK, resp)
+		}
+	}
+	mux.HandleFunc("GET /users/{id}/recommendations", recommendations(false))
+	mux.HandleFunc("GET /users/{id}/anti-recommendations", recommendations(true))
+	mux.HandleFunc("GET /movies/{id}", func(w http.ResponseWriter, r *http.Request) {
+		imdbID, err := normalizeIMDBID(r.PathValue("id"))
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: err.Error()})
+			return
+		}
+		title, rated, unrated, ok := store.Movie(imdbID)
+		if !ok {
+			writeJSON(w, http.StatusNotFound, errorResponse{Error: fmt.Sprintf("%v: %s", ErrMovieNotFound, imdbID)})
+			return
+		}
+		resp := movieResponse{IMDBID: imdbID, Title: title, Ratings: len(rated), Unrated: len(unrated)}
+		if len(rated) > 0 {
+			sum := 0.0
+			for _, m := range rated {
+				sum += m.Rating
+			}
+			mean := sum / float64(len(rated))
+			resp.MeanRating = &mean
+		}
+		if resp.Details, err = metadata.MovieDetails(imdbID); err != nil {
+			resp.DetailsError = err.Error()
+		}
+		writeJSON(w, http.StatusOK, resp)
+	})
+	return mux
+}
+
+// userID odczytuje ID użytkownika ze ścieżki; przy błędzie wysyła 400 i zwraca false.
+func userID(w http.ResponseWriter, r *http.Request) (int, bool) {
+	personID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: fmt.Sprintf("expected a numeric user ID, found %q", r.PathValue("id"))})
+		return 0, false
+	}
+	return personID, true
+}
+
+// writeJSON wysyła v jako odpowiedź JSON.
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
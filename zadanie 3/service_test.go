@@ -0,0 +1,113 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// call wysyła żądanie do usługi i dekoduje odpowiedź JSON do v, jeśli v nie jest nil.
+func call(t *testing.T, server *httptest.Server, method, path, body string, v any) *http.Response {
+	t.Helper()
+	req, err := http.NewRequest(method, server.URL+path, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := server.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if v != nil {
+		if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+			t.Fatalf("%s %s: %v", method, path, err)
+		}
+	}
+	return resp
+}
+
+func newTestService(t *testing.T, strategy Strategy) (*Store, *httptest.Server) {
+	t.Helper()
+	store, err := NewStore(surveyRatings(), strategy, DefaultOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(newService(store, FixtureProvider{}))
+	t.Cleanup(server.Close)
+	return store, server
+}
+
+func TestServiceRatingsAndRecommendations(t *testing.T) {
+	store, server := newTestService(t, StrategyCluster)
+
+	var rating ratingResponse
+	resp := call(t, server, "POST", "/ratings", `{"user": 7, "title": " a ", "rating": 10}`, &rating)
+	if resp.StatusCode != http.StatusCreated || rating.Title != "A" || rating.IMDBID != "tt000000A" {
+		t.Fatalf("POST /ratings: expected 201 with the known title A, found %d %+v", resp.StatusCode, rating)
+	}
+	resp = call(t, server, "GET", "/users/7/recommendations", "", nil)
+	if resp.StatusCode != http.StatusServiceUnavailable || resp.Header.Get("Retry-After") == "" {
+		t.Errorf("before retraining: expected 503 with Retry-After, found %d", resp.StatusCode)
+	}
+	if err := store.Retrain(); err != nil {
+		t.Fatal(err)
+	}
+	var recommendations recommendationsResponse
+	resp = call(t, server, "GET", "/users/7/recommendations?n=2", "", &recommendations)
+	if resp.StatusCode != http.StatusOK || len(recommendations.Movies) != 2 || recommendations.ModelVersion != 1 {
+		t.Errorf("after retraining: expected 200 with 2 movies from model version 1, found %d %+v", resp.StatusCode, recommendations)
+	}
+
+	for _, path := range []string{"/users/7/recommendations?n=0", "/users/7/anti-recommendations?n=-1", "/users/7/recommendations?n=five", "/users/seven/recommendations"} {
+		if resp := call(t, server, "GET", path, "", nil); resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("GET %s: expected 400, found %d", path, resp.StatusCode)
+		}
+	}
+	for _, body := range []string{`{"user": 7, "title": "A", "rating": 11}`, `{"user": 7, "title": " "}`, `{"user": 7, "title": "G", "imdbID": "123"}`, `{"user": 7, "film": "A"}`} {
+		if resp := call(t, server, "POST", "/ratings", body, nil); resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("POST /ratings %s: expected 400, found %d", body, resp.StatusCode)
+		}
+	}
+}
+
+func TestServiceFoldsInNewUser(t *testing.T) {
+	_, server := newTestService(t, StrategySVD)
+	call(t, server, "POST", "/ratings", `{"user": 7, "title": "D", "rating": 9}`, nil)
+	var recommendations recommendationsResponse
+	resp := call(t, server, "GET", "/users/7/recommendations?n=1", "", &recommendations)
+	if resp.StatusCode != http.StatusOK || len(recommendations.Movies) != 1 {
+		t.Errorf("expected 200 with a recommendation right after the first rating, found %d %+v", resp.StatusCode, recommendations)
+	}
+}
+
+func TestServiceUnknownAndDeletedUsers(t *testing.T) {
+	_, server := newTestService(t, StrategySVD)
+	for _, path := range []string{"/users/99", "/users/99/recommendations", "/users/99/anti-recommendations"} {
+		if resp := call(t, server, "GET", path, "", nil); resp.StatusCode != http.StatusNotFound {
+			t.Errorf("GET %s: expected 404, found %d", path, resp.StatusCode)
+		}
+	}
+
+	var user userResponse
+	if resp := call(t, server, "GET", "/users/6", "", &user); resp.StatusCode != http.StatusOK || len(user.Ratings) != 5 {
+		t.Errorf("GET /users/6: expected 200 with 4 rated and 1 unrated movie, found %d %+v", resp.StatusCode, user)
+	}
+	if resp := call(t, server, "DELETE", "/users/6", "", nil); resp.StatusCode != http.StatusNoContent {
+		t.Errorf("DELETE /users/6: expected 204, found %d", resp.StatusCode)
+	}
+	for _, path := range []string{"/users/6", "/users/6/recommendations"} {
+		if resp := call(t, server, "GET", path, "", nil); resp.StatusCode != http.StatusNotFound {
+			t.Errorf("GET %s after DELETE: expected 404, found %d", path, resp.StatusCode)
+		}
+	}
+	if resp := call(t, server, "DELETE", "/users/6", "", nil); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("second DELETE /users/6: expected 404, found %d", resp.StatusCode)
+	}
+
+	var movie movieResponse
+	if resp := call(t, server, "GET", "/movies/tt000000B", "", &movie); resp.StatusCode != http.StatusOK || movie.Ratings != 4 || movie.Unrated != 0 {
+		t.Errorf("GET /movies/tt000000B: expected 4 ratings and no unrated view left after the deletion, found %d %+v", resp.StatusCode, movie)
+	}
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Metric to miara podobieństwa dwóch wektorów ocen.
+// Wszystkie miary zwracają wartości z przedziału [-1, 1] (Jaccard [0, 1]),
+// gdzie większa wartość oznacza bardziej podobne preferencje.
+type Metric string
+
+const (
+	// Cosine to kosinus kąta między ocenami wspólnych pozycji.
+	Cosine Metric = "cosine"
+	// Pearson to współczynnik korelacji Pearsona ocen wspólnych pozycji,
+	// odporny na to, że jedna osoba ocenia ogólnie wyżej od drugiej.
+	Pearson Metric = "pearson"
+	// AdjustedCosine to kosinus ocen pomniejszonych o średnią ocenę danej pozycji.
+	AdjustedCosine Metric = "adjusted-cosine"
+	// Jaccard to stosunek liczby wspólnych pozycji do liczby wszystkich ocenionych,
+	// bez względu na wartości ocen.
+	Jaccard Metric = "jaccard"
+)
+
+// Metrics zwraca nazwy wszystkich miar podobieństwa.
+func Metrics() []Metric {
+	return []Metric{Cosine, Pearson, AdjustedCosine, Jaccard}
+}
+
+// ParseMetric zamienia nazwę miary na Metric.
+func ParseMetric(name string) (Metric, error) {
+	for _, m := range Metrics() {
+		if string(m) == name {
+			return m, nil
+		}
+	}
+	return "", fmt.Errorf("unknown similarity metric %q (want cosine, pearson, adjusted-cosine or jaccard)", name)
+}
+
+// similarity oblicza podobieństwo wektorów ocen a i b w mierze m. Wektor to mapa
+// pozycja -> ocena: dla użytkownika pozycjami są filmy, dla filmu użytkownicy.
+// Porównywane są tylko pozycje ocenione w obu wektorach, więc brak oceny nie jest
+// traktowany jak ocena 0. means to średnie oceny pozycji, potrzebne tylko dla
+// AdjustedCosine. Wektory bez wspólnych pozycji mają podobieństwo 0.
+func similarity[K comparable](m Metric, a, b map[K]float64, means map[K]float64) float64 {
+	if len(b) < len(a) {
+		a, b = b, a
+	}
+	common := 0
+	for key := range a {
+		if _, ok := b[key]; ok {
+			common++
+		}
+	}
+	if common == 0 {
+		return 0
+	}
+	switch m {
+	case Jaccard:
+		return float64(common) / float64(len(a)+len(b)-common)
+	case Pearson:
+		meanA, meanB := 0.0, 0.0
+		for key, x := range a {
+			if y, ok := b[key]; ok {
+				meanA += x
+				meanB += y
+			}
+		}
+		meanA /= float64(common)
+		meanB /= float64(common)
+		return cosineOf(a, b, func(key K, x, y float64) (float64, float64) { return x - meanA, y - meanB })
+	case AdjustedCosine:
+		return cosineOf(a, b, func(key K, x, y float64) (float64, float64) { return x - means[key], y - means[key] })
+	default:
+		return cosineOf(a, b, func(key K, x, y float64) (float64, float64) { return x, y })
+	}
+}
+
+// cosineOf liczy kosinus wspólnych pozycji a i b po przekształceniu ocen funkcją center.
+func cosineOf[K comparable](a, b map[K]float64, center func(key K, x, y float64) (float64, float64)) float64 {
+	dot, normA, normB := 0.0, 0.0, 0.0
+	for key, x := range a {
+		y, ok := b[key]
+		if !ok {
+			continue
+		}
+		x, y = center(key, x, y)
+		dot += x * y
+		normA += x * x
+		normB += y * y
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / math.Sqrt(normA*normB)
+}
+
+// distance zamienia podobieństwo na odległość z przedziału [0, 2] używaną przy grupowaniu.
+func distance[K comparable](m Metric, a, b map[K]float64, means map[K]float64) float64 {
+	return 1 - similarity(m, a, b, means)
+}
+
+// itemMeans zwraca średnią ocenę każdej pozycji na podstawie wektorów ocen.
+func itemMeans[U, K comparable](vectors map[U]map[K]float64) map[K]float64 {
+	sums := make(map[K]float64)
+	counts := make(map[K]int)
+	for _, vector := range vectors {
+		for key, x := range vector {
+			sums[key] += x
+			counts[key]++
+		}
+	}
+	for key := range sums {
+		sums[key] /= float64(counts[key])
+	}
+	return sums
+}
+
+// sortedKeys zwraca klucze mapy w porządku rosnącym, żeby wyniki nie zależały
+// od kolejności iteracji po mapie.
+func sortedKeys[K int | string, V any](m map[K]V) []K {
+	keys := make([]K, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+	return keys
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSimilarity(t *testing.T) {
+	// Wspólne są tylko "B" i "C": a ocenia je 2 i 3, b odwrotnie 6 i 4.
+	a := map[string]float64{"A": 1, "B": 2, "C": 3}
+	b := map[string]float64{"B": 6, "C": 4, "D": 1}
+	means := map[string]float64{"B": 3, "C": 4}
+	tests := []struct {
+		metric Metric
+		want   float64
+	}{
+		{Cosine, 24.0 / 26},               // (2·6 + 3·4) / (√13 · √52)
+		{Pearson, -1},                     // odchylenia od średnich 2.5 i 5: (-0.5, 0.5) i (1, -1)
+		{AdjustedCosine, -1 / math.Sqrt2}, // odchylenia od means: (-1, -1) i (3, 0)
+		{Jaccard, 0.5},                    // 2 wspólne z 4 ocenionych
+	}
+	for _, tt := range tests {
+		if got := similarity(tt.metric, a, b, means); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("%s: expected %g, found %g", tt.metric, tt.want, got)
+		}
+		if got := similarity(tt.metric, b, a, means); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("%s: expected a symmetric %g, found %g", tt.metric, tt.want, got)
+		}
+		// bez wspólnych pozycji podobieństwo jest 0, a nie NaN
+		if got := similarity(tt.metric, a, map[string]float64{"E": 5}, means); got != 0 {
+			t.Errorf("%s: expected 0 without common items, found %g", tt.metric, got)
+		}
+	}
+
+	// stałe oceny nie mają wariancji, więc korelacja nie jest określona
+	flat := map[string]float64{"B": 5, "C": 5}
+	if got := similarity(Pearson, a, flat, means); got != 0 {
+		t.Errorf("expected 0 for constant ratings, found %g", got)
+	}
+}
+
+func TestParseMetric(t *testing.T) {
+	for _, m := range Metrics() {
+		if got, err := ParseMetric(string(m)); err != nil || got != m {
+			t.Errorf("expected %s, found %q and %v", m, got, err)
+		}
+	}
+	if _, err := ParseMetric("euclidean"); err == nil {
+		t.Error("expected an error for an unknown metric")
+	}
+}
@@ -0,0 +1,361 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// snapshot to niezmienny stan używany przy odczytach: kopia ocen i wyuczony na niej model.
+type snapshot struct {
+	ratings   *MovieRatings
+	model     Recommender
+	version   int
+	trainedAt time.Time // czas ostatniego pełnego uczenia
+	folded    bool      // model zawiera oceny wprowadzone przez FoldIn po pełnym uczeniu
+}
+
+// Store przechowuje oceny w pamięci i model rekomendacji wyuczony na nich.
+// Nowe oceny są widoczne od razu w danych (UserRatings, Movie). Model, który
+// jest Incremental (svd, als), przyjmuje je od razu przez FoldIn, a co jakiś
+// czas (Run) jest uczony od nowa w tle. Pozostałe modele są uczone od nowa
+// w tle po każdej zmianie, a do tego czasu rekomendacje pochodzą z poprzedniego
+// modelu. Modele są podmieniane atomowo, więc odczyty nigdy nie czekają na uczenie.
+type Store struct {
+	strategy Strategy
+	opts     Options
+
+	mu      sync.Mutex // chroni ratings, titles, version i changes
+	ratings MovieRatings
+	titles  map[string]string // titleKey -> tytuł
+	version int
+	changes map[int]int // użytkownik -> wersja danych z jego ostatnią zmianą
+
+	trainMu  sync.Mutex // uczenia nie nakładają się na siebie
+	swapMu   sync.Mutex // podmiany modelu nie nakładają się na siebie
+	current  atomic.Pointer[snapshot]
+	retrain  chan struct{} // zmiany czekające na pełne uczenie
+	retrains atomic.Int64
+	trainErr atomic.Pointer[error] // błąd ostatniego uczenia albo nil po udanym
+}
+
+// NewStore kopiuje oceny mr i uczy na nich pierwszy model strategii strategy.
+func NewStore(mr *MovieRatings, strategy Strategy, opts Options) (*Store, error) {
+	if len(mr.Ratings) == 0 {
+		return nil, errors.New("no ratings to train on")
+	}
+	s := &Store{strategy: strategy, opts: opts, titles: make(map[string]string), changes: make(map[int]int), retrain: make(chan struct{}, 1)}
+	s.ratings = *copyRatings(mr)
+	for _, ratings := range [][]MovieRating{s.ratings.Ratings, s.ratings.Unrated} {
+		for _, r := range ratings {
+			s.titles[titleKey(r.MovieTitle)] = r.MovieTitle
+		}
+	}
+	if err := s.Retrain(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// copyRatings zwraca kopię ocen niezależną od dalszych zmian mr.
+func copyRatings(mr *MovieRatings) *MovieRatings {
+	return &MovieRatings{
+		Ratings: append([]MovieRating(nil), mr.Ratings...),
+		Unrated: append([]MovieRating(nil), mr.Unrated...),
+	}
+}
+
+// AddRating zapisuje ocenę użytkownika, zastępując jego wcześniejszą ocenę tego
+// samego filmu. rating == nil oznacza film obejrzany bez oceny. Tytuł jest
+// dopasowywany do znanych tytułów tak jak przy wczytywaniu CSV (titleKey);
+// imdbID (z przedrostkiem "tt" jak w ReadIMDBIDs) jest używane tylko dla filmów,
+// których ID nie jest jeszcze znane.
+// Zwraca zapisaną ocenę po wprowadzeniu jej do modelu (update).
+func (s *Store) AddRating(personID int, title, imdbID string, rating *float64) (MovieRating, error) {
+	key := titleKey(title)
+	if key == "" {
+		return MovieRating{}, errors.New("empty movie title")
+	}
+	if rating != nil && !validRating(*rating) {
+		return MovieRating{}, fmt.Errorf("rating %g outside the scale %g-%g", *rating, MinRating, MaxRating)
+	}
+	imdbID = strings.TrimSpace(imdbID)
+	if imdbID != "" && !strings.HasPrefix(imdbID, "tt") {
+		return MovieRating{}, fmt.Errorf("IMDB ID %q does not start with tt", imdbID)
+	}
+
+	s.mu.Lock()
+	if known, ok := s.titles[key]; ok {
+		title = known
+	} else {
+		title = cleanTitle(title)
+		s.titles[key] = title
+	}
+	movie := MovieRating{PersonID: personID, MovieTitle: title}
+	s.ratings.Ratings = withoutRating(s.ratings.Ratings, personID, key, &movie.IMDBID)
+	s.ratings.Unrated = withoutRating(s.ratings.Unrated, personID, key, &movie.IMDBID)
+	if movie.IMDBID == "" {
+		movie.IMDBID, _ = s.ratings.GetIMDBIDByTitle(title)
+	}
+	if movie.IMDBID == "" {
+		movie.IMDBID = imdbID
+	}
+	if rating != nil {
+		movie.Rating = *rating
+		s.ratings.Ratings = append(s.ratings.Ratings, movie)
+	} else {
+		s.ratings.Unrated = append(s.ratings.Unrated, movie)
+	}
+	s.changed(personID)
+	s.mu.Unlock()
+	s.update()
+	return movie, nil
+}
+
+// RemoveUser usuwa wszystkie oceny użytkownika, także z modelu (update),
+// i zwraca ich liczbę.
+func (s *Store) RemoveUser(personID int) int {
+	s.mu.Lock()
+	removed := 0
+	for _, ratings := range []*[]MovieRating{&s.ratings.Ratings, &s.ratings.Unrated} {
+		kept := (*ratings)[:0:0]
+		for _, r := range *ratings {
+			if r.PersonID == personID {
+				removed++
+			} else {
+				kept = append(kept, r)
+			}
+		}
+		*ratings = kept
+	}
+	if removed == 0 {
+		s.mu.Unlock()
+		return 0
+	}
+	s.changed(personID)
+	s.mu.Unlock()
+	s.update()
+	return removed
+}
+
+// withoutRating zwraca oceny bez oceny użytkownika dla filmu o kluczu key;
+// ID IMDB usuniętej oceny trafia do imdbID.
+func withoutRating(ratings []MovieRating, personID int, key string, imdbID *string) []MovieRating {
+	out := ratings[:0:0]
+	for _, r := range ratings {
+		if r.PersonID == personID && titleKey(r.MovieTitle) == key {
+			*imdbID = r.IMDBID
+			continue
+		}
+		out = append(out, r)
+	}
+	return out
+}
+
+// changed zapisuje nową wersję danych ze zmianą ocen użytkownika personID.
+// Wymaga s.mu.
+func (s *Store) changed(personID int) {
+	s.version++
+	s.changes[personID] = s.version
+}
+
+// update wprowadza do modelu zmiany ocen przez FoldIn, a jeśli model tego nie
+// potrafi, zgłasza pętli Run potrzebę pełnego uczenia; kolejne zgłoszenia
+// przed uczeniem się łączą.
+func (s *Store) update() {
+	s.swapMu.Lock()
+	defer s.swapMu.Unlock()
+	next, err := s.foldChanges(s.current.Load())
+	if err != nil {
+		select {
+		case s.retrain <- struct{}{}:
+		default:
+		}
+		return
+	}
+	s.current.Store(next)
+}
+
+// errNotIncremental oznacza model, do którego nie da się wprowadzić zmian przez FoldIn.
+var errNotIncremental = errors.New("the model cannot fold in ratings")
+
+// foldChanges zwraca migawkę bieżących danych z modelem base, do którego FoldIn
+// wprowadził oceny wszystkich użytkowników zmienionych po wersji base.version.
+// Wymaga s.swapMu.
+func (s *Store) foldChanges(base *snapshot) (*snapshot, error) {
+	s.mu.Lock()
+	data, version := copyRatings(&s.ratings), s.version
+	var users []int
+	for user, changed := range s.changes {
+		if changed > base.version {
+			users = append(users, user)
+		}
+	}
+	s.mu.Unlock()
+	next := &snapshot{ratings: data, model: base.model, version: version, trainedAt: base.trainedAt, folded: base.folded}
+	if len(users) == 0 {
+		return next, nil
+	}
+	model, ok := base.model.(Incremental)
+	if !ok {
+		return nil, errNotIncremental
+	}
+	byUser := make(map[int][]MovieRating)
+	for _, r := range data.Ratings {
+		byUser[r.PersonID] = append(byUser[r.PersonID], r)
+	}
+	for _, user := range users {
+		var err error
+		if model, err = model.FoldIn(user, byUser[user]); err != nil {
+			return nil, err
+		}
+	}
+	next.model, next.folded = model, true
+	return next, nil
+}
+
+// UserRatings zwraca bieżące oceny użytkownika i filmy obejrzane bez oceny.
+func (s *Store) UserRatings(personID int) ([]MovieRating, []MovieRating) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var rated, unrated []MovieRating
+	for _, r := range s.ratings.Ratings {
+		if r.PersonID == personID {
+			rated = append(rated, r)
+		}
+	}
+	for _, r := range s.ratings.Unrated {
+		if r.PersonID == personID {
+			unrated = append(unrated, r)
+		}
+	}
+	return rated, unrated
+}
+
+// Movie zwraca tytuł filmu o podanym ID IMDB, jego bieżące oceny i filmy obejrzane
+// bez oceny; ok jest false, gdy nikt nie widział filmu.
+func (s *Store) Movie(imdbID string) (title string, rated, unrated []MovieRating, ok bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, r := range s.ratings.Ratings {
+		if r.IMDBID == imdbID {
+			title, ok = r.MovieTitle, true
+			rated = append(rated, r)
+		}
+	}
+	for _, r := range s.ratings.Unrated {
+		if r.IMDBID == imdbID {
+			title, ok = r.MovieTitle, true
+			unrated = append(unrated, r)
+		}
+	}
+	return title, rated, unrated, ok
+}
+
+// StoreStatus opisuje stan danych i modelu.
+type StoreStatus struct {
+	Strategy     Strategy  `json:"strategy"`
+	DataVersion  int       `json:"dataVersion"`  // rośnie z każdą zmianą ocen
+	ModelVersion int       `json:"modelVersion"` // wersja danych, na której wyuczono bieżący model
+	TrainedAt    time.Time `json:"trainedAt"`
+	Retrains     int64     `json:"retrains"`
+	Folded       bool      `json:"folded"` // model zawiera oceny wprowadzone przyrostowo po ostatnim pełnym uczeniu
+	Ratings      int       `json:"ratings"`
+	TrainError   string    `json:"trainError,omitempty"` // błąd ostatniego uczenia, jeśli się nie udało
+}
+
+// Status zwraca stan danych i modelu.
+func (s *Store) Status() StoreStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	snap := s.current.Load()
+	status := StoreStatus{
+		Strategy:     s.strategy,
+		DataVersion:  s.version,
+		ModelVersion: snap.version,
+		TrainedAt:    snap.trainedAt,
+		Retrains:     s.retrains.Load(),
+		Folded:       snap.folded,
+		Ratings:      len(s.ratings.Ratings),
+	}
+	if err := s.TrainError(); err != nil {
+		status.TrainError = err.Error()
+	}
+	return status
+}
+
+// TrainError zwraca błąd ostatniego uczenia albo nil, jeśli się udało.
+func (s *Store) TrainError() error {
+	if err := s.trainErr.Load(); err != nil {
+		return *err
+	}
+	return nil
+}
+
+// Recommend zwraca rekomendacje i antyrekomendacje bieżącego modelu oraz wersję
+// danych, na której go wyuczono. Nie czeka na trwające uczenie.
+func (s *Store) Recommend(personID, n int) ([]Recommendation, []Recommendation, int, error) {
+	snap := s.current.Load()
+	top, bottom, err := snap.ratings.Recommend(snap.model, personID, n)
+	return top, bottom, snap.version, err
+}
+
+// Retrain uczy model od nowa na bieżących danych, o ile bieżący model nie jest
+// już wyuczony w całości na nich, i podmienia go. Zmiany ocen, które nadeszły
+// w trakcie uczenia, wprowadza do nowego modelu przez FoldIn, jeśli model to
+// potrafi. Wynik uczenia zwraca potem TrainError.
+func (s *Store) Retrain() error {
+	s.trainMu.Lock()
+	defer s.trainMu.Unlock()
+	s.mu.Lock()
+	data, version := copyRatings(&s.ratings), s.version
+	s.mu.Unlock()
+	if current := s.current.Load(); current != nil && current.version == version && !current.folded {
+		return nil
+	}
+	model, err := NewRecommender(s.strategy, data, s.opts)
+	if err != nil {
+		s.trainErr.Store(&err)
+		return err
+	}
+	trained := &snapshot{ratings: data, model: model, version: version, trainedAt: time.Now()}
+	s.swapMu.Lock()
+	if next, err := s.foldChanges(trained); err == nil {
+		trained = next
+	}
+	s.current.Store(trained)
+	s.swapMu.Unlock()
+	s.trainErr.Store(nil)
+	s.retrains.Add(1)
+	return nil
+}
+
+// Run uczy model od nowa w tle, aż do anulowania ctx: po zmianie danych, której
+// model nie przyjął przez FoldIn, i co interval (0 wyłącza uczenie okresowe),
+// jeśli model nie jest wyuczony w całości na bieżących danych, czyli po zmianach
+// wprowadzonych przez FoldIn albo po nieudanym uczeniu. Błędy uczenia są
+// wypisywane na stderr, a odczyty korzystają wtedy dalej z poprzedniego modelu.
+func (s *Store) Run(ctx context.Context, interval time.Duration) {
+	var tick <-chan time.Time
+	if interval > 0 {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.retrain:
+		case <-tick:
+		}
+		if err := s.Retrain(); err != nil {
+			fmt.Fprintln(os.Stderr, "Error retraining the model:", err)
+		}
+	}
+}
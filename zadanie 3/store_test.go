@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// surveyRatings to mała ankieta z dwiema grupami gustów: użytkownicy 1-3 lubią
+// filmy akcji (A, B, C), a 4-6 dramaty (D, E, F).
+func surveyRatings() *MovieRatings {
+	mr := &MovieRatings{}
+	add := func(user int, title string, rating float64) {
+		mr.Ratings = append(mr.Ratings, MovieRating{PersonID: user, MovieTitle: title, IMDBID: "tt000000" + title, Rating: rating})
+	}
+	for user := 1; user <= 3; user++ {
+		add(user, "A", 9)
+		add(user, "B", 8+float64(user%2))
+		add(user, "D", 2)
+		add(user, "E", 3)
+	}
+	for user := 4; user <= 6; user++ {
+		add(user, "A", 2)
+		add(user, "C", 3)
+		add(user, "D", 9)
+		add(user, "F", 8+float64(user%2))
+	}
+	add(1, "F", 3)
+	add(4, "B", 2)
+	add(2, "C", 8)
+	add(5, "E", 9)
+	mr.Unrated = append(mr.Unrated, MovieRating{PersonID: 6, MovieTitle: "B", IMDBID: "tt000000B"})
+	return mr
+}
+
+func rate(rating float64) *float64 { return &rating }
+
+func TestStoreFoldsInRatings(t *testing.T) {
+	for _, strategy := range []Strategy{StrategySVD, StrategyALS} {
+		store, err := NewStore(surveyRatings(), strategy, DefaultOptions())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := store.AddRating(7, "a", "", rate(10)); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := store.AddRating(7, "B", "", rate(9)); err != nil {
+			t.Fatal(err)
+		}
+		top, _, version, err := store.Recommend(7, 2)
+		if err != nil {
+			t.Fatalf("%s: expected recommendations for a folded-in user, found %v", strategy, err)
+		}
+		if len(top) != 2 || version != 2 {
+			t.Errorf("%s: expected 2 recommendations from data version 2, found %v from %d", strategy, titles(top), version)
+		}
+		status := store.Status()
+		if !status.Folded || status.Retrains != 1 || status.ModelVersion != status.DataVersion {
+			t.Errorf("%s: expected a folded model of the current data after one training, found %+v", strategy, status)
+		}
+
+		if err := store.Retrain(); err != nil {
+			t.Fatal(err)
+		}
+		if status := store.Status(); status.Folded || status.Retrains != 2 {
+			t.Errorf("%s: expected a fully trained model after Retrain, found %+v", strategy, status)
+		}
+		if err := store.Retrain(); err != nil || store.Status().Retrains != 2 {
+			t.Errorf("%s: expected Retrain to skip a model trained on the current data, found %v after %d trainings", strategy, err, store.Status().Retrains)
+		}
+
+		if removed := store.RemoveUser(7); removed != 2 {
+			t.Errorf("%s: expected 2 removed ratings, found %d", strategy, removed)
+		}
+		if _, _, _, err := store.Recommend(7, 2); !errors.Is(err, ErrUnknownUser) {
+			t.Errorf("%s: expected ErrUnknownUser for a removed user, found %v", strategy, err)
+		}
+		if _, err := store.current.Load().model.Predict(7, "C"); !errors.Is(err, ErrUnknownUser) {
+			t.Errorf("%s: expected the removed user to leave the model, found %v", strategy, err)
+		}
+	}
+}
+
+func TestStoreRetrainsInBackground(t *testing.T) {
+	store, err := NewStore(surveyRatings(), StrategyUserKNN, DefaultOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.AddRating(7, "A", "", rate(10)); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, _, err := store.Recommend(7, 2); !errors.Is(err, ErrUnknownUser) {
+		t.Fatalf("expected ErrUnknownUser before retraining user-knn, found %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go store.Run(ctx, 0)
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if _, _, _, err := store.Recommend(7, 2); err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("the model was not retrained after a rating change")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if status := store.Status(); status.Folded || status.Retrains != 2 {
+		t.Errorf("expected one background retraining, found %+v", status)
+	}
+}